@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vas-solutus/arca-tap-forwarder/internal/capture"
+	"github.com/vas-solutus/arca-tap-forwarder/internal/forwarder"
+	"golang.org/x/net/bpf"
+)
+
+// startDiagServer starts the opt-in diagnostic HTTP server: /debug/pprof/*,
+// Prometheus-format /metrics, /attachments and /flows as JSON, and a
+// /capture endpoint that streams a live pcap. It exists because the old
+// "log first 5 packets" debugging this daemon used to do is useless in
+// production; this gives an operator a real way to see what a running
+// daemon is doing without shelling into the guest (see dockerd's own
+// hidden --network-diagnostic-port for the same idea). It always binds to
+// loopback only, never the container-facing network, and the caller should
+// only start it at all when diagnostics were explicitly requested.
+func startDiagServer(fwd *forwarder.Forwarder, diagPort int) *http.Server {
+	d := &diagHandler{fwd: fwd}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/metrics", d.handleMetrics)
+	mux.HandleFunc("/attachments", d.handleAttachments)
+	mux.HandleFunc("/flows", d.handleFlows)
+	mux.HandleFunc("/capture", d.handleCapture)
+
+	addr := fmt.Sprintf("127.0.0.1:%d", diagPort)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("diag: failed to start diagnostic server on %s: %v", addr, err)
+		return nil
+	}
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		log.Printf("diag: diagnostic server listening on %s (loopback only)", addr)
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("diag: server error: %v", err)
+		}
+	}()
+
+	return srv
+}
+
+// diagHandler holds the state diag.go's handlers read from; it never
+// mutates the forwarder, only observes it.
+type diagHandler struct {
+	fwd *forwarder.Forwarder
+}
+
+// handleMetrics exports every attachment's Stats counters, labeled by
+// device, in Prometheus text exposition format.
+func (d *diagHandler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	counters := []struct {
+		name string
+		help string
+		get  func(forwarder.Stats) uint64
+	}{
+		{"arca_tap_forwarder_packets_sent_total", "Packets forwarded from TAP to vsock.", func(s forwarder.Stats) uint64 { return s.PacketsSent.Load() }},
+		{"arca_tap_forwarder_packets_received_total", "Packets forwarded from vsock to TAP.", func(s forwarder.Stats) uint64 { return s.PacketsReceived.Load() }},
+		{"arca_tap_forwarder_bytes_sent_total", "Bytes forwarded from TAP to vsock.", func(s forwarder.Stats) uint64 { return s.BytesSent.Load() }},
+		{"arca_tap_forwarder_bytes_received_total", "Bytes forwarded from vsock to TAP.", func(s forwarder.Stats) uint64 { return s.BytesReceived.Load() }},
+		{"arca_tap_forwarder_send_errors_total", "Errors writing to vsock.", func(s forwarder.Stats) uint64 { return s.SendErrors.Load() }},
+		{"arca_tap_forwarder_receive_errors_total", "Errors reading from TAP.", func(s forwarder.Stats) uint64 { return s.ReceiveErrors.Load() }},
+		{"arca_tap_forwarder_capture_drops_total", "Packets dropped from a capture subscriber's queue.", func(s forwarder.Stats) uint64 { return s.CaptureDrops.Load() }},
+	}
+
+	attachments := d.fwd.ListAttachments()
+	for _, c := range counters {
+		fmt.Fprintf(w, "# HELP %s %s\n", c.name, c.help)
+		fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+		for _, a := range attachments {
+			fmt.Fprintf(w, "%s{device=%q} %d\n", c.name, a.Device, c.get(a.GetStats()))
+		}
+	}
+}
+
+// diagAttachment is the /attachments JSON representation of one
+// NetworkAttachment's externally-visible state.
+type diagAttachment struct {
+	Device     string     `json:"device"`
+	IPAddress  string     `json:"ip_address"`
+	Gateway    string     `json:"gateway"`
+	MACAddress string     `json:"mac_address"`
+	ChannelID  uint32     `json:"channel_id"`
+	Mode       string     `json:"mode"`
+	Stats      diagCounts `json:"stats"`
+}
+
+type diagCounts struct {
+	PacketsSent     uint64 `json:"packets_sent"`
+	PacketsReceived uint64 `json:"packets_received"`
+	BytesSent       uint64 `json:"bytes_sent"`
+	BytesReceived   uint64 `json:"bytes_received"`
+	SendErrors      uint64 `json:"send_errors"`
+	ReceiveErrors   uint64 `json:"receive_errors"`
+	CaptureDrops    uint64 `json:"capture_drops"`
+}
+
+// diagAttachmentsResponse is /attachments' top-level JSON shape: the
+// shared data connection's state plus every attachment's.
+type diagAttachmentsResponse struct {
+	DataConnection string           `json:"data_connection"`
+	Attachments    []diagAttachment `json:"attachments"`
+}
+
+// handleAttachments returns the JSON equivalent of the gRPC ListNetworks
+// response, plus the shared data connection's state - whether Serve has a
+// live host connection bound yet - since that's the thing most worth
+// knowing when traffic isn't flowing and there's no way to attach a
+// debugger to ask.
+func (d *diagHandler) handleAttachments(w http.ResponseWriter, r *http.Request) {
+	attachments := d.fwd.ListAttachments()
+	resp := diagAttachmentsResponse{
+		DataConnection: d.fwd.DataConnectionState(),
+		Attachments:    make([]diagAttachment, 0, len(attachments)),
+	}
+	for _, a := range attachments {
+		stats := a.GetStats()
+		resp.Attachments = append(resp.Attachments, diagAttachment{
+			Device:     a.Device,
+			IPAddress:  a.IPAddress,
+			Gateway:    a.Gateway,
+			MACAddress: a.MAC,
+			ChannelID:  a.Channel,
+			Mode:       a.Mode.String(),
+			Stats: diagCounts{
+				PacketsSent:     stats.PacketsSent.Load(),
+				PacketsReceived: stats.PacketsReceived.Load(),
+				BytesSent:       stats.BytesSent.Load(),
+				BytesReceived:   stats.BytesReceived.Load(),
+				SendErrors:      stats.SendErrors.Load(),
+				ReceiveErrors:   stats.ReceiveErrors.Load(),
+				CaptureDrops:    stats.CaptureDrops.Load(),
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("diag: encoding /attachments response: %v", err)
+	}
+}
+
+// diagFlow is the /flows JSON representation of one NetworkAttachment's
+// per-flow accounting (see forwarder.FlowStats), with the owning device
+// attached since /flows reports across every netstack-mode attachment at
+// once.
+type diagFlow struct {
+	Device     string `json:"device"`
+	SrcAddress string `json:"src_address"`
+	DstAddress string `json:"dst_address"`
+	Proto      string `json:"proto"`
+	SrcPort    uint16 `json:"src_port"`
+	DstPort    uint16 `json:"dst_port"`
+	Packets    uint64 `json:"packets"`
+	Bytes      uint64 `json:"bytes"`
+}
+
+// handleFlows returns every ModeNetstackL3 attachment's current per-flow
+// counters. Attachments in ModeRawL2 have no L3 visibility and are
+// silently omitted, rather than erroring the whole response.
+func (d *diagHandler) handleFlows(w http.ResponseWriter, r *http.Request) {
+	var flows []diagFlow
+	for _, a := range d.fwd.ListAttachments() {
+		if a.Mode != forwarder.ModeNetstackL3 {
+			continue
+		}
+		fs, err := a.ListFlows()
+		if err != nil {
+			continue
+		}
+		for _, f := range fs {
+			flows = append(flows, diagFlow{
+				Device:     a.Device,
+				SrcAddress: net.IP(f.Key.SrcAddr.AsSlice()).String(),
+				DstAddress: net.IP(f.Key.DstAddr.AsSlice()).String(),
+				Proto:      f.Key.Proto.String(),
+				SrcPort:    f.Key.SrcPort,
+				DstPort:    f.Key.DstPort,
+				Packets:    f.Packets,
+				Bytes:      f.Bytes,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(flows); err != nil {
+		log.Printf("diag: encoding /flows response: %v", err)
+	}
+}
+
+// handleCapture streams a live pcap of ?device=...'s traffic for
+// ?duration=... (default 10s, parsed with time.ParseDuration) to the
+// response body, optionally filtered by ?filter=..., so an operator can
+// `curl .../capture?device=eth0 > eth0.pcap` without a gRPC client or a
+// shell in the guest.
+func (d *diagHandler) handleCapture(w http.ResponseWriter, r *http.Request) {
+	device := r.URL.Query().Get("device")
+	if device == "" {
+		http.Error(w, "missing required query parameter: device", http.StatusBadRequest)
+		return
+	}
+	attachment, ok := d.fwd.GetAttachment(device)
+	if !ok {
+		http.Error(w, fmt.Sprintf("device %s not attached", device), http.StatusNotFound)
+		return
+	}
+
+	duration := 10 * time.Second
+	if s := r.URL.Query().Get("duration"); s != "" {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid duration: %v", err), http.StatusBadRequest)
+			return
+		}
+		duration = parsed
+	}
+
+	filter, err := parseBPFFilterQuery(r.URL.Query().Get("filter"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid filter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := capture.NewSubscriber(filter, capture.DefaultSnaplen)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	attachment.SubscribeCapture(sub)
+	defer attachment.UnsubscribeCapture(sub)
+
+	w.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+	if err := capture.WriteGlobalHeader(w, capture.DefaultSnaplen); err != nil {
+		log.Printf("diag: writing pcap header for %s: %v", device, err)
+		return
+	}
+	flusher, canFlush := w.(http.Flusher)
+	if canFlush {
+		flusher.Flush()
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), duration)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case rec, ok := <-sub.Packets():
+			if !ok {
+				return
+			}
+			if err := capture.WriteRecord(w, rec, capture.DefaultSnaplen); err != nil {
+				log.Printf("diag: writing pcap record for %s: %v", device, err)
+				return
+			}
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// parseBPFFilterQuery parses a "op,jt,jf,k;op,jt,jf,k;..." BPF program -
+// the same raw quadruples `tcpdump -dd` prints, semicolon-separated - from
+// a query parameter. There's no reasonable way to pass CapturePackets'
+// repeated BpfInstruction field through a URL query, so /capture uses this
+// flattened text form instead.
+func parseBPFFilterQuery(s string) ([]bpf.Instruction, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ";")
+	instructions := make([]bpf.Instruction, len(parts))
+	for i, part := range parts {
+		fields := strings.Split(part, ",")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("instruction %d: want 4 comma-separated fields, got %d", i, len(fields))
+		}
+		var vals [4]uint64
+		for j, field := range fields {
+			v, err := strconv.ParseUint(strings.TrimSpace(field), 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("instruction %d field %d: %w", i, j, err)
+			}
+			vals[j] = v
+		}
+		ins, ok := bpf.RawInstruction{
+			Op: uint16(vals[0]),
+			Jt: uint8(vals[1]),
+			Jf: uint8(vals[2]),
+			K:  uint32(vals[3]),
+		}.Disassemble()
+		if !ok {
+			return nil, fmt.Errorf("instruction %d: unrecognized BPF opcode %#x", i, vals[0])
+		}
+		instructions[i] = ins
+	}
+	return instructions, nil
+}
@@ -5,22 +5,35 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"flag"
+	"fmt"
 	"log"
-	"os"
+	"net"
+	"net/http"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/mdlayher/vsock"
-	pb "github.com/vas-solutus/arca-tap-forwarder/proto"
+	"github.com/vas-solutus/arca-tap-forwarder/internal/capture"
 	"github.com/vas-solutus/arca-tap-forwarder/internal/forwarder"
+	pb "github.com/vas-solutus/arca-tap-forwarder/proto"
+	"golang.org/x/net/bpf"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	CONTROL_PORT = 5555
-	VERSION      = "0.1.0"
+	// DATA_PORT carries the shared multiplexed relay connection (see
+	// internal/relay) that every attached network's packets travel over,
+	// replacing the old one-vsock-port-per-device scheme.
+	DATA_PORT = 5556
+	VERSION   = "0.2.0"
 )
 
 var startTime = time.Now()
@@ -29,12 +42,15 @@ var startTime = time.Now()
 type server struct {
 	pb.UnimplementedTAPForwarderServer
 	forwarder *forwarder.Forwarder
+
+	fileCapturesMu sync.Mutex
+	fileCaptures   map[string]*forwarder.FileCapture
 }
 
 // AttachNetwork creates a TAP device and starts packet forwarding
 func (s *server) AttachNetwork(ctx context.Context, req *pb.AttachNetworkRequest) (*pb.AttachNetworkResponse, error) {
-	log.Printf("AttachNetwork: device=%s vsock_port=%d ip=%s gateway=%s netmask=%d",
-		req.Device, req.VsockPort, req.IpAddress, req.Gateway, req.Netmask)
+	log.Printf("AttachNetwork: device=%s ip=%s gateway=%s netmask=%d",
+		req.Device, req.IpAddress, req.Gateway, req.Netmask)
 
 	// Default netmask to /24 if not specified
 	netmask := req.Netmask
@@ -45,10 +61,10 @@ func (s *server) AttachNetwork(ctx context.Context, req *pb.AttachNetworkRequest
 	// Attach network
 	attachment, err := s.forwarder.AttachNetwork(
 		req.Device,
-		req.VsockPort,
 		req.IpAddress,
 		req.Gateway,
 		netmask,
+		modeFromProto(req.Mode),
 	)
 	if err != nil {
 		log.Printf("AttachNetwork failed: %v", err)
@@ -61,6 +77,7 @@ func (s *server) AttachNetwork(ctx context.Context, req *pb.AttachNetworkRequest
 	return &pb.AttachNetworkResponse{
 		Success:    true,
 		MacAddress: attachment.MAC,
+		ChannelId:  attachment.Channel,
 	}, nil
 }
 
@@ -90,10 +107,10 @@ func (s *server) ListNetworks(ctx context.Context, req *pb.ListNetworksRequest)
 	for _, a := range attachments {
 		stats := a.GetStats()
 		networks = append(networks, &pb.NetworkInfo{
-			Device:    a.Device,
-			IpAddress: a.IPAddress,
-			Gateway:   a.Gateway,
-			VsockPort: a.VsockPort,
+			Device:     a.Device,
+			IpAddress:  a.IPAddress,
+			Gateway:    a.Gateway,
+			ChannelId:  a.Channel,
 			MacAddress: a.MAC,
 			Stats: &pb.PacketStats{
 				PacketsSent:     stats.PacketsSent.Load(),
@@ -102,6 +119,7 @@ func (s *server) ListNetworks(ctx context.Context, req *pb.ListNetworksRequest)
 				BytesReceived:   stats.BytesReceived.Load(),
 				SendErrors:      stats.SendErrors.Load(),
 				ReceiveErrors:   stats.ReceiveErrors.Load(),
+				CaptureDrops:    stats.CaptureDrops.Load(),
 			},
 		})
 	}
@@ -131,20 +149,276 @@ func (s *server) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.G
 			BytesReceived:   totalStats.BytesReceived.Load(),
 			SendErrors:      totalStats.SendErrors.Load(),
 			ReceiveErrors:   totalStats.ReceiveErrors.Load(),
+			CaptureDrops:    totalStats.CaptureDrops.Load(),
 		},
 	}, nil
 }
 
+// CapturePackets streams one attachment's live traffic back to the caller
+// as a pcap byte stream: a global header on the first message, then one
+// WriteRecord-framed message per captured packet, so the client can just
+// do `cat > file.pcap` against the concatenated stream. Subscribing never
+// blocks or slows down forwarding (see internal/capture); if the client
+// reads too slowly, packets are dropped and counted in the attachment's
+// CaptureDrops stat rather than backing up.
+func (s *server) CapturePackets(req *pb.CaptureRequest, stream pb.TAPForwarder_CapturePacketsServer) error {
+	attachment, ok := s.forwarder.GetAttachment(req.Device)
+	if !ok {
+		return status.Errorf(codes.NotFound, "device %s not attached", req.Device)
+	}
+
+	filter, err := bpfProgramFromProto(req.FilterProgram)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid BPF filter: %v", err)
+	}
+
+	snaplen := int(req.Snaplen)
+	sub, err := capture.NewSubscriber(filter, snaplen)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if snaplen <= 0 {
+		snaplen = capture.DefaultSnaplen
+	}
+
+	attachment.SubscribeCapture(sub)
+	defer attachment.UnsubscribeCapture(sub)
+
+	var hdr bytes.Buffer
+	if err := capture.WriteGlobalHeader(&hdr, uint32(snaplen)); err != nil {
+		return status.Errorf(codes.Internal, "writing pcap header: %v", err)
+	}
+	if err := stream.Send(&pb.CaptureChunk{Data: hdr.Bytes()}); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	var deadline <-chan time.Time
+	if req.MaxDurationSeconds > 0 {
+		timer := time.NewTimer(time.Duration(req.MaxDurationSeconds) * time.Second)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return nil
+		case rec, ok := <-sub.Packets():
+			if !ok {
+				return nil
+			}
+			var buf bytes.Buffer
+			if err := capture.WriteRecord(&buf, rec, uint32(snaplen)); err != nil {
+				return status.Errorf(codes.Internal, "writing pcap record: %v", err)
+			}
+			if err := stream.Send(&pb.CaptureChunk{Data: buf.Bytes()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// StartFileCapture begins a local-mode capture that writes straight to a
+// pcap file on the daemon's own filesystem instead of streaming over this
+// RPC connection, for debugging hot paths where the extra hop would be too
+// expensive. Only one file capture may be active per device at a time.
+func (s *server) StartFileCapture(ctx context.Context, req *pb.StartFileCaptureRequest) (*pb.StartFileCaptureResponse, error) {
+	filter, err := bpfProgramFromProto(req.FilterProgram)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid BPF filter: %v", err)
+	}
+
+	s.fileCapturesMu.Lock()
+	defer s.fileCapturesMu.Unlock()
+
+	if _, exists := s.fileCaptures[req.Device]; exists {
+		return nil, status.Errorf(codes.AlreadyExists, "file capture already running on device %s", req.Device)
+	}
+
+	fc, err := s.forwarder.StartFileCapture(req.Device, req.Path, filter, int64(req.MaxBytes))
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
+	s.fileCaptures[req.Device] = fc
+
+	log.Printf("StartFileCapture: device=%s path=%s", req.Device, req.Path)
+
+	return &pb.StartFileCaptureResponse{Success: true}, nil
+}
+
+// StopFileCapture ends a capture started by StartFileCapture.
+func (s *server) StopFileCapture(ctx context.Context, req *pb.StopFileCaptureRequest) (*pb.StopFileCaptureResponse, error) {
+	s.fileCapturesMu.Lock()
+	fc, exists := s.fileCaptures[req.Device]
+	if exists {
+		delete(s.fileCaptures, req.Device)
+	}
+	s.fileCapturesMu.Unlock()
+
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "no file capture running on device %s", req.Device)
+	}
+
+	fc.Stop()
+	log.Printf("StopFileCapture: device=%s", req.Device)
+
+	return &pb.StopFileCaptureResponse{Success: true}, nil
+}
+
+// modeFromProto converts the wire-format network mode from an
+// AttachNetworkRequest into the forwarder.Mode it selects. An unset/unknown
+// value defaults to ModeRawL2, the cheaper and historically only mode.
+func modeFromProto(mode pb.NetworkMode) forwarder.Mode {
+	if mode == pb.NetworkMode_NETWORK_MODE_NETSTACK_L3 {
+		return forwarder.ModeNetstackL3
+	}
+	return forwarder.ModeRawL2
+}
+
+// SetFirewallRules replaces a netstack-mode attachment's 5-tuple allow/deny
+// list. It's a no-op error for RAW_L2 attachments, which have no L3
+// visibility to filter on.
+func (s *server) SetFirewallRules(ctx context.Context, req *pb.SetFirewallRulesRequest) (*pb.SetFirewallRulesResponse, error) {
+	attachment, ok := s.forwarder.GetAttachment(req.Device)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "device %s not attached", req.Device)
+	}
+
+	rules := make([]forwarder.FirewallRule, 0, len(req.Rules))
+	for i, r := range req.Rules {
+		rule := forwarder.FirewallRule{
+			Proto:   protoFromWire(r.Proto),
+			SrcPort: uint16(r.SrcPort),
+			DstPort: uint16(r.DstPort),
+			Action:  actionFromWire(r.Action),
+		}
+		if r.SrcCidr != "" {
+			_, cidr, err := net.ParseCIDR(r.SrcCidr)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "rule %d: invalid src_cidr %q: %v", i, r.SrcCidr, err)
+			}
+			rule.SrcCIDR = cidr
+		}
+		if r.DstCidr != "" {
+			_, cidr, err := net.ParseCIDR(r.DstCidr)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "rule %d: invalid dst_cidr %q: %v", i, r.DstCidr, err)
+			}
+			rule.DstCIDR = cidr
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := attachment.SetFirewallRules(rules); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
+
+	log.Printf("SetFirewallRules: device=%s rules=%d", req.Device, len(rules))
+
+	return &pb.SetFirewallRulesResponse{Success: true}, nil
+}
+
+// ListFlows returns a netstack-mode attachment's current per-flow
+// packet/byte counters.
+func (s *server) ListFlows(ctx context.Context, req *pb.ListFlowsRequest) (*pb.ListFlowsResponse, error) {
+	attachment, ok := s.forwarder.GetAttachment(req.Device)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "device %s not attached", req.Device)
+	}
+
+	flows, err := attachment.ListFlows()
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
+
+	resp := &pb.ListFlowsResponse{Flows: make([]*pb.FlowInfo, 0, len(flows))}
+	for _, f := range flows {
+		resp.Flows = append(resp.Flows, &pb.FlowInfo{
+			SrcAddress: net.IP(f.Key.SrcAddr.AsSlice()).String(),
+			DstAddress: net.IP(f.Key.DstAddr.AsSlice()).String(),
+			Proto:      f.Key.Proto.String(),
+			SrcPort:    uint32(f.Key.SrcPort),
+			DstPort:    uint32(f.Key.DstPort),
+			Packets:    f.Packets,
+			Bytes:      f.Bytes,
+		})
+	}
+
+	return resp, nil
+}
+
+// protoFromWire converts a SetFirewallRulesRequest rule's wire-format
+// transport protocol into the forwarder.Proto it matches on.
+func protoFromWire(p pb.FirewallRule_Proto) forwarder.Proto {
+	switch p {
+	case pb.FirewallRule_PROTO_TCP:
+		return forwarder.ProtoTCP
+	case pb.FirewallRule_PROTO_UDP:
+		return forwarder.ProtoUDP
+	case pb.FirewallRule_PROTO_ICMP:
+		return forwarder.ProtoICMP
+	default:
+		return forwarder.ProtoAny
+	}
+}
+
+// actionFromWire converts a SetFirewallRulesRequest rule's wire-format
+// action into the forwarder.Action it applies on a match.
+func actionFromWire(a pb.FirewallRule_Action) forwarder.Action {
+	if a == pb.FirewallRule_ACTION_DENY {
+		return forwarder.ActionDeny
+	}
+	return forwarder.ActionAllow
+}
+
+// bpfProgramFromProto converts the wire-format BPF program from a
+// CaptureRequest (the same raw {op, jt, jf, k} quadruples `tcpdump -dd`
+// prints) into the instruction list capture.NewSubscriber expects.
+func bpfProgramFromProto(prog []*pb.BpfInstruction) ([]bpf.Instruction, error) {
+	if len(prog) == 0 {
+		return nil, nil
+	}
+	instructions := make([]bpf.Instruction, len(prog))
+	for i, raw := range prog {
+		ins, ok := bpf.RawInstruction{
+			Op: uint16(raw.Op),
+			Jt: uint8(raw.Jt),
+			Jf: uint8(raw.Jf),
+			K:  raw.K,
+		}.Disassemble()
+		if !ok {
+			return nil, fmt.Errorf("instruction %d: unrecognized BPF opcode %#x", i, raw.Op)
+		}
+		instructions[i] = ins
+	}
+	return instructions, nil
+}
+
 func main() {
-	log.Printf("Arca TAP Forwarder Daemon starting... version=%s control_port=%d", VERSION, CONTROL_PORT)
+	diagPort := flag.Int("diag-port", 0, "if nonzero, start a loopback-only diagnostic HTTP server (pprof, metrics, attachments, flows, capture) on this port; off by default")
+	flag.Parse()
+
+	log.Printf("Arca TAP Forwarder Daemon starting... version=%s control_port=%d data_port=%d", VERSION, CONTROL_PORT, DATA_PORT)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// Create forwarder
 	fwd := forwarder.New()
 
+	var diagSrv *http.Server
+	if *diagPort != 0 {
+		diagSrv = startDiagServer(fwd, *diagPort)
+	}
+
 	// Create gRPC server
 	grpcServer := grpc.NewServer()
 	pb.RegisterTAPForwarderServer(grpcServer, &server{
-		forwarder: fwd,
+		forwarder:    fwd,
+		fileCaptures: make(map[string]*forwarder.FileCapture),
 	})
 
 	// Listen on vsock
@@ -156,10 +430,6 @@ func main() {
 
 	log.Printf("gRPC server listening on vsock port %d", CONTROL_PORT)
 
-	// Handle shutdown signals
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
 	// Start gRPC server in goroutine
 	go func() {
 		if err := grpcServer.Serve(listener); err != nil {
@@ -167,12 +437,24 @@ func main() {
 		}
 	}()
 
+	// Start the shared multiplexed data connection in the background; it
+	// runs for the daemon's lifetime and reconnects are handled internally
+	// by Forwarder.Serve/setMux.
+	go func() {
+		if err := fwd.Serve(ctx, DATA_PORT); err != nil {
+			log.Fatalf("Forwarder data connection failed: %v", err)
+		}
+	}()
+
 	// Wait for shutdown signal
-	sig := <-sigChan
-	log.Printf("Received signal %v, shutting down...", sig)
+	<-ctx.Done()
+	log.Println("Received shutdown signal, shutting down...")
 
 	// Graceful shutdown
 	grpcServer.GracefulStop()
+	if diagSrv != nil {
+		diagSrv.Close()
+	}
 
 	log.Println("Arca TAP Forwarder Daemon stopped")
-}
\ No newline at end of file
+}
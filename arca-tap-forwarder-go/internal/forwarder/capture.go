@@ -0,0 +1,81 @@
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/vas-solutus/arca-tap-forwarder/internal/capture"
+	"golang.org/x/net/bpf"
+)
+
+// FileCapture is a running local-mode capture started by StartFileCapture.
+// Call Stop to end it early; it also stops on its own once maxBytes bytes
+// have been written.
+type FileCapture struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Stop ends the capture and waits for its goroutine to finish flushing and
+// closing the output file.
+func (c *FileCapture) Stop() {
+	c.cancel()
+	<-c.done
+}
+
+// StartFileCapture subscribes to device's live traffic and writes every
+// matching packet straight to a pcap file at path, bypassing the
+// CapturePackets RPC entirely. It's meant for debugging the daemon's own
+// hot paths, where streaming packets back over a gRPC/vsock hop would be
+// too expensive or would itself perturb what's being measured. filter may
+// be nil to capture everything. The capture stops once maxBytes bytes have
+// been written (0 means unlimited) or Stop is called.
+func (f *Forwarder) StartFileCapture(device string, path string, filter []bpf.Instruction, maxBytes int64) (*FileCapture, error) {
+	attachment, ok := f.GetAttachment(device)
+	if !ok {
+		return nil, fmt.Errorf("device %s not attached", device)
+	}
+
+	sub, err := capture.NewSubscriber(filter, capture.DefaultSnaplen)
+	if err != nil {
+		return nil, err
+	}
+
+	fw, err := capture.CreateFile(path, capture.DefaultSnaplen)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment.SubscribeCapture(sub)
+
+	ctx, cancel := context.WithCancel(attachment.ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer fw.Close()
+		defer attachment.UnsubscribeCapture(sub)
+
+		var written int64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case rec, ok := <-sub.Packets():
+				if !ok {
+					return
+				}
+				if err := fw.Write(rec); err != nil {
+					log.Printf("file capture on %s: writing to %s: %v", device, path, err)
+					return
+				}
+				written += int64(len(rec.Data))
+				if maxBytes > 0 && written >= maxBytes {
+					return
+				}
+			}
+		}
+	}()
+
+	return &FileCapture{cancel: cancel, done: done}, nil
+}
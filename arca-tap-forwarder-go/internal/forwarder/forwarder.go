@@ -11,23 +11,43 @@ import (
 	"sync"
 	"sync/atomic"
 
-	"github.com/mdlayher/vsock"
-	"github.com/vas-solutus/arca-tap-forwarder/internal/tap"
+	"github.com/vas-solutus/arca-tap-forwarder/internal/capture"
+	"github.com/vas-solutus/arca-tap-forwarder/internal/relay"
 )
 
 // NetworkAttachment represents an active network interface with forwarding
 type NetworkAttachment struct {
 	Device    string
-	VsockPort uint32
+	Channel   uint32
 	IPAddress string
 	Gateway   string
 	MAC       string
+	Mode      Mode
 
-	tap        *tap.TAP
-	vsockConn  net.Conn
-	cancel     context.CancelFunc
-	stats      Stats
-	statsLock  sync.RWMutex
+	tap       TapDevice
+	ctx       context.Context
+	cancel    context.CancelFunc
+	stats     Stats
+	statsLock sync.RWMutex
+	cfg       Config
+
+	genMu     sync.Mutex
+	mux       *relay.Mux
+	genCancel context.CancelFunc
+
+	capture *capture.Hub
+
+	// ns is non-nil only for Mode == ModeNetstackL3 attachments; see
+	// netstack.go.
+	ns *netstackState
+}
+
+// batchReader is implemented by TAP devices that support reading several
+// packets per syscall (see tap.TAP.ReadBatch on Linux). Devices that don't
+// support it (other platforms, or the unsupported-platform stub) just fall
+// back to one Read per packet via the ok-false path below.
+type batchReader interface {
+	ReadBatch(bufs [][]byte, sizes []int) (int, error)
 }
 
 // Stats tracks packet statistics
@@ -38,33 +58,156 @@ type Stats struct {
 	BytesReceived   atomic.Uint64
 	SendErrors      atomic.Uint64
 	ReceiveErrors   atomic.Uint64
+	// CaptureDrops counts packets dropped from a capture subscriber's ring
+	// buffer because it fell behind - forwarding itself never waits on a
+	// capture client, so drops here never slow down the data path.
+	CaptureDrops atomic.Uint64
+}
+
+// Config tunes the batched TAP<->vsock forwarding path.
+type Config struct {
+	// MaxBatch is the maximum number of packets read from a TAP device in
+	// one ReadBatch call before they're shipped out to vsock.
+	MaxBatch int
+	// MaxBatchBytes caps the total bytes read in one batch, so a run of
+	// jumbo frames can't grow a batch unboundedly even if MaxBatch allows
+	// more packets.
+	MaxBatchBytes int
 }
 
-// Forwarder manages multiple network attachments
+// DefaultConfig returns the forwarding tunables used when New is called
+// without an explicit Config.
+func DefaultConfig() Config {
+	return Config{
+		MaxBatch:      64,
+		MaxBatchBytes: 1 << 20, // 1 MiB
+	}
+}
+
+// Forwarder manages multiple network attachments, all of whose packets
+// travel over one shared, multiplexed vsock connection (see internal/relay)
+// rather than one vsock.Listen per device.
 type Forwarder struct {
 	attachments map[string]*NetworkAttachment
 	mu          sync.RWMutex
+	cfg         Config
+	nextChannel atomic.Uint32
+	mux         *relay.Mux
+
+	transport  Transport
+	tapFactory TapFactory
 }
 
-// New creates a new Forwarder
-func New() *Forwarder {
-	return &Forwarder{
+// Option configures optional Forwarder behavior. The zero value of every
+// Option-settable field is the real vsock/kernel-TAP implementation;
+// forwardertest uses WithTransport and WithTapFactory to substitute
+// in-memory equivalents so Forwarder can be exercised without the vsock
+// kernel module or root privileges.
+type Option func(*Forwarder)
+
+// WithTransport overrides the listener Serve accepts the host's data
+// connection on.
+func WithTransport(t Transport) Option {
+	return func(f *Forwarder) { f.transport = t }
+}
+
+// WithTapFactory overrides how AttachNetwork creates the TAP device backing
+// a new attachment.
+func WithTapFactory(tf TapFactory) Option {
+	return func(f *Forwarder) { f.tapFactory = tf }
+}
+
+// New creates a new Forwarder using DefaultConfig.
+func New(opts ...Option) *Forwarder {
+	return NewWithConfig(DefaultConfig(), opts...)
+}
+
+// NewWithConfig creates a new Forwarder with explicit batching tunables.
+func NewWithConfig(cfg Config, opts ...Option) *Forwarder {
+	if cfg.MaxBatch < 1 {
+		cfg.MaxBatch = 1
+	}
+	f := &Forwarder{
 		attachments: make(map[string]*NetworkAttachment),
+		cfg:         cfg,
+		transport:   vsockTransport{},
+		tapFactory:  realTapFactory{},
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Serve listens on dataPort for the host's shared multiplexed data
+// connection and runs until ctx is canceled. A reconnect (the host relay
+// restarting) replaces the previous connection and rebinds every existing
+// attachment to it, rather than requiring every network to be re-attached
+// from scratch.
+func (f *Forwarder) Serve(ctx context.Context, dataPort uint32) error {
+	listener, err := f.transport.Listen(dataPort)
+	if err != nil {
+		return fmt.Errorf("failed to listen on vsock data port %d: %w", dataPort, err)
+	}
+	defer listener.Close()
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	log.Printf("Waiting for host data connection on vsock port %d", dataPort)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accepting vsock data connection: %w", err)
+		}
+		log.Printf("Host connected on vsock data port %d", dataPort)
+		f.setMux(conn)
 	}
 }
 
-// AttachNetwork creates a TAP device and starts forwarding packets to/from vsock
-func (f *Forwarder) AttachNetwork(device string, vsockPort uint32, ipAddress string, gateway string, netmask uint32) (*NetworkAttachment, error) {
+// setMux installs mux as the shared connection every attachment forwards
+// over, closing out whatever connection preceded it and rebinding every
+// existing attachment to the new one.
+func (f *Forwarder) setMux(conn net.Conn) {
+	mux := relay.NewMux(conn)
+
 	f.mu.Lock()
-	defer f.mu.Unlock()
+	old := f.mux
+	f.mux = mux
+	attachments := make([]*NetworkAttachment, 0, len(f.attachments))
+	for _, a := range f.attachments {
+		attachments = append(attachments, a)
+	}
+	f.mu.Unlock()
 
-	// Check if already attached
+	if old != nil {
+		old.Close()
+	}
+	for _, a := range attachments {
+		a.rebind(mux)
+	}
+}
+
+// AttachNetwork creates a TAP device and allocates it a channel on the
+// shared data connection. The channel ID is returned so the caller can
+// hand it back to the host, which sends an ATTACH frame for that channel
+// on the shared connection before it starts pumping DATA frames.
+func (f *Forwarder) AttachNetwork(device string, ipAddress string, gateway string, netmask uint32, mode Mode) (*NetworkAttachment, error) {
+	f.mu.Lock()
 	if _, exists := f.attachments[device]; exists {
+		f.mu.Unlock()
 		return nil, fmt.Errorf("device %s already attached", device)
 	}
+	mux := f.mux
+	f.mu.Unlock()
 
 	// Create TAP device
-	tapDev, err := tap.Create(device)
+	tapDev, err := f.tapFactory.Create(device)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create TAP device: %w", err)
 	}
@@ -90,58 +233,80 @@ func (f *Forwarder) AttachNetwork(device string, vsockPort uint32, ipAddress str
 		}
 	}
 
-	// Listen on vsock port for host connection
-	listener, err := vsock.Listen(vsockPort, nil)
-	if err != nil {
-		tapDev.Close()
-		return nil, fmt.Errorf("failed to listen on vsock port %d: %w", vsockPort, err)
-	}
-
-	// Create attachment (vsockConn will be set when host connects)
+	channel := f.nextChannel.Add(1)
 	ctx, cancel := context.WithCancel(context.Background())
 	attachment := &NetworkAttachment{
 		Device:    device,
-		VsockPort: vsockPort,
+		Channel:   channel,
 		IPAddress: ipAddress,
 		Gateway:   gateway,
 		MAC:       tapDev.MAC().String(),
+		Mode:      mode,
 		tap:       tapDev,
-		vsockConn: nil, // Will be set when host connects
+		ctx:       ctx,
 		cancel:    cancel,
+		cfg:       f.cfg,
+		capture:   capture.NewHub(),
 	}
 
-	// Accept connection from host in background
-	go func() {
-		log.Printf("Waiting for host connection on vsock port %d for device %s", vsockPort, device)
-		conn, err := listener.Accept()
+	if mode == ModeNetstackL3 {
+		ns, err := setupNetstack(tapDev, ipAddress, netmask)
 		if err != nil {
-			log.Printf("Failed to accept vsock connection on port %d: %v", vsockPort, err)
+			tapDev.Close()
 			cancel()
-			return
+			return nil, fmt.Errorf("failed to set up netstack for %s: %w", device, err)
 		}
+		attachment.ns = ns
+	}
 
-		attachment.vsockConn = conn
-		log.Printf("Host connected to vsock port %d for device %s", vsockPort, device)
-
-		// Start bidirectional forwarding now that we have the connection
-		go attachment.forwardTAPtoVsock(ctx)
-		go attachment.forwardVsockToTAP(ctx)
-	}()
-
+	f.mu.Lock()
 	f.attachments[device] = attachment
+	f.mu.Unlock()
+
+	if mux != nil {
+		attachment.rebind(mux)
+	}
+	// If no host connection exists yet, the attachment is bound the next
+	// time setMux runs.
 
-	log.Printf("Network attached: device=%s vsock_port=%d ip=%s mac=%s",
-		device, vsockPort, ipAddress, attachment.MAC)
+	log.Printf("Network attached: device=%s channel=%d ip=%s mac=%s",
+		device, channel, ipAddress, attachment.MAC)
 
 	return attachment, nil
 }
 
+// rebind (re)starts forwarding for the attachment against mux, canceling
+// whichever goroutines were forwarding against a previous connection
+// first - used both on first attach and on a host reconnect.
+func (a *NetworkAttachment) rebind(mux *relay.Mux) {
+	a.genMu.Lock()
+	if a.genCancel != nil {
+		a.genCancel()
+	}
+	genCtx, genCancel := context.WithCancel(a.ctx)
+	a.mux = mux
+	a.genCancel = genCancel
+	a.genMu.Unlock()
+
+	inbound := mux.Register(a.Channel)
+	if a.Mode == ModeNetstackL3 {
+		go a.forwardNetstackToVsock(genCtx, mux)
+		go a.forwardVsockToNetstack(genCtx, inbound)
+		return
+	}
+	go a.forwardTAPtoVsock(genCtx, mux)
+	go a.forwardVsockToTAP(genCtx, inbound)
+}
+
 // DetachNetwork stops forwarding and destroys the TAP device
 func (f *Forwarder) DetachNetwork(device string) error {
 	f.mu.Lock()
-	defer f.mu.Unlock()
-
 	attachment, exists := f.attachments[device]
+	if exists {
+		delete(f.attachments, device)
+	}
+	f.mu.Unlock()
+
 	if !exists {
 		return fmt.Errorf("device %s not found", device)
 	}
@@ -149,21 +314,39 @@ func (f *Forwarder) DetachNetwork(device string) error {
 	// Stop forwarding
 	attachment.cancel()
 
-	// Close connections
-	if attachment.vsockConn != nil {
-		attachment.vsockConn.Close()
+	attachment.genMu.Lock()
+	mux := attachment.mux
+	attachment.genMu.Unlock()
+	if mux != nil {
+		mux.Unregister(attachment.Channel)
+		if err := mux.Send(attachment.Channel, relay.FrameDetach, nil); err != nil {
+			log.Printf("Failed to notify host of detach for %s: %v", device, err)
+		}
 	}
+
+	attachment.closeNetstack()
+
 	if attachment.tap != nil {
 		attachment.tap.Close()
 	}
 
-	delete(f.attachments, device)
-
 	log.Printf("Network detached: device=%s", device)
 
 	return nil
 }
 
+// DataConnectionState reports whether Serve currently has a live host data
+// connection bound ("connected") or is still waiting to accept one
+// ("waiting"), for diagnostics - see cmd/arca-tap-forwarder's diag server.
+func (f *Forwarder) DataConnectionState() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.mux != nil {
+		return "connected"
+	}
+	return "waiting"
+}
+
 // GetAttachment returns the attachment for a device
 func (f *Forwarder) GetAttachment(device string) (*NetworkAttachment, bool) {
 	f.mu.RLock()
@@ -198,14 +381,35 @@ func (f *Forwarder) GetTotalStats() Stats {
 		total.BytesReceived.Add(attachment.stats.BytesReceived.Load())
 		total.SendErrors.Add(attachment.stats.SendErrors.Load())
 		total.ReceiveErrors.Add(attachment.stats.ReceiveErrors.Load())
+		total.CaptureDrops.Add(attachment.stats.CaptureDrops.Load())
 		attachment.statsLock.RUnlock()
 	}
 	return total
 }
 
-// forwardTAPtoVsock forwards packets from TAP device to vsock
-func (a *NetworkAttachment) forwardTAPtoVsock(ctx context.Context) {
-	buf := make([]byte, 65536) // Max Ethernet frame size
+// forwardTAPtoVsock forwards packets from the TAP device to the shared
+// mux as channel-scoped DATA frames. When the underlying TAP device
+// supports ReadBatch, up to cfg.MaxBatch packets are pulled from the TAP
+// in one call (capped by cfg.MaxBatchBytes) before being shipped out,
+// amortizing the per-packet read-loop overhead on the hot path. Devices
+// without batch support fall back to one packet per iteration, exactly as
+// before. mux.Send serializes this attachment's frames against every
+// other channel's through the mux's single writer goroutine, so the
+// shared connection never sees interleaved partial frames.
+func (a *NetworkAttachment) forwardTAPtoVsock(ctx context.Context, mux *relay.Mux) {
+	maxBatch := a.cfg.MaxBatch
+	if maxBatch < 1 {
+		maxBatch = 1
+	}
+	maxBatchBytes := a.cfg.MaxBatchBytes
+
+	br, batched := a.tap.(batchReader)
+
+	bufs := make([][]byte, maxBatch)
+	sizes := make([]int, maxBatch)
+	for i := range bufs {
+		bufs[i] = make([]byte, 65536) // Max Ethernet frame size
+	}
 
 	for {
 		select {
@@ -214,82 +418,133 @@ func (a *NetworkAttachment) forwardTAPtoVsock(ctx context.Context) {
 		default:
 		}
 
-		// Read from TAP device
-		n, err := a.tap.Read(buf)
-		if err != nil {
-			if err != io.EOF {
-				a.stats.ReceiveErrors.Add(1)
-				log.Printf("TAP read error on %s: %v", a.Device, err)
+		var count int
+		if batched {
+			n, err := br.ReadBatch(bufs, sizes)
+			if err != nil {
+				if err != io.EOF {
+					a.stats.ReceiveErrors.Add(1)
+					log.Printf("TAP read error on %s: %v", a.Device, err)
+				}
+				return
 			}
-			return
+			count = n
+		} else {
+			n, err := a.tap.Read(bufs[0])
+			if err != nil {
+				if err != io.EOF {
+					a.stats.ReceiveErrors.Add(1)
+					log.Printf("TAP read error on %s: %v", a.Device, err)
+				}
+				return
+			}
+			sizes[0] = n
+			count = 1
 		}
 
-		a.stats.PacketsReceived.Add(1)
-		a.stats.BytesReceived.Add(uint64(n))
+		batchBytes := 0
+		for i := 0; i < count; i++ {
+			n := sizes[i]
+			batchBytes += n
 
-		// Log first few packets for debugging
-		if a.stats.PacketsReceived.Load() <= 5 {
-			log.Printf("TAP->vsock: device=%s bytes=%d packet=%d", a.Device, n, a.stats.PacketsReceived.Load())
-		}
+			a.stats.PacketsReceived.Add(1)
+			a.stats.BytesReceived.Add(uint64(n))
 
-		// Write to vsock
-		_, err = a.vsockConn.Write(buf[:n])
-		if err != nil {
-			a.stats.SendErrors.Add(1)
-			log.Printf("vsock write error on %s: %v", a.Device, err)
-			return
-		}
+			// Log first few packets for debugging
+			if a.stats.PacketsReceived.Load() <= 5 {
+				log.Printf("TAP->vsock: device=%s channel=%d bytes=%d packet=%d", a.Device, a.Channel, n, a.stats.PacketsReceived.Load())
+			}
+
+			if a.capture.HasSubscribers() {
+				a.stats.CaptureDrops.Add(uint64(a.capture.Offer(capture.DirectionIn, bufs[i][:n])))
+			}
 
-		a.stats.PacketsSent.Add(1)
-		a.stats.BytesSent.Add(uint64(n))
+			if err := mux.Send(a.Channel, relay.FrameData, bufs[i][:n]); err != nil {
+				a.stats.SendErrors.Add(1)
+				log.Printf("vsock write error on %s: %v", a.Device, err)
+				return
+			}
+
+			a.stats.PacketsSent.Add(1)
+			a.stats.BytesSent.Add(uint64(n))
+
+			if maxBatchBytes > 0 && batchBytes >= maxBatchBytes {
+				break
+			}
+		}
 	}
 }
 
-// forwardVsockToTAP forwards packets from vsock to TAP device
-func (a *NetworkAttachment) forwardVsockToTAP(ctx context.Context) {
-	buf := make([]byte, 65536) // Max Ethernet frame size
+// forwardVsockToTAP forwards frames delivered on inbound (this
+// attachment's channel-scoped queue from the shared mux) to the TAP
+// device. The first frame from the host is expected to be an ATTACH
+// handshake; DATA frames are written to the TAP, and a DETACH from the
+// host ends forwarding the same way a local DetachNetwork call would.
+func (a *NetworkAttachment) forwardVsockToTAP(ctx context.Context, inbound <-chan relay.Frame) {
 	var reversePackets atomic.Uint64
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		default:
-		}
 
-		// Read from vsock
-		n, err := a.vsockConn.Read(buf)
-		if err != nil {
-			if err != io.EOF {
-				a.stats.ReceiveErrors.Add(1)
-				log.Printf("vsock read error on %s: %v", a.Device, err)
+		case f, ok := <-inbound:
+			if !ok {
+				return
 			}
-			return
-		}
 
-		reversePackets.Add(1)
+			switch f.Type {
+			case relay.FrameAttach:
+				log.Printf("Host ATTACH for device=%s channel=%d", a.Device, a.Channel)
+				continue
+			case relay.FrameKeepalive:
+				continue
+			case relay.FrameDetach:
+				log.Printf("Host DETACH for device=%s channel=%d", a.Device, a.Channel)
+				return
+			case relay.FrameData:
+				// handled below
+			default:
+				log.Printf("Unknown frame type %s on channel %d, ignoring", f.Type, a.Channel)
+				continue
+			}
 
-		// Log first few packets for debugging
-		if reversePackets.Load() <= 5 {
-			log.Printf("vsock->TAP: device=%s bytes=%d packet=%d", a.Device, n, reversePackets.Load())
-		}
+			reversePackets.Add(1)
+			if reversePackets.Load() <= 5 {
+				log.Printf("vsock->TAP: device=%s channel=%d bytes=%d packet=%d", a.Device, a.Channel, len(f.Payload), reversePackets.Load())
+			}
 
-		a.stats.PacketsReceived.Add(1)
-		a.stats.BytesReceived.Add(uint64(n))
+			a.stats.PacketsReceived.Add(1)
+			a.stats.BytesReceived.Add(uint64(len(f.Payload)))
 
-		// Write to TAP device
-		_, err = a.tap.Write(buf[:n])
-		if err != nil {
-			a.stats.SendErrors.Add(1)
-			log.Printf("TAP write error on %s: %v", a.Device, err)
-			return
-		}
+			if a.capture.HasSubscribers() {
+				a.stats.CaptureDrops.Add(uint64(a.capture.Offer(capture.DirectionOut, f.Payload)))
+			}
 
-		a.stats.PacketsSent.Add(1)
-		a.stats.BytesSent.Add(uint64(n))
+			if _, err := a.tap.Write(f.Payload); err != nil {
+				a.stats.SendErrors.Add(1)
+				log.Printf("TAP write error on %s: %v", a.Device, err)
+				return
+			}
+
+			a.stats.PacketsSent.Add(1)
+			a.stats.BytesSent.Add(uint64(len(f.Payload)))
+		}
 	}
 }
 
+// SubscribeCapture registers sub to receive a filtered, non-blocking copy
+// of this attachment's traffic in both directions (see internal/capture)
+// until UnsubscribeCapture is called.
+func (a *NetworkAttachment) SubscribeCapture(sub *capture.Subscriber) {
+	a.capture.Subscribe(sub)
+}
+
+// UnsubscribeCapture stops delivery to sub and closes its output channel.
+func (a *NetworkAttachment) UnsubscribeCapture(sub *capture.Subscriber) {
+	a.capture.Unsubscribe(sub)
+}
+
 // GetStats returns a copy of the current statistics
 func (a *NetworkAttachment) GetStats() Stats {
 	a.statsLock.RLock()
@@ -303,6 +558,7 @@ func (a *NetworkAttachment) GetStats() Stats {
 	stats.BytesReceived.Store(a.stats.BytesReceived.Load())
 	stats.SendErrors.Store(a.stats.SendErrors.Load())
 	stats.ReceiveErrors.Store(a.stats.ReceiveErrors.Load())
+	stats.CaptureDrops.Store(a.stats.CaptureDrops.Load())
 
 	return stats
 }
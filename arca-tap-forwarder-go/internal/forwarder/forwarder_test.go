@@ -0,0 +1,107 @@
+package forwarder_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vas-solutus/arca-tap-forwarder/internal/forwarder"
+	"github.com/vas-solutus/arca-tap-forwarder/internal/forwardertest"
+	"github.com/vas-solutus/arca-tap-forwarder/internal/relay"
+)
+
+const testDataPort = 5556
+
+// readFrame waits up to a few seconds for a Frame on ch, failing the test
+// on timeout instead of hanging forever if forwarding is broken.
+func readFrame(t *testing.T, ch <-chan relay.Frame) relay.Frame {
+	t.Helper()
+	select {
+	case f := <-ch:
+		return f
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for frame")
+		return relay.Frame{}
+	}
+}
+
+func TestForwarderEndToEnd(t *testing.T) {
+	transport := forwardertest.NewMemTransport()
+	taps := forwardertest.NewFakeTapFactory()
+	fwd := forwarder.New(
+		forwarder.WithTransport(transport),
+		forwarder.WithTapFactory(taps),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- fwd.Serve(ctx, testDataPort) }()
+
+	attachment, err := fwd.AttachNetwork("eth0", "10.0.0.2", "10.0.0.1", 24, forwarder.ModeRawL2)
+	if err != nil {
+		t.Fatalf("AttachNetwork: %v", err)
+	}
+
+	fakeTap, ok := taps.Device("eth0")
+	if !ok {
+		t.Fatal("no FakeTAP registered for eth0")
+	}
+	if !fakeTap.IsUp() {
+		t.Fatal("expected BringUp to have been called")
+	}
+	if ip, netmask := fakeTap.IPAddress(); ip != "10.0.0.2" || netmask != 24 {
+		t.Fatalf("IPAddress() = %s/%d, want 10.0.0.2/24", ip, netmask)
+	}
+
+	// Play the host side: dial in, wrap the connection in a Mux exactly as
+	// the real host relay would, and register the attachment's channel to
+	// receive frames.
+	hostConn, err := transport.Dial(testDataPort)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	hostMux := relay.NewMux(hostConn)
+	defer hostMux.Close()
+	hostInbound := hostMux.Register(attachment.Channel)
+
+	// Container -> host: a frame written to the FakeTAP's inbound side
+	// should show up on the host's channel-scoped mux queue.
+	outFrame := []byte("pretend-ethernet-frame-out")
+	if err := fakeTap.Inbound(outFrame); err != nil {
+		t.Fatalf("Inbound: %v", err)
+	}
+	got := readFrame(t, hostInbound)
+	if got.Type != relay.FrameData || !bytes.Equal(got.Payload, outFrame) {
+		t.Fatalf("host received %+v, want DATA frame with payload %q", got, outFrame)
+	}
+
+	// Host -> container: a DATA frame sent from the host side should come
+	// back out of the FakeTAP's outbound side.
+	inFrame := []byte("pretend-ethernet-frame-in")
+	if err := hostMux.Send(attachment.Channel, relay.FrameData, inFrame); err != nil {
+		t.Fatalf("hostMux.Send: %v", err)
+	}
+	buf := make([]byte, 1500)
+	n, err := fakeTap.Outbound(buf)
+	if err != nil {
+		t.Fatalf("Outbound: %v", err)
+	}
+	if !bytes.Equal(buf[:n], inFrame) {
+		t.Fatalf("FakeTAP received %q, want %q", buf[:n], inFrame)
+	}
+
+	stats := attachment.GetStats()
+	if stats.PacketsSent.Load() == 0 || stats.PacketsReceived.Load() == 0 {
+		t.Fatalf("expected nonzero packet counters, got %+v", stats)
+	}
+
+	if err := fwd.DetachNetwork("eth0"); err != nil {
+		t.Fatalf("DetachNetwork: %v", err)
+	}
+	if _, ok := fwd.GetAttachment("eth0"); ok {
+		t.Fatal("expected eth0 to be gone after DetachNetwork")
+	}
+}
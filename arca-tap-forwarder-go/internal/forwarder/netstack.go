@@ -0,0 +1,519 @@
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/vas-solutus/arca-tap-forwarder/internal/relay"
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
+	"gvisor.dev/gvisor/pkg/tcpip/link/fdbased"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/icmp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+)
+
+// Mode selects how an attachment's packets travel between the TAP device
+// and the shared vsock connection.
+type Mode int
+
+const (
+	// ModeRawL2 copies Ethernet frames straight between TAP and vsock with
+	// no L3/L4 visibility - the default, and the cheapest path.
+	ModeRawL2 Mode = iota
+	// ModeNetstackL3 routes the attachment's traffic through a per-
+	// attachment gVisor stack instead, so firewall rules and per-flow
+	// stats apply and (eventually) TCP connections can be terminated
+	// locally for proxying. It costs real CPU, so it's opt-in.
+	ModeNetstackL3
+)
+
+func (m Mode) String() string {
+	if m == ModeNetstackL3 {
+		return "netstack-l3"
+	}
+	return "raw-l2"
+}
+
+// netstackNICTAP and netstackNICVsock are the two NICs making up an
+// attachment's netstack: one fed directly from the TAP fd, the other
+// fed from this attachment's channel on the shared vsock mux. The stack
+// forwards IP traffic between them, subject to the attachment's firewall.
+const (
+	netstackNICTAP   = tcpip.NICID(1)
+	netstackNICVsock = tcpip.NICID(2)
+)
+
+const netstackMTU = 1500
+
+// fdProvider is implemented by TapDevices that expose a raw fd gVisor's
+// fdbased link endpoint can attach directly to (currently *tap.TAP on Linux
+// only; see tap.TAP.Fd). NETSTACK_L3 mode is unavailable where it isn't.
+type fdProvider interface {
+	Fd() int
+}
+
+// netstackState holds the gVisor plumbing and policy/accounting state
+// backing an attachment created with ModeNetstackL3.
+type netstackState struct {
+	s        *stack.Stack
+	vsockEP  *channel.Endpoint
+	firewall *firewall
+	flows    *flowTable
+}
+
+// setupNetstack builds the per-attachment stack bridging tapDev (via
+// fdbased, attached as netstackNICTAP) and the shared vsock connection
+// (via a channel.Endpoint, attached as netstackNICVsock). Actual traffic
+// only starts flowing once rebind starts forwardNetstackToVsock/
+// forwardVsockToNetstack against a live mux.
+func setupNetstack(tapDev TapDevice, ipAddress string, netmask uint32) (*netstackState, error) {
+	fdp, ok := tapDev.(fdProvider)
+	if !ok {
+		return nil, fmt.Errorf("netstack: ModeNetstackL3 is not supported on this platform")
+	}
+
+	s := stack.New(stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{tcp.NewProtocol, udp.NewProtocol, icmp.NewProtocol4, icmp.NewProtocol6},
+	})
+
+	fw := newFirewall()
+	flows := newFlowTable()
+
+	tapEP, err := fdbased.New(&fdbased.Options{
+		FDs:            []int{fdp.Fd()},
+		MTU:            netstackMTU,
+		EthernetHeader: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("netstack: fdbased.New: %w", err)
+	}
+	if err := s.CreateNIC(netstackNICTAP, &filteringEndpoint{LinkEndpoint: tapEP, dir: DirectionFromTAP, fw: fw, flows: flows}); err != nil {
+		return nil, fmt.Errorf("netstack: CreateNIC(tap): %s", err)
+	}
+
+	vsockEP := channel.New(256 /* queue length */, netstackMTU, "" /* no link address - L3 only */)
+	if err := s.CreateNIC(netstackNICVsock, &filteringEndpoint{LinkEndpoint: vsockEP, dir: DirectionFromVsock, fw: fw, flows: flows}); err != nil {
+		return nil, fmt.Errorf("netstack: CreateNIC(vsock): %s", err)
+	}
+
+	if err := s.SetSpoofing(netstackNICTAP, true); err != nil {
+		return nil, fmt.Errorf("netstack: SetSpoofing(tap): %s", err)
+	}
+	if err := s.SetPromiscuousMode(netstackNICTAP, true); err != nil {
+		return nil, fmt.Errorf("netstack: SetPromiscuousMode(tap): %s", err)
+	}
+	if err := s.SetSpoofing(netstackNICVsock, true); err != nil {
+		return nil, fmt.Errorf("netstack: SetSpoofing(vsock): %s", err)
+	}
+	if err := s.SetPromiscuousMode(netstackNICVsock, true); err != nil {
+		return nil, fmt.Errorf("netstack: SetPromiscuousMode(vsock): %s", err)
+	}
+	for _, nic := range []tcpip.NICID{netstackNICTAP, netstackNICVsock} {
+		for _, proto := range []tcpip.NetworkProtocolNumber{ipv4.ProtocolNumber, ipv6.ProtocolNumber} {
+			if err := s.SetNICForwarding(nic, proto, true); err != nil {
+				return nil, fmt.Errorf("netstack: SetNICForwarding(%d, %d): %s", nic, proto, err)
+			}
+		}
+	}
+
+	routes := []tcpip.Route{
+		{Destination: header.IPv6EmptySubnet, NIC: netstackNICVsock},
+		{Destination: header.IPv4EmptySubnet, NIC: netstackNICVsock},
+	}
+	if subnet, err := containerSubnet(ipAddress, netmask); err == nil {
+		// Traffic destined for this attachment's own container subnet goes
+		// out the TAP side instead of the default (vsock/upstream) route.
+		routes = append([]tcpip.Route{{Destination: subnet, NIC: netstackNICTAP}}, routes...)
+	} else {
+		log.Printf("netstack: %v; routing everything via vsock", err)
+	}
+	s.SetRouteTable(routes)
+
+	return &netstackState{s: s, vsockEP: vsockEP, firewall: fw, flows: flows}, nil
+}
+
+// containerSubnet derives the attachment's container-side subnet from its
+// assigned IP/netmask, for the netstack route table.
+func containerSubnet(ipAddress string, netmask uint32) (tcpip.Subnet, error) {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return tcpip.Subnet{}, fmt.Errorf("invalid IP address %q", ipAddress)
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return tcpip.Subnet{}, fmt.Errorf("netstack routing only supports IPv4 container addresses today")
+	}
+	mask := net.CIDRMask(int(netmask), 32)
+	addr := tcpip.AddrFromSlice(ip4.Mask(mask))
+	subnetMask := tcpip.MaskFromBytes(mask)
+	return tcpip.NewSubnet(addr, subnetMask)
+}
+
+// forwardNetstackToVsock drains packets the stack wants to send out the
+// vsock NIC and ships them as DATA frames on the shared mux, mirroring
+// forwardTAPtoVsock's role for ModeRawL2 attachments.
+func (a *NetworkAttachment) forwardNetstackToVsock(ctx context.Context, mux *relay.Mux) {
+	ep := a.ns.vsockEP
+	for {
+		pkt := ep.ReadContext(ctx)
+		if pkt == nil {
+			return
+		}
+		view := pkt.ToView()
+		payload := view.AsSlice()
+
+		a.stats.PacketsReceived.Add(1)
+		a.stats.BytesReceived.Add(uint64(len(payload)))
+
+		if err := mux.Send(a.Channel, relay.FrameData, payload); err != nil {
+			a.stats.SendErrors.Add(1)
+			log.Printf("netstack->vsock write error on %s: %v", a.Device, err)
+			pkt.DecRef()
+			return
+		}
+		a.stats.PacketsSent.Add(1)
+		a.stats.BytesSent.Add(uint64(len(payload)))
+		pkt.DecRef()
+	}
+}
+
+// forwardVsockToNetstack injects frames arriving on this attachment's
+// channel into the stack's vsock-side NIC, mirroring forwardVsockToTAP's
+// role for ModeRawL2 attachments.
+func (a *NetworkAttachment) forwardVsockToNetstack(ctx context.Context, inbound <-chan relay.Frame) {
+	ep := a.ns.vsockEP
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case f, ok := <-inbound:
+			if !ok {
+				return
+			}
+			switch f.Type {
+			case relay.FrameAttach, relay.FrameKeepalive:
+				continue
+			case relay.FrameDetach:
+				return
+			case relay.FrameData:
+				// handled below
+			default:
+				continue
+			}
+
+			a.stats.PacketsReceived.Add(1)
+			a.stats.BytesReceived.Add(uint64(len(f.Payload)))
+
+			proto := ipv4.ProtocolNumber
+			if len(f.Payload) > 0 && header.IPVersion(f.Payload) == header.IPv6Version {
+				proto = ipv6.ProtocolNumber
+			}
+			pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+				Payload: buffer.MakeWithData(append([]byte(nil), f.Payload...)),
+			})
+			ep.InjectInbound(proto, pkt)
+			pkt.DecRef()
+
+			a.stats.PacketsSent.Add(1)
+			a.stats.BytesSent.Add(uint64(len(f.Payload)))
+		}
+	}
+}
+
+// closeNetstack tears down a ModeNetstackL3 attachment's stack. Safe to
+// call on a ModeRawL2 attachment (ns is nil).
+func (a *NetworkAttachment) closeNetstack() {
+	if a.ns == nil {
+		return
+	}
+	a.ns.s.Close()
+}
+
+// SetFirewallRules replaces this attachment's 5-tuple allow/deny list.
+// Rules are evaluated in order; the first match wins, and traffic that
+// matches nothing is allowed. Only meaningful for ModeNetstackL3
+// attachments.
+func (a *NetworkAttachment) SetFirewallRules(rules []FirewallRule) error {
+	if a.ns == nil {
+		return fmt.Errorf("device %s is not in netstack mode", a.Device)
+	}
+	a.ns.firewall.setRules(rules)
+	return nil
+}
+
+// ListFlows returns a snapshot of this attachment's per-flow packet/byte
+// counters. Only meaningful for ModeNetstackL3 attachments.
+func (a *NetworkAttachment) ListFlows() ([]FlowStats, error) {
+	if a.ns == nil {
+		return nil, fmt.Errorf("device %s is not in netstack mode", a.Device)
+	}
+	return a.ns.flows.snapshot(), nil
+}
+
+// Direction records which NIC a packet entered an attachment's netstack
+// from, for flow accounting.
+type Direction byte
+
+const (
+	// DirectionFromTAP is a packet entering from the container (TAP) side.
+	DirectionFromTAP Direction = iota
+	// DirectionFromVsock is a packet entering from the host (vsock) side.
+	DirectionFromVsock
+)
+
+// Proto identifies a flow's transport protocol for FlowKey/FirewallRule
+// matching.
+type Proto byte
+
+const (
+	ProtoAny Proto = iota
+	ProtoTCP
+	ProtoUDP
+	ProtoICMP
+)
+
+func (p Proto) String() string {
+	switch p {
+	case ProtoTCP:
+		return "tcp"
+	case ProtoUDP:
+		return "udp"
+	case ProtoICMP:
+		return "icmp"
+	default:
+		return "any"
+	}
+}
+
+// Action is what a matching FirewallRule does to a packet.
+type Action byte
+
+const (
+	ActionAllow Action = iota
+	ActionDeny
+)
+
+// FirewallRule is one 5-tuple allow/deny entry in an attachment's
+// firewall. A zero-value CIDR or port matches anything; Proto: ProtoAny
+// matches every transport protocol.
+type FirewallRule struct {
+	SrcCIDR *net.IPNet
+	DstCIDR *net.IPNet
+	Proto   Proto
+	SrcPort uint16 // 0 matches any port
+	DstPort uint16 // 0 matches any port
+	Action  Action
+}
+
+// FlowKey identifies one flow for accounting purposes.
+type FlowKey struct {
+	SrcAddr tcpip.Address
+	DstAddr tcpip.Address
+	Proto   Proto
+	SrcPort uint16
+	DstPort uint16
+}
+
+// FlowStats is one flow's accumulated packet/byte counters.
+type FlowStats struct {
+	Key      FlowKey
+	Packets  uint64
+	Bytes    uint64
+	LastSeen time.Time
+}
+
+// firewall evaluates FirewallRules against a parsed 5-tuple. It's safe for
+// concurrent use: rule updates (SetFirewallRules) and packet-path lookups
+// (every forwarded packet) both take the read-mostly lock.
+type firewall struct {
+	mu    sync.RWMutex
+	rules []FirewallRule
+}
+
+func newFirewall() *firewall {
+	return &firewall{}
+}
+
+func (f *firewall) setRules(rules []FirewallRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append([]FirewallRule(nil), rules...)
+}
+
+// allow reports whether a packet with the given 5-tuple is allowed.
+// Unmatched traffic is allowed by default - the firewall is opt-in via
+// SetFirewallRules, matching ModeNetstackL3 itself being opt-in.
+func (f *firewall) allow(srcIP, dstIP net.IP, proto Proto, srcPort, dstPort uint16) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, r := range f.rules {
+		if r.Proto != ProtoAny && r.Proto != proto {
+			continue
+		}
+		if r.SrcCIDR != nil && !r.SrcCIDR.Contains(srcIP) {
+			continue
+		}
+		if r.DstCIDR != nil && !r.DstCIDR.Contains(dstIP) {
+			continue
+		}
+		if r.SrcPort != 0 && r.SrcPort != srcPort {
+			continue
+		}
+		if r.DstPort != 0 && r.DstPort != dstPort {
+			continue
+		}
+		return r.Action == ActionAllow
+	}
+	return true
+}
+
+// flowTable accumulates per-flow packet/byte counters.
+type flowTable struct {
+	mu    sync.Mutex
+	flows map[FlowKey]*FlowStats
+}
+
+func newFlowTable() *flowTable {
+	return &flowTable{flows: make(map[FlowKey]*FlowStats)}
+}
+
+func (t *flowTable) record(key FlowKey, bytes int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fs, ok := t.flows[key]
+	if !ok {
+		fs = &FlowStats{Key: key}
+		t.flows[key] = fs
+	}
+	fs.Packets++
+	fs.Bytes += uint64(bytes)
+	fs.LastSeen = time.Now()
+}
+
+func (t *flowTable) snapshot() []FlowStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]FlowStats, 0, len(t.flows))
+	for _, fs := range t.flows {
+		out = append(out, *fs)
+	}
+	return out
+}
+
+// filteringEndpoint decorates a stack.LinkEndpoint so that every packet it
+// delivers up to the stack is first checked against fw and recorded in
+// flows. It only overrides Attach; every other LinkEndpoint method is
+// promoted straight through to the embedded endpoint.
+type filteringEndpoint struct {
+	stack.LinkEndpoint
+	dir   Direction
+	fw    *firewall
+	flows *flowTable
+}
+
+func (e *filteringEndpoint) Attach(dispatcher stack.NetworkDispatcher) {
+	e.LinkEndpoint.Attach(&filteringDispatcher{inner: dispatcher, fw: e.fw, flows: e.flows})
+}
+
+// filteringDispatcher is the stack.NetworkDispatcher a filteringEndpoint
+// installs in place of the stack's own, so it sees every packet before the
+// stack does.
+type filteringDispatcher struct {
+	inner stack.NetworkDispatcher
+	fw    *firewall
+	flows *flowTable
+}
+
+func (d *filteringDispatcher) DeliverNetworkPacket(protocol tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) {
+	key, size, allowed := d.inspect(protocol, pkt)
+	if !allowed {
+		pkt.DecRef()
+		return
+	}
+	if size > 0 {
+		d.flows.record(key, size)
+	}
+	d.inner.DeliverNetworkPacket(protocol, pkt)
+}
+
+func (d *filteringDispatcher) DeliverLinkPacket(protocol tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) {
+	d.inner.DeliverLinkPacket(protocol, pkt)
+}
+
+// inspect parses pkt's network/transport headers into a FlowKey and
+// consults the firewall. Packets that don't parse as IPv4/IPv6 (or whose
+// transport protocol isn't one ListFlows/SetFirewallRules understands) are
+// allowed through unaccounted, rather than dropped - the firewall only
+// acts on traffic it can actually identify.
+func (d *filteringDispatcher) inspect(protocol tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) (FlowKey, int, bool) {
+	view := pkt.ToView()
+	buf := view.AsSlice()
+
+	var (
+		srcAddr, dstAddr tcpip.Address
+		transportProto   tcpip.TransportProtocolNumber
+		transportPayload []byte
+	)
+
+	switch protocol {
+	case ipv4.ProtocolNumber:
+		if len(buf) < header.IPv4MinimumSize {
+			return FlowKey{}, 0, true
+		}
+		ip := header.IPv4(buf)
+		srcAddr, dstAddr = ip.SourceAddress(), ip.DestinationAddress()
+		transportProto = ip.TransportProtocol()
+		transportPayload = ip.Payload()
+	case ipv6.ProtocolNumber:
+		if len(buf) < header.IPv6MinimumSize {
+			return FlowKey{}, 0, true
+		}
+		ip := header.IPv6(buf)
+		srcAddr, dstAddr = ip.SourceAddress(), ip.DestinationAddress()
+		transportProto = ip.TransportProtocol()
+		transportPayload = ip.Payload()
+	default:
+		return FlowKey{}, 0, true
+	}
+
+	var (
+		proto            Proto
+		srcPort, dstPort uint16
+	)
+	switch transportProto {
+	case header.TCPProtocolNumber:
+		if len(transportPayload) < header.TCPMinimumSize {
+			return FlowKey{}, 0, true
+		}
+		tcpHdr := header.TCP(transportPayload)
+		proto, srcPort, dstPort = ProtoTCP, tcpHdr.SourcePort(), tcpHdr.DestinationPort()
+	case header.UDPProtocolNumber:
+		if len(transportPayload) < header.UDPMinimumSize {
+			return FlowKey{}, 0, true
+		}
+		udpHdr := header.UDP(transportPayload)
+		proto, srcPort, dstPort = ProtoUDP, udpHdr.SourcePort(), udpHdr.DestinationPort()
+	case header.ICMPv4ProtocolNumber, header.ICMPv6ProtocolNumber:
+		proto = ProtoICMP
+	default:
+		return FlowKey{}, 0, true
+	}
+
+	if !d.fw.allow(net.IP(srcAddr.AsSlice()), net.IP(dstAddr.AsSlice()), proto, srcPort, dstPort) {
+		return FlowKey{}, 0, false
+	}
+
+	key := FlowKey{SrcAddr: srcAddr, DstAddr: dstAddr, Proto: proto, SrcPort: srcPort, DstPort: dstPort}
+	return key, len(buf), true
+}
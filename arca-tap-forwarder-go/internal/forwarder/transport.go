@@ -0,0 +1,49 @@
+package forwarder
+
+import (
+	"net"
+
+	"github.com/mdlayher/vsock"
+	"github.com/vas-solutus/arca-tap-forwarder/internal/tap"
+)
+
+// Transport abstracts the listener Forwarder.Serve accepts the host's
+// shared data connection on. The default, vsockTransport, requires the
+// vsock kernel module and AF_VSOCK privileges; WithTransport lets tests
+// substitute an in-memory listener instead (see forwardertest).
+type Transport interface {
+	Listen(port uint32) (net.Listener, error)
+}
+
+type vsockTransport struct{}
+
+func (vsockTransport) Listen(port uint32) (net.Listener, error) {
+	return vsock.Listen(port, nil)
+}
+
+// TapDevice is the subset of *tap.TAP's documented cross-platform surface
+// (see internal/tap) that Forwarder depends on. It exists so tests can
+// substitute a fake device backed by in-memory pipes instead of a real
+// kernel TAP device; *tap.TAP satisfies it without any changes.
+type TapDevice interface {
+	Read(buf []byte) (int, error)
+	Write(buf []byte) (int, error)
+	SetIP(ipAddr string, netmask uint32) error
+	BringUp() error
+	Name() string
+	MAC() net.HardwareAddr
+	Close() error
+}
+
+// TapFactory creates the TapDevice backing a newly-attached network.
+// WithTapFactory lets tests substitute a fake factory instead of opening a
+// real kernel TAP device (see forwardertest).
+type TapFactory interface {
+	Create(name string) (TapDevice, error)
+}
+
+type realTapFactory struct{}
+
+func (realTapFactory) Create(name string) (TapDevice, error) {
+	return tap.Create(name)
+}
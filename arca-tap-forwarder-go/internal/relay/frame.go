@@ -0,0 +1,106 @@
+// Package relay implements the single-port multiplexed vsock protocol that
+// carries every network's packets to and from the host over one shared
+// connection, instead of forwarder.AttachNetwork opening a dedicated
+// vsock.Listen per device. Each attached network gets a channel ID instead
+// of a port; frames are length-prefixed and tagged with a channel ID and a
+// frame type (DATA/ATTACH/DETACH/KEEPALIVE) so many channels can share one
+// connection without a per-channel accept handshake. This mirrors the
+// single-port relay approach used by modern WireGuard/WebRTC relays.
+package relay
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// FrameType identifies what a Frame carries.
+type FrameType byte
+
+const (
+	// FrameData carries one raw Ethernet frame for Channel.
+	FrameData FrameType = iota + 1
+	// FrameAttach is sent by the host before it starts pumping FrameData
+	// for a channel; Payload carries opaque device metadata (e.g. the
+	// device name) for logging/diagnostics.
+	FrameAttach
+	// FrameDetach tells the peer a channel is going away, so it can stop
+	// forwarding and release any per-channel state.
+	FrameDetach
+	// FrameKeepalive has no payload; it exists purely to keep the shared
+	// connection's NAT/firewall state (and vsock idle timeouts, where
+	// applicable) alive during quiet periods.
+	FrameKeepalive
+)
+
+func (t FrameType) String() string {
+	switch t {
+	case FrameData:
+		return "DATA"
+	case FrameAttach:
+		return "ATTACH"
+	case FrameDetach:
+		return "DETACH"
+	case FrameKeepalive:
+		return "KEEPALIVE"
+	default:
+		return fmt.Sprintf("FrameType(%d)", byte(t))
+	}
+}
+
+const (
+	lengthSize  = 4 // length prefix, counts only the header+payload below
+	channelSize = 4
+	typeSize    = 1
+	headerSize  = channelSize + typeSize
+)
+
+// MaxPayloadSize bounds a single frame's payload, generously above the
+// largest Ethernet frame any TAP device here produces. It exists so a
+// corrupt or malicious length prefix can't make ReadFrame allocate an
+// unbounded buffer.
+const MaxPayloadSize = 1 << 20 // 1 MiB
+
+// Frame is one multiplexed message read from or written to a shared
+// connection.
+type Frame struct {
+	Channel uint32
+	Type    FrameType
+	Payload []byte
+}
+
+// Encode renders f as length-prefixed wire bytes, ready to hand to a
+// single writer goroutine (see Mux) so concurrent channels' writes to the
+// shared connection stay atomic.
+func Encode(f Frame) []byte {
+	buf := make([]byte, lengthSize+headerSize+len(f.Payload))
+	binary.BigEndian.PutUint32(buf[0:lengthSize], uint32(headerSize+len(f.Payload)))
+	binary.BigEndian.PutUint32(buf[lengthSize:lengthSize+channelSize], f.Channel)
+	buf[lengthSize+channelSize] = byte(f.Type)
+	copy(buf[lengthSize+headerSize:], f.Payload)
+	return buf
+}
+
+// ReadFrame reads one frame from r, blocking until a full frame (or an
+// error) is available.
+func ReadFrame(r io.Reader) (Frame, error) {
+	var lenBuf [lengthSize]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return Frame{}, err
+	}
+	n := binary.BigEndian.Uint32(lenBuf[:])
+	if n < headerSize || n > headerSize+MaxPayloadSize {
+		return Frame{}, fmt.Errorf("relay: invalid frame length %d", n)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return Frame{}, err
+	}
+
+	return Frame{
+		Channel: binary.BigEndian.Uint32(body[0:channelSize]),
+		Type:    FrameType(body[channelSize]),
+		Payload: body[headerSize:],
+	}, nil
+}
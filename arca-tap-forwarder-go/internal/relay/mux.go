@@ -0,0 +1,147 @@
+package relay
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+)
+
+// writeQueueDepth bounds how many encoded frames can be queued for the
+// writer goroutine before Send blocks, so a slow/stuck connection applies
+// backpressure to callers instead of growing memory unboundedly.
+const writeQueueDepth = 256
+
+// inboundQueueDepth bounds each channel's inbound frame queue for the same
+// reason, on the receive side.
+const inboundQueueDepth = 256
+
+// ErrClosed is returned by Send once the Mux has been closed.
+var ErrClosed = errors.New("relay: mux closed")
+
+// Mux multiplexes Frames for many channels over one shared connection. All
+// writes - regardless of which channel they're for - go through a single
+// writer goroutine fed by a chan []byte, which is what keeps the shared
+// connection's writes atomic: two channels' forwardTAPtoVsock goroutines
+// can call Send concurrently without their frames interleaving on the
+// wire.
+type Mux struct {
+	conn net.Conn
+
+	writeCh   chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mu       sync.Mutex
+	channels map[uint32]chan Frame
+}
+
+// NewMux wraps conn and starts its read/write pumps. The caller is
+// responsible for calling Close when the connection is done with.
+func NewMux(conn net.Conn) *Mux {
+	m := &Mux{
+		conn:     conn,
+		writeCh:  make(chan []byte, writeQueueDepth),
+		closed:   make(chan struct{}),
+		channels: make(map[uint32]chan Frame),
+	}
+	go m.writeLoop()
+	go m.readLoop()
+	return m
+}
+
+func (m *Mux) writeLoop() {
+	for buf := range m.writeCh {
+		if _, err := m.conn.Write(buf); err != nil {
+			log.Printf("relay: write error, closing mux: %v", err)
+			m.Close()
+			return
+		}
+	}
+}
+
+func (m *Mux) readLoop() {
+	defer m.Close()
+	for {
+		f, err := ReadFrame(m.conn)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("relay: read error: %v", err)
+			}
+			return
+		}
+
+		m.mu.Lock()
+		ch, ok := m.channels[f.Channel]
+		m.mu.Unlock()
+		if !ok {
+			// No one's registered for this channel (yet, or any more);
+			// drop it rather than blocking the read loop for every other
+			// channel.
+			continue
+		}
+
+		select {
+		case ch <- f:
+		default:
+			log.Printf("relay: channel %d inbound queue full, dropping %s frame", f.Channel, f.Type)
+		}
+	}
+}
+
+// Register returns the inbound Frame queue for channel, creating it if
+// this is the first registration. Call Unregister when the channel is
+// detached so readLoop stops holding a reference to it.
+func (m *Mux) Register(channel uint32) <-chan Frame {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ch, ok := m.channels[channel]; ok {
+		return ch
+	}
+	ch := make(chan Frame, inboundQueueDepth)
+	m.channels[channel] = ch
+	return ch
+}
+
+// Unregister stops delivering frames for channel and closes its inbound
+// queue.
+func (m *Mux) Unregister(channel uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ch, ok := m.channels[channel]; ok {
+		delete(m.channels, channel)
+		close(ch)
+	}
+}
+
+// Send encodes and enqueues a frame for the writer goroutine, returning
+// ErrClosed once the Mux is shutting down rather than sending on a closed
+// channel.
+func (m *Mux) Send(channel uint32, typ FrameType, payload []byte) error {
+	buf := Encode(Frame{Channel: channel, Type: typ, Payload: payload})
+	select {
+	case m.writeCh <- buf:
+		return nil
+	case <-m.closed:
+		return ErrClosed
+	}
+}
+
+// Close shuts down the writer goroutine, closes every registered
+// channel's inbound queue, and closes the underlying connection. It's
+// safe to call more than once and from multiple goroutines.
+func (m *Mux) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closed)
+		close(m.writeCh)
+
+		m.mu.Lock()
+		for channel, ch := range m.channels {
+			delete(m.channels, channel)
+			close(ch)
+		}
+		m.mu.Unlock()
+	})
+	return m.conn.Close()
+}
@@ -0,0 +1,193 @@
+//go:build darwin
+
+package tap
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// utunControlName is the kernel control name registered by the utun driver.
+const utunControlName = "com.apple.net.utun_control"
+
+// Address-family prefixes utun prepends to every packet it reads/writes.
+// Big-endian uint32 values, matching <sys/socket.h> AF_INET/AF_INET6.
+const (
+	afInet  = 2
+	afInet6 = 30 // AF_INET6 on Darwin (not the Linux value)
+)
+
+// TAP represents a utun device on Darwin. There is no Ethernet framing or
+// MAC address on utun - it's a pure L3 point-to-point tunnel - so MAC()
+// returns a synthesized, stable-looking address for API compatibility with
+// the Linux TAP implementation.
+type TAP struct {
+	file     *os.File
+	name     string
+	mac      net.HardwareAddr
+	netStack *netStack
+}
+
+// Create opens a utun device. name should look like "utunN"; if the
+// requested unit is unavailable the kernel may hand back a different
+// (lowest-available) unit, so callers should use Name() rather than the
+// string they passed in. By default the fd is exposed raw via Read/Write;
+// pass WithMode(ModeNetstack) to route it through a gVisor userspace stack
+// instead (see netstack.go).
+func Create(name string, opts ...Option) (*TAP, error) {
+	unit, err := utunUnit(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Socket(unix.AF_SYSTEM, unix.SOCK_DGRAM, unix.SYSPROTO_CONTROL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AF_SYSTEM socket: %w", err)
+	}
+
+	info := &unix.CtlInfo{}
+	copy(info.Name[:], utunControlName)
+	if err := unix.IoctlCtlInfo(fd, info); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("CTLIOCGINFO failed: %w", err)
+	}
+
+	sc := &unix.SockaddrCtl{
+		ID:   info.Id,
+		Unit: uint32(unit) + 1, // scUnit is 1-indexed; unit+1 requests utun<unit>
+	}
+	if err := unix.Connect(fd, sc); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to connect to utun control: %w", err)
+	}
+
+	// The kernel names the resulting interface via a socket option; read it
+	// back so Name() reflects the unit actually granted.
+	ifName, err := unix.GetsockoptString(fd, unix.SYSPROTO_CONTROL, 2 /* UTUN_OPT_IFNAME */)
+	if err != nil || ifName == "" {
+		ifName = fmt.Sprintf("utun%d", unit)
+	}
+
+	file := os.NewFile(uintptr(fd), ifName)
+
+	macBytes, err := randomMAC()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to generate MAC address: %w", err)
+	}
+
+	return finishCreate(&TAP{
+		file: file,
+		name: ifName,
+		mac:  net.HardwareAddr(macBytes[:]),
+	}, opts)
+}
+
+// utunUnit parses the trailing digits off a "utunN" name.
+func utunUnit(name string) (int, error) {
+	if !strings.HasPrefix(name, "utun") {
+		return 0, fmt.Errorf("darwin TAP device names must look like utunN, got %q", name)
+	}
+	unit, err := strconv.Atoi(strings.TrimPrefix(name, "utun"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid utun unit in %q: %w", name, err)
+	}
+	return unit, nil
+}
+
+// SetIP configures the point-to-point address and netmask for the utun
+// device. utun has no SIOCSIFADDR-style ioctl semantics for a plain
+// interface address like Linux's TAP; configuration goes through ifconfig
+// instead, which also takes care of installing the on-link route.
+func (t *TAP) SetIP(ipAddr string, netmask uint32) error {
+	ip := net.ParseIP(ipAddr)
+	if ip == nil || ip.To4() == nil {
+		return fmt.Errorf("invalid IPv4 address: %s", ipAddr)
+	}
+	mask := net.CIDRMask(int(netmask), 32)
+	maskStr := net.IP(mask).String()
+
+	// "ifconfig utunN inet <addr> <addr> netmask <mask>" configures utun as
+	// a point-to-point link to itself; combined with BringUp's route add
+	// this is enough for the gateway-style usage the forwarder needs.
+	cmd := exec.Command("ifconfig", t.name, "inet", ipAddr, ipAddr, "netmask", maskStr)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ifconfig %s failed: %w (output: %s)", t.name, err, out)
+	}
+	return nil
+}
+
+// BringUp marks the interface UP.
+func (t *TAP) BringUp() error {
+	cmd := exec.Command("ifconfig", t.name, "up")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ifconfig %s up failed: %w (output: %s)", t.name, err, out)
+	}
+	return nil
+}
+
+// Read reads one packet from the utun device, stripping the 4-byte address
+// family prefix the kernel prepends.
+func (t *TAP) Read(buf []byte) (int, error) {
+	raw := make([]byte, len(buf)+4)
+	n, err := unix.Read(int(t.file.Fd()), raw)
+	if err != nil {
+		return 0, err
+	}
+	if n < 4 {
+		return 0, nil
+	}
+	copy(buf, raw[4:n])
+	return n - 4, nil
+}
+
+// Write writes one packet to the utun device, prepending the 4-byte address
+// family prefix the kernel requires (big-endian AF_INET/AF_INET6).
+func (t *TAP) Write(buf []byte) (int, error) {
+	af := uint32(afInet)
+	if len(buf) > 0 && buf[0]>>4 == 6 {
+		af = afInet6
+	}
+
+	raw := make([]byte, 4+len(buf))
+	raw[0] = byte(af >> 24)
+	raw[1] = byte(af >> 16)
+	raw[2] = byte(af >> 8)
+	raw[3] = byte(af)
+	copy(raw[4:], buf)
+
+	n, err := unix.Write(int(t.file.Fd()), raw)
+	if err != nil {
+		return 0, err
+	}
+	if n < 4 {
+		return 0, nil
+	}
+	return n - 4, nil
+}
+
+// Name returns the interface name granted by the kernel.
+func (t *TAP) Name() string {
+	return t.name
+}
+
+// MAC returns a synthesized MAC address. utun is a pure L3 tunnel and has
+// no link-layer address of its own; callers that need a consistent
+// identifier across platforms can still rely on this value.
+func (t *TAP) MAC() net.HardwareAddr {
+	return t.mac
+}
+
+// Close closes the utun socket.
+func (t *TAP) Close() error {
+	if t.file != nil {
+		return t.file.Close()
+	}
+	return nil
+}
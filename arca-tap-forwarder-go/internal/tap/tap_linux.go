@@ -0,0 +1,389 @@
+//go:build linux
+
+package tap
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// /dev/net/tun device path
+	tunDevice = "/dev/net/tun"
+
+	// TAP device type. These (and TUNSETIFF itself) are tun/tap-driver
+	// specific and aren't part of x/sys/unix's generic ioctl surface, so
+	// they stay as local constants.
+	iffTAP        = 0x0002
+	iffNOPI       = 0x1000
+	iffMultiQueue = 0x0100
+	TUNSETIFF     = 0x400454ca
+)
+
+// TAP represents a TAP network device
+type TAP struct {
+	file     *os.File
+	name     string
+	mac      net.HardwareAddr
+	netStack *netStack
+}
+
+// Create creates a new TAP device with the specified name. By default the
+// fd is exposed raw via Read/Write; pass WithMode(ModeNetstack) to route it
+// through a gVisor userspace stack instead (see netstack.go).
+func Create(name string, opts ...Option) (*TAP, error) {
+	taps, err := createQueues(name, 1)
+	if err != nil {
+		return nil, err
+	}
+	return finishCreate(taps[0], opts)
+}
+
+// CreateMultiQueue opens `queues` file descriptors bound to the same TAP
+// name via IFF_MULTI_QUEUE, so independent goroutines can Read/Write
+// concurrently without contending on a single fd's internal lock. The
+// kernel load-balances packets across queues (by flow hash on egress from
+// the host side), which is what lets multiple forwarder goroutines scale
+// past one core. Falls back to a single queue on kernels/drivers that
+// reject IFF_MULTI_QUEUE - callers should treat a returned slice of length
+// 1 as "no multi-queue support" rather than an error.
+func CreateMultiQueue(name string, queues int) ([]*TAP, error) {
+	if queues < 1 {
+		queues = 1
+	}
+	taps, err := createQueues(name, queues)
+	if err == nil {
+		return taps, nil
+	}
+
+	// Retry without IFF_MULTI_QUEUE in case the kernel/driver doesn't
+	// support it; a single queue is still a working TAP device.
+	single, singleErr := createQueues(name, 1)
+	if singleErr != nil {
+		return nil, fmt.Errorf("multi-queue create failed (%v) and single-queue fallback failed: %w", err, singleErr)
+	}
+	return single, nil
+}
+
+// createQueues opens `queues` fds against the same TAP name. When queues
+// is 1 the IFF_MULTI_QUEUE flag is omitted so single-queue kernels behave
+// exactly as before.
+func createQueues(name string, queues int) ([]*TAP, error) {
+	taps := make([]*TAP, 0, queues)
+	for i := 0; i < queues; i++ {
+		t, err := openQueue(name, queues > 1)
+		if err != nil {
+			for _, opened := range taps {
+				opened.Close()
+			}
+			return nil, err
+		}
+		taps = append(taps, t)
+	}
+
+	// Generate one MAC address and apply it to the shared interface name;
+	// every queue fd refers to the same underlying netdev.
+	macBytes, err := randomMAC()
+	if err != nil {
+		for _, opened := range taps {
+			opened.Close()
+		}
+		return nil, fmt.Errorf("failed to generate MAC address: %w", err)
+	}
+	mac := net.HardwareAddr(macBytes[:])
+	for _, t := range taps {
+		t.mac = mac
+	}
+	if err := taps[0].setMAC(mac); err != nil {
+		for _, opened := range taps {
+			opened.Close()
+		}
+		return nil, fmt.Errorf("failed to set MAC address: %w", err)
+	}
+
+	return taps, nil
+}
+
+// openQueue opens a single /dev/net/tun fd bound to name.
+func openQueue(name string, multiQueue bool) (*TAP, error) {
+	// Open /dev/net/tun in blocking mode
+	// Blocking I/O is fine since we're in dedicated goroutines
+	fd, err := unix.Open(tunDevice, unix.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", tunDevice, err)
+	}
+
+	ifr, err := unix.NewIfreq(name)
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("invalid interface name %q: %w", name, err)
+	}
+	flags := uint16(iffTAP | iffNOPI)
+	if multiQueue {
+		flags |= iffMultiQueue
+	}
+	ifr.SetUint16(flags)
+
+	// Create TAP device via TUNSETIFF ioctl
+	if err := unix.IoctlIfreq(fd, TUNSETIFF, ifr); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("TUNSETIFF ioctl failed: %w", err)
+	}
+
+	// Create os.File from fd for compatibility
+	file := os.NewFile(uintptr(fd), tunDevice)
+
+	return &TAP{
+		file: file,
+		name: name,
+	}, nil
+}
+
+// SetIP configures the IP address and netmask for the TAP device
+func (t *TAP) SetIP(ipAddr string, netmask uint32) error {
+	// Parse IP address
+	ip := net.ParseIP(ipAddr)
+	if ip == nil {
+		return fmt.Errorf("invalid IP address: %s", ipAddr)
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return fmt.Errorf("not an IPv4 address: %s", ipAddr)
+	}
+
+	// Open socket for ioctl
+	sockFd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create socket: %w", err)
+	}
+	defer unix.Close(sockFd)
+
+	// Set IP address
+	addrReq, err := unix.NewIfreq(t.name)
+	if err != nil {
+		return fmt.Errorf("invalid interface name %q: %w", t.name, err)
+	}
+	if err := addrReq.SetInet4Addr(ip4); err != nil {
+		return fmt.Errorf("invalid IPv4 address %s: %w", ipAddr, err)
+	}
+	if err := unix.IoctlIfreqSockaddr(sockFd, unix.SIOCSIFADDR, addrReq); err != nil {
+		return fmt.Errorf("SIOCSIFADDR ioctl failed: %w", err)
+	}
+
+	// Set netmask
+	mask := net.CIDRMask(int(netmask), 32)
+	maskReq, err := unix.NewIfreq(t.name)
+	if err != nil {
+		return fmt.Errorf("invalid interface name %q: %w", t.name, err)
+	}
+	if err := maskReq.SetInet4Addr(mask); err != nil {
+		return fmt.Errorf("invalid netmask /%d: %w", netmask, err)
+	}
+	if err := unix.IoctlIfreqSockaddr(sockFd, unix.SIOCSIFNETMASK, maskReq); err != nil {
+		return fmt.Errorf("SIOCSIFNETMASK ioctl failed: %w", err)
+	}
+
+	return nil
+}
+
+// BringUp brings the TAP interface up
+func (t *TAP) BringUp() error {
+	sockFd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create socket: %w", err)
+	}
+	defer unix.Close(sockFd)
+
+	// Get current flags
+	ifr, err := unix.NewIfreq(t.name)
+	if err != nil {
+		return fmt.Errorf("invalid interface name %q: %w", t.name, err)
+	}
+	if err := unix.IoctlIfreq(sockFd, unix.SIOCGIFFLAGS, ifr); err != nil {
+		return fmt.Errorf("SIOCGIFFLAGS ioctl failed: %w", err)
+	}
+
+	// Set UP and RUNNING flags
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP | unix.IFF_RUNNING)
+
+	if err := unix.IoctlIfreq(sockFd, unix.SIOCSIFFLAGS, ifr); err != nil {
+		return fmt.Errorf("SIOCSIFFLAGS ioctl failed: %w", err)
+	}
+
+	return nil
+}
+
+// setMAC sets the MAC address for the TAP device
+func (t *TAP) setMAC(mac net.HardwareAddr) error {
+	sockFd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create socket: %w", err)
+	}
+	defer unix.Close(sockFd)
+
+	ifr, err := unix.NewIfreq(t.name)
+	if err != nil {
+		return fmt.Errorf("invalid interface name %q: %w", t.name, err)
+	}
+	if err := ifr.SetHardwareAddr(mac); err != nil {
+		return fmt.Errorf("invalid MAC address %s: %w", mac, err)
+	}
+	if err := unix.IoctlIfreqSockaddr(sockFd, unix.SIOCSIFHWADDR, ifr); err != nil {
+		return fmt.Errorf("SIOCSIFHWADDR ioctl failed: %w", err)
+	}
+
+	return nil
+}
+
+// SetMTU sets the TAP device's MTU.
+func (t *TAP) SetMTU(mtu int) error {
+	sockFd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create socket: %w", err)
+	}
+	defer unix.Close(sockFd)
+
+	ifr, err := unix.NewIfreq(t.name)
+	if err != nil {
+		return fmt.Errorf("invalid interface name %q: %w", t.name, err)
+	}
+	ifr.SetUint32(uint32(mtu))
+	if err := unix.IoctlIfreq(sockFd, unix.SIOCSIFMTU, ifr); err != nil {
+		return fmt.Errorf("SIOCSIFMTU ioctl failed: %w", err)
+	}
+
+	return nil
+}
+
+// SetTxQueueLen sets the TAP device's transmit queue length (the number of
+// packets the kernel will buffer for this interface before dropping).
+func (t *TAP) SetTxQueueLen(length int) error {
+	sockFd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create socket: %w", err)
+	}
+	defer unix.Close(sockFd)
+
+	ifr, err := unix.NewIfreq(t.name)
+	if err != nil {
+		return fmt.Errorf("invalid interface name %q: %w", t.name, err)
+	}
+	ifr.SetUint32(uint32(length))
+	if err := unix.IoctlIfreq(sockFd, unix.SIOCSIFTXQLEN, ifr); err != nil {
+		return fmt.Errorf("SIOCSIFTXQLEN ioctl failed: %w", err)
+	}
+
+	return nil
+}
+
+// Read reads a packet from the TAP device using blocking I/O
+func (t *TAP) Read(buf []byte) (int, error) {
+	n, err := unix.Read(int(t.file.Fd()), buf)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Write writes a packet to the TAP device using blocking I/O
+func (t *TAP) Write(buf []byte) (int, error) {
+	n, err := unix.Write(int(t.file.Fd()), buf)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ReadBatch fills as many of bufs as are immediately available, one packet
+// per buffer, recording each packet's length in the corresponding sizes
+// entry. It always blocks for at least one packet, then drains the fd with
+// non-blocking reads (checked via poll) until either bufs is exhausted or
+// no more data is queued - this amortizes the per-packet syscall/lock
+// overhead of the forwarder's read loop without requiring multi-queue fds.
+// Returns the number of buffers filled.
+func (t *TAP) ReadBatch(bufs [][]byte, sizes []int) (int, error) {
+	if len(bufs) == 0 {
+		return 0, nil
+	}
+	fd := int(t.file.Fd())
+
+	n, err := unix.Read(fd, bufs[0])
+	if err != nil {
+		return 0, err
+	}
+	sizes[0] = n
+	count := 1
+
+	for count < len(bufs) {
+		ready, err := pollReadable(fd)
+		if err != nil || !ready {
+			break
+		}
+		n, err := unix.Read(fd, bufs[count])
+		if err != nil {
+			break
+		}
+		sizes[count] = n
+		count++
+	}
+
+	return count, nil
+}
+
+// WriteBatch writes each packet in bufs to the TAP device via unix.Writev,
+// one syscall per packet (the tun driver treats each write(2)/writev(2)
+// call as exactly one frame, so Writev here is used for its single-call,
+// no-extra-copy semantics rather than to merge multiple packets into one
+// syscall). Returns the number of packets written; on error it returns how
+// many packets were successfully written before the failure.
+func (t *TAP) WriteBatch(bufs [][]byte) (int, error) {
+	fd := int(t.file.Fd())
+	for i, buf := range bufs {
+		if _, err := unix.Writev(fd, [][]byte{buf}); err != nil {
+			return i, err
+		}
+	}
+	return len(bufs), nil
+}
+
+// pollReadable does a zero-timeout poll to check whether fd has data
+// queued without blocking - used by ReadBatch to opportunistically drain
+// a backlog instead of always stopping at one packet per call.
+func pollReadable(fd int) (bool, error) {
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, 0)
+	if err != nil {
+		return false, err
+	}
+	return n > 0 && fds[0].Revents&unix.POLLIN != 0, nil
+}
+
+// Name returns the TAP device name
+func (t *TAP) Name() string {
+	return t.name
+}
+
+// MAC returns the MAC address
+func (t *TAP) MAC() net.HardwareAddr {
+	return t.mac
+}
+
+// Fd returns the underlying TAP device's raw file descriptor, so callers
+// outside this package can attach it directly to a packet-oriented
+// consumer (e.g. gVisor's fdbased link endpoint) instead of going through
+// Read/Write/ReadBatch. The fd remains owned by t; callers must not close
+// it themselves.
+func (t *TAP) Fd() int {
+	return int(t.file.Fd())
+}
+
+// Close closes the TAP device
+func (t *TAP) Close() error {
+	if t.file != nil {
+		return t.file.Close()
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build !linux && !darwin && !freebsd
+
+package tap
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// TAP is a stub on platforms we don't yet support. Every method returns an
+// error so callers fail loudly at runtime rather than the package failing
+// to build at all.
+type TAP struct {
+	netStack *netStack
+}
+
+// Create always fails on unsupported platforms.
+func Create(name string, opts ...Option) (*TAP, error) {
+	return nil, fmt.Errorf("tap: unsupported platform %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func (t *TAP) SetIP(ipAddr string, netmask uint32) error {
+	return fmt.Errorf("tap: unsupported platform %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func (t *TAP) BringUp() error {
+	return fmt.Errorf("tap: unsupported platform %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func (t *TAP) Read(buf []byte) (int, error) {
+	return 0, fmt.Errorf("tap: unsupported platform %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func (t *TAP) Write(buf []byte) (int, error) {
+	return 0, fmt.Errorf("tap: unsupported platform %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func (t *TAP) Name() string {
+	return ""
+}
+
+func (t *TAP) MAC() net.HardwareAddr {
+	return nil
+}
+
+func (t *TAP) Close() error {
+	return nil
+}
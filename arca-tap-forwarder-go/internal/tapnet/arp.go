@@ -0,0 +1,75 @@
+package tapnet
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// ARP packet layout (RFC 826), fixed for Ethernet/IPv4.
+const (
+	arpHeaderLen = 28
+
+	arpHTypeEthernet = 1
+	arpPTypeIPv4     = etherTypeIPv4
+
+	arpOpRequest = 1
+	arpOpReply   = 2
+)
+
+type arpPacket struct {
+	op       uint16
+	senderMAC net.HardwareAddr
+	senderIP  net.IP
+	targetMAC net.HardwareAddr
+	targetIP  net.IP
+}
+
+func parseARP(payload []byte) (arpPacket, bool) {
+	if len(payload) < arpHeaderLen {
+		return arpPacket{}, false
+	}
+	hType := binary.BigEndian.Uint16(payload[0:2])
+	pType := binary.BigEndian.Uint16(payload[2:4])
+	hLen := payload[4]
+	pLen := payload[5]
+	if hType != arpHTypeEthernet || pType != arpPTypeIPv4 || hLen != 6 || pLen != 4 {
+		return arpPacket{}, false
+	}
+	return arpPacket{
+		op:        binary.BigEndian.Uint16(payload[6:8]),
+		senderMAC: net.HardwareAddr(payload[8:14]),
+		senderIP:  net.IP(payload[14:18]),
+		targetMAC: net.HardwareAddr(payload[18:24]),
+		targetIP:  net.IP(payload[24:28]),
+	}, true
+}
+
+func buildARPReply(ourMAC net.HardwareAddr, ourIP net.IP, theirMAC net.HardwareAddr, theirIP net.IP) []byte {
+	buf := make([]byte, arpHeaderLen)
+	binary.BigEndian.PutUint16(buf[0:2], arpHTypeEthernet)
+	binary.BigEndian.PutUint16(buf[2:4], arpPTypeIPv4)
+	buf[4] = 6
+	buf[5] = 4
+	binary.BigEndian.PutUint16(buf[6:8], arpOpReply)
+	copy(buf[8:14], ourMAC)
+	copy(buf[14:18], ourIP.To4())
+	copy(buf[18:24], theirMAC)
+	copy(buf[24:28], theirIP.To4())
+	return buf
+}
+
+// handleARP replies to ARP requests for cfg.ServerIP (the gateway). Any
+// other ARP traffic is ignored - a real gateway would also answer for
+// itself only, so we don't need a full ARP cache here.
+func (s *Server) handleARP(eth ethernetFrame) []byte {
+	req, ok := parseARP(eth.payload)
+	if !ok || req.op != arpOpRequest {
+		return nil
+	}
+	if !req.targetIP.Equal(s.cfg.ServerIP) {
+		return nil
+	}
+
+	reply := buildARPReply(s.cfg.GatewayMAC, s.cfg.ServerIP, req.senderMAC, req.senderIP)
+	return buildEthernet(req.senderMAC, s.cfg.GatewayMAC, etherTypeARP, reply)
+}
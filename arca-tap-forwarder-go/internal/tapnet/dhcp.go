@@ -0,0 +1,176 @@
+package tapnet
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// BOOTP/DHCP (RFC 2131 / RFC 2132) constants. Only the subset this
+// in-process responder needs is modeled - no relay agent, no options we
+// don't emit ourselves.
+const (
+	bootpHeaderLen = 236
+	dhcpMagicCookie = 0x63825363
+
+	bootRequest = 1
+	bootReply   = 2
+
+	htypeEthernet = 1
+
+	dhcpClientPort = 68
+	dhcpServerPort = 67
+
+	optPad          = 0
+	optSubnetMask   = 1
+	optRouter       = 3
+	optDNS          = 6
+	optRequestedIP  = 50
+	optLeaseTime    = 51
+	optMsgType      = 53
+	optServerID     = 54
+	optParamReqList = 55
+	optMTU          = 26
+	optEnd          = 255
+
+	dhcpDiscover = 1
+	dhcpOffer    = 2
+	dhcpRequest  = 3
+	dhcpAck      = 5
+	dhcpNak      = 6
+)
+
+// dhcpMessage is a parsed BOOTP/DHCP message.
+type dhcpMessage struct {
+	op      byte
+	xid     uint32
+	chAddr  net.HardwareAddr
+	ciAddr  net.IP
+	options map[byte][]byte
+}
+
+func parseDHCP(buf []byte) (dhcpMessage, bool) {
+	if len(buf) < bootpHeaderLen+4 {
+		return dhcpMessage{}, false
+	}
+	if binary.BigEndian.Uint32(buf[236:240]) != dhcpMagicCookie {
+		return dhcpMessage{}, false
+	}
+
+	msg := dhcpMessage{
+		op:      buf[0],
+		xid:     binary.BigEndian.Uint32(buf[4:8]),
+		ciAddr:  net.IP(append([]byte(nil), buf[12:16]...)),
+		chAddr:  net.HardwareAddr(append([]byte(nil), buf[28:34]...)),
+		options: make(map[byte][]byte),
+	}
+
+	opts := buf[240:]
+	for i := 0; i < len(opts); {
+		code := opts[i]
+		if code == optEnd {
+			break
+		}
+		if code == optPad {
+			i++
+			continue
+		}
+		if i+1 >= len(opts) {
+			break
+		}
+		l := int(opts[i+1])
+		if i+2+l > len(opts) {
+			break
+		}
+		msg.options[code] = opts[i+2 : i+2+l]
+		i += 2 + l
+	}
+
+	return msg, true
+}
+
+func (m dhcpMessage) msgType() byte {
+	if v, ok := m.options[optMsgType]; ok && len(v) == 1 {
+		return v[0]
+	}
+	return 0
+}
+
+func (m dhcpMessage) requestedIP() net.IP {
+	if v, ok := m.options[optRequestedIP]; ok && len(v) == 4 {
+		return net.IP(v)
+	}
+	return nil
+}
+
+// buildDHCPReply constructs an OFFER or ACK BOOTP message for req.
+func (s *Server) buildDHCPReply(req dhcpMessage, msgType byte) []byte {
+	buf := make([]byte, bootpHeaderLen+4)
+	buf[0] = bootReply
+	buf[1] = htypeEthernet
+	buf[2] = 6 // hardware address length
+	binary.BigEndian.PutUint32(buf[4:8], req.xid)
+	copy(buf[16:20], s.cfg.ClientIP.To4()) // yiaddr: "your" (client) IP
+	copy(buf[20:24], s.cfg.ServerIP.To4()) // siaddr: next server
+	copy(buf[28:34], req.chAddr)
+	binary.BigEndian.PutUint32(buf[236:240], dhcpMagicCookie)
+
+	opts := make([]byte, 0, 64)
+	opts = appendOption(opts, optMsgType, []byte{msgType})
+	opts = appendOption(opts, optServerID, s.cfg.ServerIP.To4())
+	opts = appendOption(opts, optLeaseTime, uint32Bytes(s.cfg.LeaseSeconds))
+	opts = appendOption(opts, optSubnetMask, s.cfg.Netmask.To4())
+	opts = appendOption(opts, optRouter, s.cfg.ServerIP.To4())
+	if len(s.cfg.DNS) > 0 {
+		dns := make([]byte, 0, 4*len(s.cfg.DNS))
+		for _, ip := range s.cfg.DNS {
+			dns = append(dns, ip.To4()...)
+		}
+		opts = appendOption(opts, optDNS, dns)
+	}
+	if s.cfg.MTU > 0 {
+		opts = appendOption(opts, optMTU, []byte{byte(s.cfg.MTU >> 8), byte(s.cfg.MTU)})
+	}
+	opts = append(opts, optEnd)
+
+	return append(buf, opts...)
+}
+
+func appendOption(opts []byte, code byte, value []byte) []byte {
+	opts = append(opts, code, byte(len(value)))
+	return append(opts, value...)
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+// handleDHCP implements the DISCOVER/OFFER, REQUEST/ACK exchange for a
+// single statically-assigned client IP (cfg.ClientIP). There is no lease
+// pool: the TAP side only ever has one peer, the enclave guest, so we just
+// hand out the configured address every time.
+func (s *Server) handleDHCP(eth ethernetFrame, udp udpPacket) []byte {
+	req, ok := parseDHCP(udp.payload)
+	if !ok || req.op != bootRequest {
+		return nil
+	}
+
+	var reply []byte
+	switch req.msgType() {
+	case dhcpDiscover:
+		reply = s.buildDHCPReply(req, dhcpOffer)
+	case dhcpRequest:
+		requested := req.requestedIP()
+		if requested != nil && !requested.Equal(s.cfg.ClientIP) {
+			reply = s.buildDHCPReply(req, dhcpNak)
+		} else {
+			reply = s.buildDHCPReply(req, dhcpAck)
+		}
+	default:
+		return nil
+	}
+
+	ipPkt := buildUDPv4(s.cfg.ServerIP, s.cfg.ClientIP, dhcpServerPort, dhcpClientPort, reply)
+	return buildEthernet(eth.src, s.cfg.GatewayMAC, etherTypeIPv4, ipPkt)
+}
@@ -0,0 +1,51 @@
+package tapnet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const (
+	ethHeaderLen = 14
+
+	etherTypeIPv4 = 0x0800
+	etherTypeARP  = 0x0806
+
+	broadcastMAC = "\xff\xff\xff\xff\xff\xff"
+)
+
+// ethernetFrame is a thin view over a raw Ethernet II frame. It does not
+// copy the underlying buffer.
+type ethernetFrame struct {
+	dst       net.HardwareAddr
+	src       net.HardwareAddr
+	etherType uint16
+	payload   []byte
+}
+
+func parseEthernet(buf []byte) (ethernetFrame, error) {
+	if len(buf) < ethHeaderLen {
+		return ethernetFrame{}, fmt.Errorf("tapnet: frame too short (%d bytes)", len(buf))
+	}
+	return ethernetFrame{
+		dst:       net.HardwareAddr(buf[0:6]),
+		src:       net.HardwareAddr(buf[6:12]),
+		etherType: binary.BigEndian.Uint16(buf[12:14]),
+		payload:   buf[ethHeaderLen:],
+	}, nil
+}
+
+// buildEthernet prepends an Ethernet II header to payload.
+func buildEthernet(dst, src net.HardwareAddr, etherType uint16, payload []byte) []byte {
+	frame := make([]byte, ethHeaderLen+len(payload))
+	copy(frame[0:6], dst)
+	copy(frame[6:12], src)
+	binary.BigEndian.PutUint16(frame[12:14], etherType)
+	copy(frame[ethHeaderLen:], payload)
+	return frame
+}
+
+func isBroadcast(mac net.HardwareAddr) bool {
+	return string(mac) == broadcastMAC
+}
@@ -0,0 +1,95 @@
+package tapnet
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+const (
+	ipv4HeaderLen = 20
+	udpHeaderLen  = 8
+
+	protoUDP = 17
+)
+
+type ipv4Packet struct {
+	src, dst net.IP
+	protocol byte
+	payload  []byte
+}
+
+func parseIPv4(buf []byte) (ipv4Packet, bool) {
+	if len(buf) < ipv4HeaderLen {
+		return ipv4Packet{}, false
+	}
+	ihl := int(buf[0]&0x0f) * 4
+	if ihl < ipv4HeaderLen || len(buf) < ihl {
+		return ipv4Packet{}, false
+	}
+	return ipv4Packet{
+		src:      net.IP(buf[12:16]),
+		dst:      net.IP(buf[16:20]),
+		protocol: buf[9],
+		payload:  buf[ihl:],
+	}, true
+}
+
+type udpPacket struct {
+	srcPort, dstPort uint16
+	payload          []byte
+}
+
+func parseUDP(buf []byte) (udpPacket, bool) {
+	if len(buf) < udpHeaderLen {
+		return udpPacket{}, false
+	}
+	return udpPacket{
+		srcPort: binary.BigEndian.Uint16(buf[0:2]),
+		dstPort: binary.BigEndian.Uint16(buf[2:4]),
+		payload: buf[udpHeaderLen:],
+	}, true
+}
+
+func ipChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i : i+2]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
+
+// buildUDPv4 wraps payload in a UDP/IPv4 datagram addressed src:srcPort ->
+// dst:dstPort, suitable for the DHCP server replies this package sends.
+// Fragmentation, options and anything beyond the bare minimum needed for
+// BOOTP are intentionally unsupported.
+func buildUDPv4(src, dst net.IP, srcPort, dstPort uint16, payload []byte) []byte {
+	udp := make([]byte, udpHeaderLen+len(payload))
+	binary.BigEndian.PutUint16(udp[0:2], srcPort)
+	binary.BigEndian.PutUint16(udp[2:4], dstPort)
+	binary.BigEndian.PutUint16(udp[4:6], uint16(len(udp)))
+	copy(udp[udpHeaderLen:], payload)
+
+	total := ipv4HeaderLen + len(udp)
+	ip := make([]byte, total)
+	ip[0] = 0x45 // version 4, IHL 5
+	ip[1] = 0x00 // DSCP/ECN
+	binary.BigEndian.PutUint16(ip[2:4], uint16(total))
+	binary.BigEndian.PutUint16(ip[4:6], 0) // identification
+	binary.BigEndian.PutUint16(ip[6:8], 0) // flags/fragment offset
+	ip[8] = 64                             // TTL
+	ip[9] = protoUDP
+	copy(ip[12:16], src.To4())
+	copy(ip[16:20], dst.To4())
+	binary.BigEndian.PutUint16(ip[10:12], ipChecksum(ip[0:20]))
+	copy(ip[ipv4HeaderLen:], udp)
+
+	// UDP checksum is optional over IPv4 (0 disables verification); DHCP
+	// clients in the wild tolerate this, so we skip the pseudo-header dance.
+	return ip
+}
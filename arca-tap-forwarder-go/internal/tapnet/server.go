@@ -0,0 +1,102 @@
+// Package tapnet synthesizes the link-layer services a bare TAP device
+// needs to act as a gateway - ARP replies and DHCPv4 - without requiring
+// the enclave-side guest to be statically configured. It sits between
+// TAP.Read/Write and the rest of the forwarder: call Run to pump frames,
+// and everything that isn't ARP-for-the-gateway or DHCP is handed to a
+// user-supplied packet handler.
+package tapnet
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+)
+
+// tapDevice is the subset of *tap.TAP this package depends on. Declared
+// locally (rather than importing the tap package) so tests can supply a
+// fake without needing a real platform TAP device.
+type tapDevice interface {
+	Read(buf []byte) (int, error)
+	Write(buf []byte) (int, error)
+}
+
+// PacketHandler receives every Ethernet frame that isn't consumed by the
+// built-in ARP/DHCP responders (i.e. everything but ARP-for-gateway and
+// UDP/67 traffic). Frames are only valid for the duration of the call.
+type PacketHandler func(frame []byte)
+
+// Config configures the synthesized gateway services.
+type Config struct {
+	ServerIP     net.IP           // gateway/DHCP server address
+	ClientIP     net.IP           // address handed out to the guest
+	Netmask      net.IP           // subnet mask advertised via DHCP option 1
+	GatewayMAC   net.HardwareAddr // source MAC for ARP/DHCP replies
+	DNS          []net.IP         // DNS servers advertised via DHCP option 6
+	LeaseSeconds uint32           // DHCP lease time
+	MTU          uint16           // optional MTU advertised via DHCP option 26
+}
+
+// Server dispatches frames read from a TAP device to the ARP responder,
+// the DHCP responder, or a user-supplied handler.
+type Server struct {
+	tap     tapDevice
+	cfg     Config
+	handler PacketHandler
+}
+
+// New creates a tapnet Server bound to tap. handler (if non-nil) is
+// invoked for every frame that isn't ARP-for-gateway or DHCP traffic.
+func New(tap tapDevice, cfg Config, handler PacketHandler) *Server {
+	return &Server{tap: tap, cfg: cfg, handler: handler}
+}
+
+// Run reads frames from the TAP device until ctx is cancelled or a fatal
+// read error occurs, dispatching ARP and DHCP traffic to the built-in
+// responders and everything else to the configured handler.
+func (s *Server) Run(ctx context.Context) error {
+	buf := make([]byte, 65536)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		n, err := s.tap.Read(buf)
+		if err != nil {
+			return fmt.Errorf("tapnet: TAP read failed: %w", err)
+		}
+
+		eth, err := parseEthernet(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		switch eth.etherType {
+		case etherTypeARP:
+			if reply := s.handleARP(eth); reply != nil {
+				if _, err := s.tap.Write(reply); err != nil {
+					log.Printf("tapnet: failed to write ARP reply: %v", err)
+				}
+				continue
+			}
+		case etherTypeIPv4:
+			if ip, ok := parseIPv4(eth.payload); ok && ip.protocol == protoUDP {
+				if udp, ok := parseUDP(ip.payload); ok && udp.dstPort == dhcpServerPort {
+					if reply := s.handleDHCP(eth, udp); reply != nil {
+						if _, err := s.tap.Write(reply); err != nil {
+							log.Printf("tapnet: failed to write DHCP reply: %v", err)
+						}
+					}
+					continue
+				}
+			}
+		}
+
+		if s.handler != nil {
+			s.handler(buf[:n])
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+
+	"arca-vsock-proxy/internal/relay"
+)
+
+// startAdminServer starts an HTTP server on addr exposing the relay
+// registry's /metrics, mirroring arca-tap-forwarder's opt-in diagnostic
+// server. Returns nil if addr is empty (disabled, the default).
+func startAdminServer(addr string, reg *relay.Registry) *http.Server {
+	if addr == "" {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("admin server: listen on %s: %v", addr, err)
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", reg)
+	srv := &http.Server{Handler: mux}
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("admin server: %v", err)
+		}
+	}()
+
+	log.Printf("Admin server listening on %s (/metrics)", addr)
+	return srv
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Well-known vsock context IDs (see Linux's include/uapi/linux/vm_sockets.h),
+// exposed by name so reverse forwards don't need to hardcode the magic
+// numbers.
+const (
+	VMADDR_CID_HYPERVISOR uint32 = 0
+	VMADDR_CID_LOCAL      uint32 = 1
+	VMADDR_CID_HOST       uint32 = 2
+	VMADDR_CID_ANY        uint32 = 0xFFFFFFFF
+)
+
+// parseCID parses a -target-cid flag value, accepting either a plain
+// decimal number or one of the well-known names above (case-insensitive).
+func parseCID(s string) (uint32, error) {
+	switch strings.ToLower(s) {
+	case "hypervisor":
+		return VMADDR_CID_HYPERVISOR, nil
+	case "local":
+		return VMADDR_CID_LOCAL, nil
+	case "host":
+		return VMADDR_CID_HOST, nil
+	case "any":
+		return VMADDR_CID_ANY, nil
+	}
+
+	v, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CID %q: must be a number or one of hypervisor/local/host/any", s)
+	}
+	return uint32(v), nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"arca-vsock-proxy/internal/transport"
+)
+
+// Config is the proxy's full configuration: every vsock forward it should
+// run concurrently.
+type Config struct {
+	Forwards []ForwardConfig `json:"forwards"`
+}
+
+// Direction selects which side of a ForwardConfig listens and which side
+// is dialed.
+type Direction string
+
+const (
+	// DirectionForward listens on vsock and dials TCP - a guest-side
+	// service reaching out to the host. This is the default and the
+	// only direction this proxy originally supported.
+	DirectionForward Direction = "forward"
+
+	// DirectionReverse listens on TCP and dials vsock - the host
+	// reaching into a service running in the guest.
+	DirectionReverse Direction = "reverse"
+)
+
+// ForwardConfig is one listener's configuration. Its meaning depends on
+// Direction:
+//
+// In DirectionForward (the default), it's a vsock listener on VsockPort;
+// exactly one of TCPAddr or SOCKS5 must be set, giving either a fixed
+// single-target forward or a SOCKS5 server that dials whatever target the
+// guest requests.
+//
+// In DirectionReverse, TCPAddr is instead the address to listen on, and
+// every accepted connection is dialed into the guest at TargetCID:VsockPort;
+// SOCKS5 isn't supported in this direction.
+type ForwardConfig struct {
+	// VsockPort is the vsock port to listen on (DirectionForward) or to
+	// dial (DirectionReverse).
+	VsockPort uint32 `json:"vsock_port"`
+
+	// TCPAddr is the fixed TCP target every connection is proxied to
+	// (DirectionForward), or the TCP address to listen on
+	// (DirectionReverse).
+	TCPAddr string `json:"tcp_addr,omitempty"`
+
+	// SOCKS5, if set, turns this listener into a SOCKS5 (RFC 1928) server
+	// instead of a fixed-target forward. DirectionForward only.
+	SOCKS5 *SOCKS5Config `json:"socks5,omitempty"`
+
+	// Direction selects which side listens and which side is dialed.
+	// Defaults to DirectionForward if empty.
+	Direction Direction `json:"direction,omitempty"`
+
+	// TargetCID is the vsock context ID to dial for DirectionReverse
+	// forwards - see VMADDR_CID_* for well-known values. Required for
+	// DirectionReverse, and unused otherwise.
+	TargetCID uint32 `json:"target_cid,omitempty"`
+
+	// Transport, if set, wraps the TCP-side dial (TLS, mutual TLS, or a
+	// WebSocket tunnel) instead of dialing plain TCP. DirectionForward
+	// only; reverse forwards dial vsock, not TCP.
+	Transport *transport.Config `json:"transport,omitempty"`
+}
+
+// SOCKS5Config configures a SOCKS5 listener's auth requirements.
+type SOCKS5Config struct {
+	// Username and Password, if Username is set, require SOCKS5
+	// username/password auth (RFC 1929) instead of no-auth.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// Validate reports whether f is a well-formed forward configuration.
+func (f ForwardConfig) Validate() error {
+	if f.VsockPort == 0 {
+		return fmt.Errorf("vsock_port is required")
+	}
+
+	switch f.Direction {
+	case "", DirectionForward:
+		if (f.TCPAddr == "") == (f.SOCKS5 == nil) {
+			return fmt.Errorf("vsock port %d: exactly one of tcp_addr or socks5 must be set", f.VsockPort)
+		}
+		if f.TargetCID != 0 {
+			return fmt.Errorf("vsock port %d: target_cid only applies to reverse forwards", f.VsockPort)
+		}
+	case DirectionReverse:
+		if f.TCPAddr == "" {
+			return fmt.Errorf("vsock port %d: tcp_addr (the address to listen on) is required for reverse forwards", f.VsockPort)
+		}
+		if f.SOCKS5 != nil {
+			return fmt.Errorf("vsock port %d: socks5 is not supported for reverse forwards", f.VsockPort)
+		}
+		if f.TargetCID == 0 {
+			return fmt.Errorf("vsock port %d: target_cid is required for reverse forwards", f.VsockPort)
+		}
+		if f.Transport != nil {
+			return fmt.Errorf("vsock port %d: transport only applies to forward-direction dials", f.VsockPort)
+		}
+	default:
+		return fmt.Errorf("vsock port %d: unknown direction %q", f.VsockPort, f.Direction)
+	}
+
+	if f.Transport != nil {
+		if _, err := transport.Build(f.Transport); err != nil {
+			return fmt.Errorf("vsock port %d: %w", f.VsockPort, err)
+		}
+	}
+	return nil
+}
+
+// LoadConfig reads and parses a JSON config file listing one or more
+// forwards. YAML isn't supported: this module has no vendored YAML
+// parser, so JSON is the only file format offered; single-forward setups
+// can skip a config file entirely and use the top-level flags instead.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	for i, f := range cfg.Forwards {
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("forwards[%d]: %w", i, err)
+		}
+	}
+
+	return &cfg, nil
+}
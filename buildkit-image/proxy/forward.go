@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/mdlayher/vsock"
+
+	"arca-vsock-proxy/internal/policy"
+	"arca-vsock-proxy/internal/relay"
+	"arca-vsock-proxy/internal/transport"
+)
+
+// sessionCfg, metrics, and activePolicy are set once from flags in main
+// before any forward starts, and read by every splice/handle*Connection
+// call - package-level state is fine here since they're write-once-then-
+// read-only, and threading them through every signature would be pure
+// plumbing.
+var (
+	sessionCfg   relay.Config
+	metrics      relay.Metrics = relay.NopMetrics{}
+	activePolicy policy.Policy = policy.AllowAll{}
+)
+
+// vsockPeer extracts the dialing peer's CID and port from a vsock
+// connection's remote address, for passing to activePolicy.
+func vsockPeer(conn net.Conn) policy.Peer {
+	if addr, ok := conn.RemoteAddr().(*vsock.Addr); ok {
+		return policy.Peer{CID: addr.ContextID, Port: addr.Port}
+	}
+	return policy.Peer{}
+}
+
+// runForward runs fc until its listener fails, dispatching on fc.Direction.
+func runForward(fc ForwardConfig) error {
+	if fc.Direction == DirectionReverse {
+		return runReverse(fc)
+	}
+	return runVsockForward(fc)
+}
+
+// runVsockForward listens on fc.VsockPort and, for every accepted
+// connection, either proxies straight to fc.TCPAddr or - if fc.SOCKS5 is
+// set - speaks SOCKS5 to negotiate a per-connection target. It blocks
+// until the listener fails.
+func runVsockForward(fc ForwardConfig) error {
+	tr, err := transport.Build(fc.Transport)
+	if err != nil {
+		return fmt.Errorf("vsock port %d: %w", fc.VsockPort, err)
+	}
+
+	listener, err := vsock.Listen(fc.VsockPort, nil)
+	if err != nil {
+		return fmt.Errorf("listening on vsock port %d: %w", fc.VsockPort, err)
+	}
+	defer listener.Close()
+
+	if fc.SOCKS5 != nil {
+		log.Printf("Starting SOCKS5 proxy: vsock:%d", fc.VsockPort)
+	} else {
+		log.Printf("Starting vsock-to-TCP proxy: vsock:%d -> %s", fc.VsockPort, fc.TCPAddr)
+	}
+
+	for {
+		vsockConn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("accepting vsock connection on port %d: %w", fc.VsockPort, err)
+		}
+
+		log.Printf("Accepted vsock connection from %s on port %d", vsockConn.RemoteAddr(), fc.VsockPort)
+		if fc.SOCKS5 != nil {
+			go handleSOCKS5Connection(vsockConn, fc.SOCKS5, tr)
+		} else {
+			go handleFixedConnection(vsockConn, fc.TCPAddr, tr)
+		}
+	}
+}
+
+// runReverse listens on fc.TCPAddr and, for every accepted connection,
+// dials into fc.TargetCID:fc.VsockPort over vsock and splices the two -
+// the host-to-guest counterpart to runVsockForward's guest-to-host
+// forwards. It blocks until the listener fails.
+func runReverse(fc ForwardConfig) error {
+	listener, err := net.Listen("tcp", fc.TCPAddr)
+	if err != nil {
+		return fmt.Errorf("listening on TCP %s: %w", fc.TCPAddr, err)
+	}
+	defer listener.Close()
+
+	log.Printf("Starting TCP-to-vsock reverse proxy: %s -> vsock cid=%d port=%d", fc.TCPAddr, fc.TargetCID, fc.VsockPort)
+
+	for {
+		tcpConn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("accepting TCP connection on %s: %w", fc.TCPAddr, err)
+		}
+
+		log.Printf("Accepted TCP connection from %s on %s", tcpConn.RemoteAddr(), fc.TCPAddr)
+		go handleReverseConnection(tcpConn, fc.TargetCID, fc.VsockPort)
+	}
+}
+
+// handleReverseConnection dials cid:port over vsock and splices it to
+// tcpConn - the reverse-direction counterpart to handleFixedConnection.
+func handleReverseConnection(tcpConn net.Conn, cid, port uint32) {
+	defer tcpConn.Close()
+
+	vsockConn, err := vsock.Dial(cid, port, nil)
+	if err != nil {
+		log.Printf("Failed to dial vsock cid=%d port=%d: %v", cid, port, err)
+		return
+	}
+	defer vsockConn.Close()
+
+	log.Printf("Reverse proxying connection: %s <-> vsock cid=%d port=%d", tcpConn.RemoteAddr(), cid, port)
+	splice(tcpConn, vsockConn)
+	log.Printf("Reverse connection closed: %s", tcpConn.RemoteAddr())
+}
+
+// handleFixedConnection proxies vsockConn to the single fixed TCP target
+// addr - the pre-multi-forward behavior of this binary, for forwards that
+// don't need SOCKS5's dynamic destination. tr dials addr, wrapping it in
+// TLS/mTLS/WebSocket if configured; TCPTransport{} dials plain TCP.
+func handleFixedConnection(vsockConn net.Conn, addr string, tr transport.Transport) {
+	defer vsockConn.Close()
+
+	peer := vsockPeer(vsockConn)
+	target, err := policy.ParseTarget(addr)
+	if err != nil {
+		log.Printf("Policy: %v", err)
+		return
+	}
+	verdict, err := activePolicy.Evaluate(peer, target)
+	if err != nil {
+		log.Printf("Policy: evaluating %s -> %s: %v", peer, target, err)
+		return
+	}
+	if !verdict.Allow {
+		log.Printf("Policy: denied %s -> %s (%s)", peer, target, verdict.Reason)
+		return
+	}
+	if verdict.RewriteTarget != nil {
+		target = *verdict.RewriteTarget
+	}
+
+	tcpConn, err := tr.Dial(context.Background(), target.String())
+	if err != nil {
+		log.Printf("Failed to connect to TCP %s: %v", target, err)
+		return
+	}
+	defer tcpConn.Close()
+
+	log.Printf("Proxying connection: %s <-> %s", vsockConn.RemoteAddr(), target)
+	splice(vsockConn, tcpConn)
+	log.Printf("Connection closed: %s", vsockConn.RemoteAddr())
+}
+
+// splice proxies a <-> b via a relay.ProxySession, applying this
+// process's configured idle timeout and reporting to the shared metrics
+// registry. It blocks until both directions finish.
+func splice(a, b net.Conn) {
+	relay.NewProxySession(a, b, sessionCfg, metrics).Run(context.Background())
+}
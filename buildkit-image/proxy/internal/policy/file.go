@@ -0,0 +1,165 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileConfig is a file-based policy's configuration: an ordered list of
+// rules, evaluated first-match-wins like a firewall ruleset.
+type FileConfig struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule matches connections by the dialing peer's CID range and the
+// requested destination, and either allows (optionally rate-limited) or
+// denies them.
+type Rule struct {
+	// CIDFrom and CIDTo bound the vsock context IDs this rule applies
+	// to, inclusive. Both zero matches every CID.
+	CIDFrom uint32 `json:"cid_from"`
+	CIDTo   uint32 `json:"cid_to"`
+
+	// DestPattern matches the requested "host:port", e.g.
+	// "*.example.com:443", "10.0.0.0/8:*", "*:*". The host half is a
+	// shell-style glob, or a CIDR if it contains a "/"; the port half
+	// is a literal port number or "*".
+	DestPattern string `json:"dest_pattern"`
+
+	// Action is "allow" or "deny".
+	Action string `json:"action"`
+
+	// RateLimitPerMinute, if nonzero, caps how many connections this
+	// rule's CID range may open per minute once Action is "allow".
+	RateLimitPerMinute int `json:"rate_limit_per_minute,omitempty"`
+}
+
+// FilePolicy is the default Policy implementation: a static, file-loaded
+// ruleset plus in-memory per-CID rate limiting.
+type FilePolicy struct {
+	rules []Rule
+
+	mu    sync.Mutex
+	rates map[uint32]*rateWindow
+}
+
+type rateWindow struct {
+	windowEnd time.Time
+	count     int
+}
+
+// LoadFilePolicy reads and parses a JSON policy file.
+func LoadFilePolicy(filePath string) (*FilePolicy, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("policy: reading %s: %w", filePath, err)
+	}
+
+	var cfg FileConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("policy: parsing %s: %w", filePath, err)
+	}
+
+	for i, r := range cfg.Rules {
+		if r.Action != "allow" && r.Action != "deny" {
+			return nil, fmt.Errorf("policy: rules[%d]: action must be \"allow\" or \"deny\", got %q", i, r.Action)
+		}
+	}
+
+	return &FilePolicy{rules: cfg.Rules, rates: make(map[uint32]*rateWindow)}, nil
+}
+
+// Evaluate checks peer and target against p's rules in order. If no rule
+// matches, the connection is allowed - the same default-allow posture the
+// proxy had before any policy existed.
+func (p *FilePolicy) Evaluate(peer Peer, target Target) (Verdict, error) {
+	for _, r := range p.rules {
+		if !cidInRange(peer.CID, r.CIDFrom, r.CIDTo) {
+			continue
+		}
+		matched, err := matchDest(r.DestPattern, target)
+		if err != nil {
+			return Verdict{}, fmt.Errorf("policy: rule %q: %w", r.DestPattern, err)
+		}
+		if !matched {
+			continue
+		}
+
+		if r.Action == "deny" {
+			return Verdict{Allow: false, Reason: fmt.Sprintf("denied by rule cid[%d-%d] %s", r.CIDFrom, r.CIDTo, r.DestPattern)}, nil
+		}
+		if r.RateLimitPerMinute > 0 && !p.allowRate(peer.CID, r.RateLimitPerMinute) {
+			return Verdict{Allow: false, Reason: fmt.Sprintf("rate limit of %d/min exceeded for cid %d", r.RateLimitPerMinute, peer.CID)}, nil
+		}
+		return Verdict{Allow: true}, nil
+	}
+	return Verdict{Allow: true}, nil
+}
+
+func (p *FilePolicy) allowRate(cid uint32, limit int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	w, ok := p.rates[cid]
+	if !ok {
+		w = &rateWindow{}
+		p.rates[cid] = w
+	}
+
+	now := time.Now()
+	if now.After(w.windowEnd) {
+		w.windowEnd = now.Add(time.Minute)
+		w.count = 0
+	}
+	w.count++
+	return w.count <= limit
+}
+
+func cidInRange(cid, from, to uint32) bool {
+	if from == 0 && to == 0 {
+		return true
+	}
+	return cid >= from && cid <= to
+}
+
+// matchDest reports whether target matches a "host:port" pattern, where
+// the host half is either a CIDR (if it contains "/") matched against
+// target's IP, or a shell-style glob matched against target's host, and
+// the port half is "*" or a literal port number.
+func matchDest(pattern string, target Target) (bool, error) {
+	host, portStr, err := net.SplitHostPort(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid dest_pattern %q: %w", pattern, err)
+	}
+
+	if portStr != "*" {
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return false, fmt.Errorf("invalid dest_pattern port %q: %w", pattern, err)
+		}
+		if uint16(port) != target.Port {
+			return false, nil
+		}
+	}
+
+	if strings.Contains(host, "/") {
+		_, cidr, err := net.ParseCIDR(host)
+		if err != nil {
+			return false, fmt.Errorf("invalid dest_pattern CIDR %q: %w", host, err)
+		}
+		ip := net.ParseIP(target.Host)
+		if ip == nil {
+			return false, nil
+		}
+		return cidr.Contains(ip), nil
+	}
+
+	return path.Match(host, target.Host)
+}
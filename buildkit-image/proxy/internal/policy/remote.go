@@ -0,0 +1,71 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// RemotePolicy asks an external daemon over a Unix socket for an
+// allow/deny verdict on every connection, so operators can run policy
+// logic - audit logging, human approval, process attribution - out of
+// this process entirely. One connect-request-response round trip per
+// Evaluate call; the daemon is free to take as long as it needs to
+// decide within Timeout.
+type RemotePolicy struct {
+	// SocketPath is the Unix socket the verdict daemon listens on.
+	SocketPath string
+
+	// Timeout bounds the whole round trip. Zero means no timeout.
+	Timeout time.Duration
+}
+
+type verdictRequest struct {
+	PeerCID    uint32 `json:"peer_cid"`
+	PeerPort   uint32 `json:"peer_port"`
+	TargetHost string `json:"target_host"`
+	TargetPort uint16 `json:"target_port"`
+}
+
+type verdictResponse struct {
+	Allow       bool   `json:"allow"`
+	Reason      string `json:"reason,omitempty"`
+	RewriteHost string `json:"rewrite_host,omitempty"`
+	RewritePort uint16 `json:"rewrite_port,omitempty"`
+}
+
+// Evaluate sends peer and target to the verdict daemon and returns its
+// decision.
+func (p *RemotePolicy) Evaluate(peer Peer, target Target) (Verdict, error) {
+	conn, err := net.DialTimeout("unix", p.SocketPath, p.Timeout)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("policy: dialing verdict socket %s: %w", p.SocketPath, err)
+	}
+	defer conn.Close()
+
+	if p.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(p.Timeout))
+	}
+
+	req := verdictRequest{
+		PeerCID:    peer.CID,
+		PeerPort:   peer.Port,
+		TargetHost: target.Host,
+		TargetPort: target.Port,
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Verdict{}, fmt.Errorf("policy: writing verdict request to %s: %w", p.SocketPath, err)
+	}
+
+	var resp verdictResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Verdict{}, fmt.Errorf("policy: reading verdict response from %s: %w", p.SocketPath, err)
+	}
+
+	v := Verdict{Allow: resp.Allow, Reason: resp.Reason}
+	if resp.RewriteHost != "" {
+		v.RewriteTarget = &Target{Host: resp.RewriteHost, Port: resp.RewritePort}
+	}
+	return v, nil
+}
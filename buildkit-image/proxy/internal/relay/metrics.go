@@ -0,0 +1,105 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is what a ProxySession reports its lifecycle to.
+type Metrics interface {
+	ConnectionOpened()
+	ConnectionClosed(d time.Duration)
+	BytesTransferred(direction string, n int64)
+}
+
+// NopMetrics discards everything, for callers that don't want metrics.
+type NopMetrics struct{}
+
+func (NopMetrics) ConnectionOpened()                          {}
+func (NopMetrics) ConnectionClosed(time.Duration)             {}
+func (NopMetrics) BytesTransferred(direction string, n int64) {}
+
+var durationBuckets = []float64{0.1, 0.5, 1, 5, 15, 60, 300}
+
+// Registry is the default Metrics implementation: in-memory counters and
+// a duration histogram, exposed in Prometheus text exposition format via
+// ServeHTTP. There's no vendored Prometheus client library in this
+// module, so - matching arca-tap-forwarder's diag server - the
+// exposition text is hand-rolled rather than pulling one in.
+type Registry struct {
+	connectionsTotal atomic.Uint64
+
+	bytesMu sync.Mutex
+	bytes   map[string]uint64
+
+	durationCounts []atomic.Uint64
+	durationSumUs  atomic.Uint64
+	durationCount  atomic.Uint64
+}
+
+// NewRegistry returns an empty Registry ready to serve as a
+// ProxySession's Metrics and to be mounted on an admin HTTP server.
+func NewRegistry() *Registry {
+	return &Registry{
+		bytes:          make(map[string]uint64),
+		durationCounts: make([]atomic.Uint64, len(durationBuckets)),
+	}
+}
+
+func (r *Registry) ConnectionOpened() {
+	r.connectionsTotal.Add(1)
+}
+
+func (r *Registry) ConnectionClosed(d time.Duration) {
+	r.durationCount.Add(1)
+	r.durationSumUs.Add(uint64(d.Microseconds()))
+
+	secs := d.Seconds()
+	for i, bound := range durationBuckets {
+		if secs <= bound {
+			r.durationCounts[i].Add(1)
+		}
+	}
+}
+
+func (r *Registry) BytesTransferred(direction string, n int64) {
+	r.bytesMu.Lock()
+	r.bytes[direction] += uint64(n)
+	r.bytesMu.Unlock()
+}
+
+// ServeHTTP writes the current counters in Prometheus text exposition
+// format, for mounting at /metrics on an admin listener.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP arca_relay_connections_total Connections proxied since start.")
+	fmt.Fprintln(w, "# TYPE arca_relay_connections_total counter")
+	fmt.Fprintf(w, "arca_relay_connections_total %d\n", r.connectionsTotal.Load())
+
+	r.bytesMu.Lock()
+	directions := make([]string, 0, len(r.bytes))
+	for d := range r.bytes {
+		directions = append(directions, d)
+	}
+	sort.Strings(directions)
+	fmt.Fprintln(w, "# HELP arca_relay_bytes Bytes proxied since start, by direction.")
+	fmt.Fprintln(w, "# TYPE arca_relay_bytes counter")
+	for _, d := range directions {
+		fmt.Fprintf(w, "arca_relay_bytes{direction=%q} %d\n", d, r.bytes[d])
+	}
+	r.bytesMu.Unlock()
+
+	fmt.Fprintln(w, "# HELP arca_relay_duration_seconds How long proxied connections stayed open.")
+	fmt.Fprintln(w, "# TYPE arca_relay_duration_seconds histogram")
+	for i, bound := range durationBuckets {
+		fmt.Fprintf(w, "arca_relay_duration_seconds_bucket{le=%q} %d\n", fmt.Sprintf("%g", bound), r.durationCounts[i].Load())
+	}
+	fmt.Fprintf(w, "arca_relay_duration_seconds_bucket{le=\"+Inf\"} %d\n", r.durationCount.Load())
+	fmt.Fprintf(w, "arca_relay_duration_seconds_sum %g\n", float64(r.durationSumUs.Load())/1e6)
+	fmt.Fprintf(w, "arca_relay_duration_seconds_count %d\n", r.durationCount.Load())
+}
@@ -0,0 +1,126 @@
+// Package relay splices two connections together with the connection-
+// lifecycle discipline mature proxies apply to every leg: pooled buffers,
+// idle deadlines, correct half-close, and shared cancellation - replacing
+// the bare io.Copy goroutine pair the proxy used to spawn directly.
+package relay
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// CloseWriter is implemented by connections that support half-closing
+// their write side without tearing down the whole connection (e.g.
+// *net.TCPConn, *tls.Conn). ProxySession type-asserts against this
+// instead of a concrete type, so a leg can be redialed over any network
+// without the half-close logic panicking or silently becoming a no-op.
+type CloseWriter interface {
+	CloseWrite() error
+}
+
+var bufPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 32*1024)
+		return &buf
+	},
+}
+
+// Config controls a ProxySession's timeouts.
+type Config struct {
+	// IdleTimeout is how long a leg may go without a successful read
+	// before the session is torn down. Zero disables idle timeouts.
+	IdleTimeout time.Duration
+}
+
+// ProxySession splices two connections together. Each direction runs on
+// its own goroutine, refreshing IdleTimeout on every successful read; as
+// soon as either direction ends (EOF, error, or idle timeout), the other
+// is canceled so Run doesn't outlive a connection that's already half
+// dead.
+type ProxySession struct {
+	a, b    net.Conn
+	cfg     Config
+	metrics Metrics
+}
+
+// NewProxySession builds a ProxySession splicing a and b under cfg,
+// reporting to metrics. Pass NopMetrics{} if the caller doesn't care.
+func NewProxySession(a, b net.Conn, cfg Config, metrics Metrics) *ProxySession {
+	if metrics == nil {
+		metrics = NopMetrics{}
+	}
+	return &ProxySession{a: a, b: b, cfg: cfg, metrics: metrics}
+}
+
+// Run splices the two legs until both directions finish or ctx is
+// canceled, blocking until done.
+func (s *ProxySession) Run(ctx context.Context) {
+	start := time.Now()
+	s.metrics.ConnectionOpened()
+	defer func() { s.metrics.ConnectionClosed(time.Since(start)) }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		s.copyDirection(ctx, s.b, s.a, "a_to_b")
+	}()
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		s.copyDirection(ctx, s.a, s.b, "b_to_a")
+	}()
+	wg.Wait()
+}
+
+// copyDirection copies src into dst, refreshing the idle deadline on
+// every successful read and half-closing dst once src is exhausted. A
+// watcher goroutine forces src's read deadline as soon as ctx is
+// canceled, so a blocked Read from the other direction failing doesn't
+// keep this one alive indefinitely.
+func (s *ProxySession) copyDirection(ctx context.Context, dst, src net.Conn, direction string) {
+	bufp := bufPool.Get().(*[]byte)
+	defer bufPool.Put(bufp)
+	buf := *bufp
+
+	watchDone := make(chan struct{})
+	defer close(watchDone)
+	go func() {
+		select {
+		case <-ctx.Done():
+			src.SetReadDeadline(time.Now())
+		case <-watchDone:
+		}
+	}()
+
+	for {
+		if s.cfg.IdleTimeout > 0 {
+			src.SetReadDeadline(time.Now().Add(s.cfg.IdleTimeout))
+		}
+
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				break
+			}
+			s.metrics.BytesTransferred(direction, int64(n))
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	closeWrite(dst)
+}
+
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(CloseWriter); ok {
+		cw.CloseWrite()
+	}
+}
@@ -0,0 +1,70 @@
+package transport
+
+import "fmt"
+
+// Kind selects which Transport a Config builds.
+type Kind string
+
+const (
+	KindTCP       Kind = "tcp"
+	KindTLS       Kind = "tls"
+	KindMTLS      Kind = "mtls"
+	KindWebSocket Kind = "websocket"
+)
+
+// Config is the JSON-facing configuration for a Transport. Which fields
+// apply depends on Kind - see TLSConfig, MTLSConfig, and
+// WebSocketTransport's doc comments for what each one means.
+type Config struct {
+	Kind Kind `json:"kind,omitempty"`
+
+	ServerName         string `json:"server_name,omitempty"`
+	CAFile             string `json:"ca_file,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+
+	WebSocketURL string `json:"websocket_url,omitempty"`
+}
+
+// Build returns the Transport cfg describes. A nil cfg, or one with an
+// empty or "tcp" Kind, returns TCPTransport{} - the proxy's original
+// unwrapped dial behavior.
+func Build(cfg *Config) (Transport, error) {
+	if cfg == nil || cfg.Kind == "" || cfg.Kind == KindTCP {
+		return TCPTransport{}, nil
+	}
+
+	switch cfg.Kind {
+	case KindTLS:
+		return TLSTransport{Config: TLSConfig{
+			ServerName:         cfg.ServerName,
+			CAFile:             cfg.CAFile,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		}}, nil
+
+	case KindMTLS:
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("transport: mtls requires cert_file and key_file")
+		}
+		return MTLSTransport{Config: MTLSConfig{
+			TLSConfig: TLSConfig{
+				ServerName:         cfg.ServerName,
+				CAFile:             cfg.CAFile,
+				InsecureSkipVerify: cfg.InsecureSkipVerify,
+			},
+			CertFile: cfg.CertFile,
+			KeyFile:  cfg.KeyFile,
+		}}, nil
+
+	case KindWebSocket:
+		if cfg.WebSocketURL == "" {
+			return nil, fmt.Errorf("transport: websocket requires websocket_url")
+		}
+		return WebSocketTransport{URL: cfg.WebSocketURL}, nil
+
+	default:
+		return nil, fmt.Errorf("transport: unknown kind %q", cfg.Kind)
+	}
+}
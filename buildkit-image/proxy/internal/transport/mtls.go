@@ -0,0 +1,84 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+)
+
+// KeySource supplies a client certificate for MTLSTransport, letting
+// operators back it with something other than a plain cert/key file pair
+// - e.g. a key derived from a vTPM-backed attestation flow. This package
+// doesn't implement one itself; it's an extension point for callers.
+type KeySource interface {
+	ClientCertificate(ctx context.Context) (tls.Certificate, error)
+}
+
+// fileKeySource is the default KeySource: a plain cert/key file pair.
+type fileKeySource struct {
+	certFile, keyFile string
+}
+
+func (f fileKeySource) ClientCertificate(context.Context) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("transport: loading client cert %s/%s: %w", f.certFile, f.keyFile, err)
+	}
+	return cert, nil
+}
+
+// MTLSConfig configures a mutual-TLS dial.
+type MTLSConfig struct {
+	TLSConfig
+
+	// CertFile and KeyFile are the client certificate/key pair, used if
+	// KeySource is nil.
+	CertFile, KeyFile string
+
+	// KeySource, if set, supplies the client certificate instead of
+	// CertFile/KeyFile.
+	KeySource KeySource
+}
+
+// MTLSTransport is TLSTransport plus a client certificate presented to
+// the server.
+type MTLSTransport struct {
+	Inner  Transport
+	Config MTLSConfig
+}
+
+func (t MTLSTransport) Dial(ctx context.Context, target string) (net.Conn, error) {
+	inner := t.Inner
+	if inner == nil {
+		inner = TCPTransport{}
+	}
+	conn, err := inner.Dial(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg, err := t.Config.TLSConfig.toTLSConfig(target)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	keySource := t.Config.KeySource
+	if keySource == nil {
+		keySource = fileKeySource{certFile: t.Config.CertFile, keyFile: t.Config.KeyFile}
+	}
+	cert, err := keySource.ClientCertificate(ctx)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsCfg.Certificates = []tls.Certificate{cert}
+
+	tlsConn := tls.Client(conn, tlsCfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("transport: mTLS handshake with %s: %w", target, err)
+	}
+	return tlsConn, nil
+}
@@ -0,0 +1,90 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+)
+
+// TLSConfig configures a TLS-wrapped dial.
+type TLSConfig struct {
+	// ServerName overrides the SNI/certificate-verification name;
+	// defaults to the dialed target's host if empty.
+	ServerName string
+
+	// CAFile, if set, is a PEM bundle used instead of the system root
+	// pool to verify the server's certificate.
+	CAFile string
+
+	// InsecureSkipVerify disables certificate verification entirely -
+	// for testing only.
+	InsecureSkipVerify bool
+}
+
+// TLSTransport dials target over TCP via Inner (TCPTransport{} if nil),
+// then performs a TLS handshake before handing the connection back.
+type TLSTransport struct {
+	Inner  Transport
+	Config TLSConfig
+}
+
+func (t TLSTransport) Dial(ctx context.Context, target string) (net.Conn, error) {
+	inner := t.Inner
+	if inner == nil {
+		inner = TCPTransport{}
+	}
+	conn, err := inner.Dial(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg, err := t.Config.toTLSConfig(target)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, tlsCfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("transport: TLS handshake with %s: %w", target, err)
+	}
+	return tlsConn, nil
+}
+
+// toTLSConfig builds a *tls.Config for dialing target, defaulting
+// ServerName to target's host and loading CAFile into RootCAs if set.
+func (c TLSConfig) toTLSConfig(target string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if cfg.ServerName == "" {
+		if host, _, err := net.SplitHostPort(target); err == nil {
+			cfg.ServerName = host
+		}
+	}
+	if c.CAFile != "" {
+		pool, err := loadCAPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+func loadCAPool(path string) (*x509.CertPool, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("transport: reading CA bundle %s: %w", path, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("transport: no certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,24 @@
+// Package transport lets the proxy's TCP-side dial go through something
+// other than a bare net.Dial: TLS, mutual TLS, or a WebSocket tunnel that
+// lets vsock traffic ride over an HTTPS-only host boundary.
+package transport
+
+import (
+	"context"
+	"net"
+)
+
+// Transport dials target ("host:port"), optionally wrapping the raw
+// connection before handing it back to the caller.
+type Transport interface {
+	Dial(ctx context.Context, target string) (net.Conn, error)
+}
+
+// TCPTransport dials target over plain TCP - the proxy's original,
+// unwrapped dial behavior.
+type TCPTransport struct{}
+
+func (TCPTransport) Dial(ctx context.Context, target string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", target)
+}
@@ -1,67 +1,159 @@
+// arca-proxy forwards connections between vsock and TCP. In its default
+// "forward" direction it listens on vsock and dials TCP, either to a
+// single fixed target or, in SOCKS5 mode, to whatever target the guest
+// asks for per-connection. Its "reverse" direction instead listens on TCP
+// and dials into the guest over vsock, exposing a guest-side service to
+// the host. It can run multiple forwards of either direction at once,
+// configured via a JSON config file, or a single one via the top-level
+// flags for the common case. Every forward-direction connection is
+// checked against a Policy (see internal/policy) before it's dialed,
+// allowing operators to enforce per-CID ACLs and rate limits, or defer
+// the decision to an external daemon. The TCP-side dial itself can go
+// through a configurable Transport (see internal/transport) - plain TCP,
+// TLS, mutual TLS, or a WebSocket tunnel - instead of always being
+// plaintext.
 package main
 
 import (
-	"io"
+	"flag"
 	"log"
-	"net"
+	"os"
 	"sync"
+	"time"
 
-	"github.com/mdlayher/vsock"
+	"arca-vsock-proxy/internal/policy"
+	"arca-vsock-proxy/internal/relay"
 )
 
+// settings holds the process-wide flags that apply to every forward
+// regardless of direction, as opposed to the per-forward ForwardConfig
+// fields.
+type settings struct {
+	idleTimeout         time.Duration
+	adminAddr           string
+	policyFile          string
+	policySocket        string
+	policySocketTimeout time.Duration
+}
+
 func main() {
-	vsockPort := uint32(8088)
-	tcpAddr := "127.0.0.1:8088"
+	args := os.Args[1:]
+	direction := DirectionForward
+	if len(args) > 0 {
+		switch args[0] {
+		case "forward":
+			direction, args = DirectionForward, args[1:]
+		case "reverse":
+			direction, args = DirectionReverse, args[1:]
+		}
+	}
 
-	// Listen on vsock using mdlayher/vsock library
-	// This provides a proper net.Listener implementation for vsock
-	listener, err := vsock.Listen(vsockPort, nil)
+	cfg, st, err := loadConfigFromFlags(direction, args)
 	if err != nil {
-		log.Fatalf("Failed to listen on vsock port %d: %v", vsockPort, err)
+		log.Fatalf("loading config: %v", err)
+	}
+	if len(cfg.Forwards) == 0 {
+		log.Fatal("no forwards configured")
 	}
-	defer listener.Close()
 
-	log.Printf("Starting vsock-to-TCP proxy: vsock:%d -> %s", vsockPort, tcpAddr)
+	sessionCfg = relay.Config{IdleTimeout: st.idleTimeout}
+	reg := relay.NewRegistry()
+	metrics = reg
+	startAdminServer(st.adminAddr, reg)
 
-	for {
-		vsockConn, err := listener.Accept()
-		if err != nil {
-			log.Printf("Failed to accept vsock connection: %v", err)
-			continue
-		}
+	if err := applyPolicy(st); err != nil {
+		log.Fatalf("loading policy: %v", err)
+	}
 
-		log.Printf("Accepted vsock connection from %s", vsockConn.RemoteAddr())
-		go handleConnection(vsockConn, tcpAddr)
+	var wg sync.WaitGroup
+	for _, fc := range cfg.Forwards {
+		fc := fc
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runForward(fc); err != nil {
+				log.Fatalf("forward on vsock port %d: %v", fc.VsockPort, err)
+			}
+		}()
 	}
+	wg.Wait()
 }
 
-func handleConnection(vsockConn net.Conn, tcpAddr string) {
-	defer vsockConn.Close()
+// loadConfigFromFlags parses args with the flags for a single forward in
+// the given default direction, then resolves a Config either from
+// -config or from those flags, alongside the process-wide settings that
+// apply to every forward regardless of direction.
+func loadConfigFromFlags(direction Direction, args []string) (cfg *Config, st settings, err error) {
+	fs := flag.NewFlagSet("arca-proxy", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to a JSON config file listing multiple forwards of either direction; overrides every other flag except the process-wide ones below")
+	vsockPort := fs.Uint("vsock-port", 8088, "forward mode: vsock port to listen on. reverse mode: vsock port to dial (ignored if -config is set)")
+	tcpAddr := fs.String("tcp-addr", "127.0.0.1:8088", "forward mode: fixed TCP target to forward every connection to. reverse mode: TCP address to listen on (ignored if -config or -socks5 is set)")
+	socks5 := fs.Bool("socks5", false, "forward mode only: run a SOCKS5 (RFC 1928) server on -vsock-port instead of forwarding to a fixed target")
+	socks5User := fs.String("socks5-username", "", "require SOCKS5 username/password auth with this username (requires -socks5-password); no-auth otherwise")
+	socks5Pass := fs.String("socks5-password", "", "SOCKS5 username/password auth password")
+	targetCID := fs.String("target-cid", "", "reverse mode: vsock context ID to dial into - a number, or one of hypervisor/local/host/any")
+	fs.DurationVar(&st.idleTimeout, "idle-timeout", 5*time.Minute, "close a proxied connection if neither side has sent data for this long; 0 disables idle timeouts")
+	fs.StringVar(&st.adminAddr, "admin-addr", "", "if set, serve Prometheus-format relay metrics (connections, bytes, duration) at /metrics on this loopback address; off by default")
+	fs.StringVar(&st.policyFile, "policy-file", "", "path to a JSON ACL policy file (CID ranges, destination patterns, rate limits) consulted before dialing each target")
+	fs.StringVar(&st.policySocket, "policy-socket", "", "Unix socket of an external policy daemon consulted for allow/deny verdicts; runs after -policy-file if both are set")
+	fs.DurationVar(&st.policySocketTimeout, "policy-socket-timeout", 2*time.Second, "timeout for each -policy-socket verdict round trip")
+	fs.Parse(args)
 
-	tcpConn, err := net.Dial("tcp", tcpAddr)
-	if err != nil {
-		log.Printf("Failed to connect to TCP %s: %v", tcpAddr, err)
-		return
+	if *configPath != "" {
+		cfg, err = LoadConfig(*configPath)
+	} else {
+		cfg, err = resolveConfig(direction, uint32(*vsockPort), *tcpAddr, *socks5, *socks5User, *socks5Pass, *targetCID)
 	}
-	defer tcpConn.Close()
-
-	log.Printf("Proxying connection: %s <-> %s", vsockConn.RemoteAddr(), tcpAddr)
+	return cfg, st, err
+}
 
-	// Bidirectional copy
-	var wg sync.WaitGroup
-	wg.Add(2)
+// applyPolicy builds activePolicy from st's -policy-file/-policy-socket
+// flags. With neither set, activePolicy stays policy.AllowAll{},
+// preserving the proxy's original unconditional-forward behavior.
+func applyPolicy(st settings) error {
+	var chain policy.ChainPolicy
+	if st.policyFile != "" {
+		fp, err := policy.LoadFilePolicy(st.policyFile)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, fp)
+	}
+	if st.policySocket != "" {
+		chain = append(chain, &policy.RemotePolicy{SocketPath: st.policySocket, Timeout: st.policySocketTimeout})
+	}
+	if len(chain) > 0 {
+		activePolicy = chain
+	}
+	return nil
+}
 
-	go func() {
-		defer wg.Done()
-		io.Copy(tcpConn, vsockConn)
-		tcpConn.(*net.TCPConn).CloseWrite()
-	}()
+// resolveConfig builds a single-forward Config from the top-level flags.
+func resolveConfig(direction Direction, vsockPort uint32, tcpAddr string, socks5 bool, socks5User, socks5Pass, targetCID string) (*Config, error) {
+	fc := ForwardConfig{VsockPort: vsockPort, Direction: direction}
 
-	go func() {
-		defer wg.Done()
-		io.Copy(vsockConn, tcpConn)
-	}()
+	switch direction {
+	case DirectionReverse:
+		fc.TCPAddr = tcpAddr
+		cid, err := parseCID(targetCID)
+		if err != nil {
+			return nil, err
+		}
+		fc.TargetCID = cid
+	default:
+		if socks5 {
+			sc := &SOCKS5Config{}
+			if socks5User != "" {
+				sc.Username, sc.Password = socks5User, socks5Pass
+			}
+			fc.SOCKS5 = sc
+		} else {
+			fc.TCPAddr = tcpAddr
+		}
+	}
 
-	wg.Wait()
-	log.Printf("Connection closed: %s", vsockConn.RemoteAddr())
+	if err := fc.Validate(); err != nil {
+		return nil, err
+	}
+	return &Config{Forwards: []ForwardConfig{fc}}, nil
 }
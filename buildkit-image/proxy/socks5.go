@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+
+	"arca-vsock-proxy/internal/policy"
+	"arca-vsock-proxy/internal/transport"
+)
+
+// SOCKS5 (RFC 1928) and username/password auth (RFC 1929) wire constants.
+const (
+	socks5Version = 0x05
+
+	socks5MethodNoAuth       = 0x00
+	socks5MethodUserPass     = 0x02
+	socks5MethodNoAcceptable = 0xFF
+
+	socks5CmdConnect = 0x01
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+
+	socks5ReplySucceeded         = 0x00
+	socks5ReplyGeneralFailure    = 0x01
+	socks5ReplyConnNotAllowed    = 0x02
+	socks5ReplyCommandNotSupport = 0x07
+	socks5ReplyAddrNotSupported  = 0x08
+
+	socks5UserPassVersion = 0x01
+	socks5UserPassSuccess = 0x00
+	socks5UserPassFailure = 0x01
+)
+
+// handleSOCKS5Connection negotiates a SOCKS5 handshake on vsockConn, dials
+// whatever target it requests via tr, and splices the two streams - the
+// dynamic-destination counterpart to handleFixedConnection's single fixed
+// target. Only the CONNECT command is supported; BIND and UDP ASSOCIATE
+// are rejected per RFC 1928.
+func handleSOCKS5Connection(vsockConn net.Conn, cfg *SOCKS5Config, tr transport.Transport) {
+	defer vsockConn.Close()
+
+	target, err := socks5Handshake(vsockConn, cfg)
+	if err != nil {
+		log.Printf("SOCKS5 handshake with %s failed: %v", vsockConn.RemoteAddr(), err)
+		return
+	}
+
+	peer := vsockPeer(vsockConn)
+	policyTarget, err := policy.ParseTarget(target)
+	if err != nil {
+		log.Printf("Policy: %v", err)
+		socks5WriteReply(vsockConn, socks5ReplyGeneralFailure)
+		return
+	}
+	verdict, err := activePolicy.Evaluate(peer, policyTarget)
+	if err != nil {
+		log.Printf("Policy: evaluating %s -> %s: %v", peer, policyTarget, err)
+		socks5WriteReply(vsockConn, socks5ReplyGeneralFailure)
+		return
+	}
+	if !verdict.Allow {
+		log.Printf("Policy: denied %s -> %s (%s)", peer, policyTarget, verdict.Reason)
+		socks5WriteReply(vsockConn, socks5ReplyConnNotAllowed)
+		return
+	}
+	if verdict.RewriteTarget != nil {
+		policyTarget = *verdict.RewriteTarget
+	}
+	target = policyTarget.String()
+
+	tcpConn, err := tr.Dial(context.Background(), target)
+	if err != nil {
+		log.Printf("SOCKS5: failed to connect to target %s: %v", target, err)
+		socks5WriteReply(vsockConn, socks5ReplyGeneralFailure)
+		return
+	}
+	defer tcpConn.Close()
+
+	if err := socks5WriteReply(vsockConn, socks5ReplySucceeded); err != nil {
+		log.Printf("SOCKS5: writing success reply to %s: %v", vsockConn.RemoteAddr(), err)
+		return
+	}
+
+	log.Printf("SOCKS5 proxying connection: %s <-> %s", vsockConn.RemoteAddr(), target)
+	splice(vsockConn, tcpConn)
+	log.Printf("SOCKS5 connection closed: %s", vsockConn.RemoteAddr())
+}
+
+// socks5Handshake reads the method-selection and request messages off
+// conn, performing username/password auth if cfg requires it, and returns
+// the "host:port" target the CONNECT request asked for. It deliberately
+// doesn't write the final success/failure reply itself - the caller does
+// that once it knows whether dialing the target actually worked.
+func socks5Handshake(conn net.Conn, cfg *SOCKS5Config) (string, error) {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return "", fmt.Errorf("reading method selection header: %w", err)
+	}
+	if hdr[0] != socks5Version {
+		return "", fmt.Errorf("unsupported SOCKS version %d", hdr[0])
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", fmt.Errorf("reading auth methods: %w", err)
+	}
+
+	wantUserPass := cfg.Username != ""
+	selected := byte(socks5MethodNoAcceptable)
+	for _, m := range methods {
+		if wantUserPass && m == socks5MethodUserPass {
+			selected = socks5MethodUserPass
+			break
+		}
+		if !wantUserPass && m == socks5MethodNoAuth {
+			selected = socks5MethodNoAuth
+			break
+		}
+	}
+	if _, err := conn.Write([]byte{socks5Version, selected}); err != nil {
+		return "", fmt.Errorf("writing method selection reply: %w", err)
+	}
+	if selected == socks5MethodNoAcceptable {
+		return "", fmt.Errorf("no acceptable auth method offered")
+	}
+
+	if selected == socks5MethodUserPass {
+		if err := socks5Authenticate(conn, cfg); err != nil {
+			return "", err
+		}
+	}
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return "", fmt.Errorf("reading request header: %w", err)
+	}
+	if req[0] != socks5Version {
+		return "", fmt.Errorf("unsupported SOCKS version %d in request", req[0])
+	}
+	if req[1] != socks5CmdConnect {
+		socks5WriteReply(conn, socks5ReplyCommandNotSupport)
+		return "", fmt.Errorf("unsupported command %d (only CONNECT is supported)", req[1])
+	}
+
+	host, err := socks5ReadAddress(conn, req[3])
+	if err != nil {
+		socks5WriteReply(conn, socks5ReplyAddrNotSupported)
+		return "", err
+	}
+
+	portBuf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, portBuf); err != nil {
+		return "", fmt.Errorf("reading destination port: %w", err)
+	}
+	port := binary.BigEndian.Uint16(portBuf)
+
+	return net.JoinHostPort(host, fmt.Sprintf("%d", port)), nil
+}
+
+// socks5Authenticate performs RFC 1929 username/password auth and fails
+// the connection if the credentials don't match cfg.
+func socks5Authenticate(conn net.Conn, cfg *SOCKS5Config) error {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return fmt.Errorf("reading auth header: %w", err)
+	}
+	if hdr[0] != socks5UserPassVersion {
+		return fmt.Errorf("unsupported username/password auth version %d", hdr[0])
+	}
+	user := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, user); err != nil {
+		return fmt.Errorf("reading username: %w", err)
+	}
+
+	passLen := make([]byte, 1)
+	if _, err := io.ReadFull(conn, passLen); err != nil {
+		return fmt.Errorf("reading password length: %w", err)
+	}
+	pass := make([]byte, passLen[0])
+	if _, err := io.ReadFull(conn, pass); err != nil {
+		return fmt.Errorf("reading password: %w", err)
+	}
+
+	ok := string(user) == cfg.Username && string(pass) == cfg.Password
+	status := byte(socks5UserPassSuccess)
+	if !ok {
+		status = socks5UserPassFailure
+	}
+	if _, err := conn.Write([]byte{socks5UserPassVersion, status}); err != nil {
+		return fmt.Errorf("writing auth reply: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("invalid username/password")
+	}
+	return nil
+}
+
+// socks5ReadAddress reads a request's DST.ADDR field for addrType (one of
+// the socks5Addr* constants) off conn and returns it as a string suitable
+// for net.JoinHostPort/net.Dial.
+func socks5ReadAddress(conn net.Conn, addrType byte) (string, error) {
+	switch addrType {
+	case socks5AddrIPv4:
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return "", fmt.Errorf("reading IPv4 address: %w", err)
+		}
+		return net.IP(buf).String(), nil
+	case socks5AddrIPv6:
+		buf := make([]byte, 16)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return "", fmt.Errorf("reading IPv6 address: %w", err)
+		}
+		return net.IP(buf).String(), nil
+	case socks5AddrDomain:
+		lenBuf := make([]byte, 1)
+		if _, err := io.ReadFull(conn, lenBuf); err != nil {
+			return "", fmt.Errorf("reading domain name length: %w", err)
+		}
+		buf := make([]byte, lenBuf[0])
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return "", fmt.Errorf("reading domain name: %w", err)
+		}
+		return string(buf), nil
+	default:
+		return "", fmt.Errorf("unsupported address type %d", addrType)
+	}
+}
+
+// socks5WriteReply writes a SOCKS5 reply with the given status and a
+// zeroed BND.ADDR/BND.PORT, since this proxy doesn't bind a distinct local
+// address worth reporting back.
+func socks5WriteReply(conn net.Conn, status byte) error {
+	reply := []byte{
+		socks5Version, status, 0x00, socks5AddrIPv4,
+		0, 0, 0, 0, // BND.ADDR
+		0, 0, // BND.PORT
+	}
+	_, err := conn.Write(reply)
+	return err
+}
@@ -0,0 +1,282 @@
+// Package capture provides the same non-blocking, drop-oldest packet
+// capture fan-out that arca-tap-forwarder-go's internal/capture gives the
+// guest daemon, sized for the helper VM's TAPRelayManager: a BPF-filtered
+// Hub that handleConnection's relay loops can feed without ever blocking
+// on a slow reader, plus a pcap file writer for local debugging. There's
+// no libpcap dependency; filtering runs classic BPF programs through
+// golang.org/x/net/bpf's VM.
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/bpf"
+)
+
+// Direction records which way a captured frame crossed a TAP relay
+// connection.
+type Direction byte
+
+const (
+	// DirectionFromContainer is a frame read off the vsock connection and
+	// written to the bridge-side TAP (container -> bridge).
+	DirectionFromContainer Direction = iota
+	// DirectionToContainer is a frame read off the bridge-side TAP and
+	// written to the vsock connection (bridge -> container).
+	DirectionToContainer
+)
+
+func (d Direction) String() string {
+	if d == DirectionToContainer {
+		return "to-container"
+	}
+	return "from-container"
+}
+
+// Record is one captured frame plus the metadata a Subscriber needs to
+// write it out as a pcap record.
+type Record struct {
+	Captured  time.Time
+	Direction Direction
+	Data      []byte
+}
+
+const (
+	// subscriberQueueDepth bounds a Subscriber's ring buffer; once full,
+	// the oldest queued frame is dropped to make room for the newest one
+	// rather than blocking the relay goroutine that's fanning out.
+	subscriberQueueDepth = 256
+
+	// DefaultSnaplen matches tcpdump's default and comfortably covers any
+	// Ethernet frame a TAP relay connection carries.
+	DefaultSnaplen = 262144
+)
+
+// Subscriber receives a filtered, capped copy of one relay connection's
+// traffic. The zero value is not usable; construct one with NewSubscriber.
+type Subscriber struct {
+	filter  *bpf.VM
+	snaplen int
+
+	out   chan Record
+	drops atomic.Uint64
+}
+
+// NewSubscriber builds a Subscriber. filter may be nil to capture
+// everything; otherwise it's a classic BPF program (e.g. as produced by
+// `tcpdump -dd`) compiled into a bpf.VM.
+func NewSubscriber(filter []bpf.Instruction, snaplen int) (*Subscriber, error) {
+	if snaplen <= 0 {
+		snaplen = DefaultSnaplen
+	}
+	s := &Subscriber{
+		snaplen: snaplen,
+		out:     make(chan Record, subscriberQueueDepth),
+	}
+	if len(filter) > 0 {
+		vm, err := bpf.NewVM(filter)
+		if err != nil {
+			return nil, fmt.Errorf("capture: compiling BPF filter: %w", err)
+		}
+		s.filter = vm
+	}
+	return s, nil
+}
+
+// Packets returns the channel new Records arrive on.
+func (s *Subscriber) Packets() <-chan Record { return s.out }
+
+// Drops returns how many frames this subscriber has had to discard
+// because its queue was full when offer was called.
+func (s *Subscriber) Drops() uint64 { return s.drops.Load() }
+
+func (s *Subscriber) matches(data []byte) bool {
+	if s.filter == nil {
+		return true
+	}
+	n, err := s.filter.Run(data)
+	if err != nil {
+		return false
+	}
+	return n > 0
+}
+
+// offer hands data to the subscriber if it passes the filter, copying only
+// up to snaplen bytes, and drops the oldest queued record to make room
+// rather than blocking the caller if the queue is full. Called from a
+// relay goroutine, so it must never block.
+func (s *Subscriber) offer(rec Record) (dropped bool) {
+	if !s.matches(rec.Data) {
+		return false
+	}
+	if len(rec.Data) > s.snaplen {
+		capped := make([]byte, s.snaplen)
+		copy(capped, rec.Data)
+		rec.Data = capped
+	} else {
+		cp := make([]byte, len(rec.Data))
+		copy(cp, rec.Data)
+		rec.Data = cp
+	}
+
+	for {
+		select {
+		case s.out <- rec:
+			return dropped
+		default:
+		}
+		select {
+		case <-s.out:
+			s.drops.Add(1)
+			dropped = true
+		default:
+		}
+	}
+}
+
+// Close stops delivery to this subscriber. Safe to call once.
+func (s *Subscriber) Close() {
+	close(s.out)
+}
+
+// Hub fans frames out to every currently-registered Subscriber for one
+// relay connection. It's safe for concurrent use; Offer is the only method
+// called from relay goroutines and never blocks.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscriber]struct{}
+}
+
+// NewHub returns an empty Hub.
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscriber]struct{})}
+}
+
+// Subscribe registers sub to receive future frames until Unsubscribe is
+// called.
+func (h *Hub) Subscribe(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[sub] = struct{}{}
+}
+
+// Unsubscribe deregisters sub and closes its output channel.
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	_, ok := h.subs[sub]
+	delete(h.subs, sub)
+	h.mu.Unlock()
+	if ok {
+		sub.Close()
+	}
+}
+
+// HasSubscribers reports whether the hub currently has any subscribers, so
+// relay code can skip the fan-out work entirely on the common no-op path.
+func (h *Hub) HasSubscribers() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs) > 0
+}
+
+// Offer fans data out to every current subscriber. It never blocks or
+// allocates on the no-subscriber path. Returns how many subscribers had to
+// drop an older queued record to make room for this one.
+func (h *Hub) Offer(direction Direction, data []byte) (drops int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.subs) == 0 {
+		return 0
+	}
+	rec := Record{Captured: time.Now(), Direction: direction, Data: data}
+	for sub := range h.subs {
+		if sub.offer(rec) {
+			drops++
+		}
+	}
+	return drops
+}
+
+// pcap file format constants (see https://wiki.wireshark.org/Development/LibpcapFileFormat).
+const (
+	pcapMagic      = 0xa1b2c3d4
+	pcapVersionMaj = 2
+	pcapVersionMin = 4
+	// linkTypeEthernet is DLT_EN10MB - a TAP relay connection carries raw
+	// Ethernet frames in both directions.
+	linkTypeEthernet = 1
+)
+
+// WriteGlobalHeader writes a pcap file's 24-byte global header to w.
+func WriteGlobalHeader(w io.Writer, snaplen uint32) error {
+	var hdr [24]byte
+	binary.LittleEndian.PutUint32(hdr[0:4], pcapMagic)
+	binary.LittleEndian.PutUint16(hdr[4:6], pcapVersionMaj)
+	binary.LittleEndian.PutUint16(hdr[6:8], pcapVersionMin)
+	binary.LittleEndian.PutUint32(hdr[16:20], snaplen)
+	binary.LittleEndian.PutUint32(hdr[20:24], linkTypeEthernet)
+	_, err := w.Write(hdr[:])
+	return err
+}
+
+// WriteRecord writes one captured frame as a pcap record (16-byte record
+// header plus the, possibly snaplen-truncated, frame bytes) to w.
+func WriteRecord(w io.Writer, rec Record, snaplen uint32) error {
+	data := rec.Data
+	inclLen := uint32(len(data))
+	if inclLen > snaplen {
+		inclLen = snaplen
+		data = data[:inclLen]
+	}
+
+	var hdr [16]byte
+	binary.LittleEndian.PutUint32(hdr[0:4], uint32(rec.Captured.Unix()))
+	binary.LittleEndian.PutUint32(hdr[4:8], uint32(rec.Captured.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(hdr[8:12], inclLen)
+	binary.LittleEndian.PutUint32(hdr[12:16], uint32(len(rec.Data)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// FileWriter writes every offered Record to an underlying pcap file,
+// implementing TAPRelayManager.StartCapture's direct-to-disk path.
+type FileWriter struct {
+	f       *os.File
+	snaplen uint32
+}
+
+// CreateFile creates path, writes the pcap global header, and returns a
+// FileWriter ready to accept records via Write.
+func CreateFile(path string, snaplen uint32) (*FileWriter, error) {
+	if snaplen == 0 {
+		snaplen = DefaultSnaplen
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("capture: creating %s: %w", path, err)
+	}
+	if err := WriteGlobalHeader(f, snaplen); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("capture: writing pcap header to %s: %w", path, err)
+	}
+	return &FileWriter{f: f, snaplen: snaplen}, nil
+}
+
+// Write appends rec to the capture file.
+func (fw *FileWriter) Write(rec Record) error {
+	return WriteRecord(fw.f, rec, fw.snaplen)
+}
+
+// Close closes the underlying file.
+func (fw *FileWriter) Close() error {
+	return fw.f.Close()
+}
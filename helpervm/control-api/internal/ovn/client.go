@@ -0,0 +1,460 @@
+// Package ovn speaks OVSDB directly to the OVN Northbound database via
+// github.com/ovn-org/libovsdb, replacing the old approach of shelling out to
+// ovn-nbctl once per property. Every multi-step operation (creating a
+// switch+router+DHCP options, attaching a port) is built as a single
+// transaction so it either lands atomically or not at all, instead of
+// leaving half-configured OVN state behind when one ovn-nbctl call in a
+// sequence failed.
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ovn-org/libovsdb/client"
+	"github.com/ovn-org/libovsdb/model"
+	"github.com/ovn-org/libovsdb/ovsdb"
+
+	"arca-network-api/internal/ovn/schema"
+)
+
+const (
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+
+	// dynamicAddressTimeout bounds how long CreateLogicalSwitchPort waits for
+	// northd to populate dynamic_addresses after a dynamic port is created.
+	dynamicAddressTimeout = 5 * time.Second
+)
+
+// NBClient wraps a libovsdb connection to the OVN Northbound database.
+type NBClient struct {
+	client.Client
+	endpoint string
+}
+
+// Connect dials endpoint (e.g. "unix:/var/run/ovn/ovnnb_db.sock" or
+// "tcp:127.0.0.1:6641"), retrying with exponential backoff - the same
+// pattern incus's setupOVN uses - until it succeeds or ctx is cancelled.
+// The monitor started here is what lets AwaitDynamicAddress below watch
+// for northd's allocation via cache updates instead of polling. This one
+// long-lived connection, reused by every request, is also what retired the
+// old per-call `ovn-nbctl` fork-and-parse path; NBClient.Connected() reports
+// its live/dead state the way an ovn-nbctl --detach control socket once did.
+func Connect(ctx context.Context, endpoint string) (*NBClient, error) {
+	dbModel, err := schema.FullDatabaseModel()
+	if err != nil {
+		return nil, fmt.Errorf("ovn: building northbound DB model: %w", err)
+	}
+
+	c, err := client.NewOVSDBClient(dbModel, client.WithEndpoint(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("ovn: creating client for %s: %w", endpoint, err)
+	}
+
+	backoff := initialBackoff
+	for {
+		if err := c.Connect(ctx); err == nil {
+			break
+		} else {
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("ovn: connecting to %s: %w", endpoint, ctx.Err())
+			case <-time.After(backoff):
+			}
+			log.Printf("ovn: connect to %s failed (%v), retrying in %s", endpoint, err, backoff)
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+
+	if _, err := c.MonitorAll(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("ovn: starting monitor against %s: %w", endpoint, err)
+	}
+
+	return &NBClient{Client: c, endpoint: endpoint}, nil
+}
+
+// CreateLogicalSwitch creates a logical switch tagged with vlanTag, its DHCP
+// options, a peer logical router, and the switch<->router port pair
+// connecting them - everything CreateBridge used to do as a dozen separate
+// ovn-nbctl calls - as one atomic transaction.
+func (c *NBClient) CreateLogicalSwitch(ctx context.Context, name string, vlanTag uint32, subnet, gateway string) error {
+	routerName := "router-" + name
+	routerPortName := "lrp-" + name
+	switchPortName := "lsp-" + name + "-router"
+	serverMAC := fmt.Sprintf("00:00:00:00:%02x:%02x", (vlanTag>>8)&0xff, vlanTag&0xff)
+
+	prefixLen := "32"
+	if parts := strings.SplitN(subnet, "/", 2); len(parts) == 2 {
+		prefixLen = parts[1]
+	}
+
+	dhcp := &schema.DHCPOptions{
+		CIDR: subnet,
+		Options: map[string]string{
+			"lease_time": "3600",
+			"router":     gateway,
+			"server_id":  gateway,
+			"server_mac": serverMAC,
+			"dns_server": gateway,
+		},
+	}
+	ls := &schema.LogicalSwitch{
+		Name:        name,
+		ExternalIDs: map[string]string{"vlan_tag": fmt.Sprintf("%d", vlanTag)},
+		OtherConfig: map[string]string{
+			"subnet":      subnet,
+			"gateway":     gateway,
+			"exclude_ips": gateway,
+		},
+	}
+	lr := &schema.LogicalRouter{Name: routerName}
+	lrp := &schema.LogicalRouterPort{
+		Name:     routerPortName,
+		MAC:      serverMAC,
+		Networks: []string{gateway + "/" + prefixLen},
+	}
+	lsp := &schema.LogicalSwitchPort{
+		Name:      switchPortName,
+		Type:      "router",
+		Addresses: []string{"router"},
+		Options:   map[string]string{"router-port": routerPortName},
+	}
+
+	var ops []ovsdb.Operation
+	for _, m := range []model.Model{dhcp, ls, lr, lrp, lsp} {
+		o, err := c.Create(m)
+		if err != nil {
+			return fmt.Errorf("ovn: building create op for %T: %w", m, err)
+		}
+		ops = append(ops, o...)
+	}
+
+	// dhcp.UUID/lsp.UUID/lrp.UUID now hold the named-uuid placeholders
+	// libovsdb's ORM generated for the Create ops above; referencing them in
+	// these Mutate ops links everything together within the same
+	// transaction, same as how ovn-nbctl composes sub-commands with --.
+	linkOps, err := c.Where(ls).Mutate(ls,
+		model.Mutation{Field: &ls.Ports, Mutator: ovsdb.MutateOperationInsert, Value: []string{lsp.UUID}},
+		model.Mutation{Field: &ls.OtherConfig, Mutator: ovsdb.MutateOperationInsert, Value: map[string]string{"dhcp_options": dhcp.UUID}},
+	)
+	if err != nil {
+		return fmt.Errorf("ovn: building switch link ops: %w", err)
+	}
+	ops = append(ops, linkOps...)
+
+	routerLinkOps, err := c.Where(lr).Mutate(lr,
+		model.Mutation{Field: &lr.Ports, Mutator: ovsdb.MutateOperationInsert, Value: []string{lrp.UUID}},
+	)
+	if err != nil {
+		return fmt.Errorf("ovn: building router link ops: %w", err)
+	}
+	ops = append(ops, routerLinkOps...)
+
+	return c.transact(ctx, ops)
+}
+
+// DeleteLogicalSwitch deletes a logical switch and its peer router. Deleting
+// the switch cascades to its ports, DHCP options and DNS records.
+func (c *NBClient) DeleteLogicalSwitch(ctx context.Context, name string) error {
+	var ops []ovsdb.Operation
+
+	if lr, err := c.lookupRouter(ctx, "router-"+name); err == nil {
+		o, err := c.Where(lr).Delete()
+		if err != nil {
+			return fmt.Errorf("ovn: building router delete op: %w", err)
+		}
+		ops = append(ops, o...)
+	}
+
+	ls, err := c.lookupSwitch(ctx, name)
+	if err != nil {
+		return fmt.Errorf("ovn: logical switch %s not found: %w", name, err)
+	}
+	o, err := c.Where(ls).Delete()
+	if err != nil {
+		return fmt.Errorf("ovn: building switch delete op: %w", err)
+	}
+	ops = append(ops, o...)
+
+	return c.transact(ctx, ops)
+}
+
+// VLANTag returns the VLAN tag stashed in external_ids:vlan_tag when name's
+// logical switch was created.
+func (c *NBClient) VLANTag(ctx context.Context, name string) (uint32, error) {
+	ls, err := c.lookupSwitch(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	var tag uint32
+	if _, err := fmt.Sscanf(ls.ExternalIDs["vlan_tag"], "%d", &tag); err != nil {
+		return 0, fmt.Errorf("ovn: invalid vlan_tag on switch %s: %w", name, err)
+	}
+	return tag, nil
+}
+
+// SwitchVLANTags returns every logical switch's name and the VLAN tag
+// stashed in its external_ids:vlan_tag, for rebuilding a VLANPool's bindings
+// from OVN's actual state on startup instead of trusting a possibly-stale
+// on-disk file.
+func (c *NBClient) SwitchVLANTags(ctx context.Context) (map[string]uint32, error) {
+	var switches []schema.LogicalSwitch
+	if err := c.List(ctx, &switches); err != nil {
+		return nil, fmt.Errorf("ovn: listing logical switches: %w", err)
+	}
+
+	tags := make(map[string]uint32, len(switches))
+	for _, ls := range switches {
+		raw, ok := ls.ExternalIDs["vlan_tag"]
+		if !ok {
+			continue // provider networks and other non-VLAN switches don't carry one
+		}
+		var tag uint32
+		if _, err := fmt.Sscanf(raw, "%d", &tag); err != nil {
+			log.Printf("ovn: switch %s has invalid vlan_tag %q, skipping: %v", ls.Name, raw, err)
+			continue
+		}
+		tags[ls.Name] = tag
+	}
+	return tags, nil
+}
+
+// ContainerPorts returns, for every logical switch that has one, the
+// containerIDs attached to it - derived from each Logical_Switch_Port's
+// external_ids:container_id, which CreateLogicalSwitchPort stamps on every
+// port it creates. Used by the inventory package's startup reconciliation to
+// detect drift between the persisted inventory and OVN's actual state.
+func (c *NBClient) ContainerPorts(ctx context.Context) (map[string][]string, error) {
+	var switches []schema.LogicalSwitch
+	if err := c.List(ctx, &switches); err != nil {
+		return nil, fmt.Errorf("ovn: listing logical switches: %w", err)
+	}
+	var ports []schema.LogicalSwitchPort
+	if err := c.List(ctx, &ports); err != nil {
+		return nil, fmt.Errorf("ovn: listing logical switch ports: %w", err)
+	}
+
+	containerIDByPortUUID := make(map[string]string, len(ports))
+	for _, p := range ports {
+		if id, ok := p.ExternalIDs["container_id"]; ok && id != "" {
+			containerIDByPortUUID[p.UUID] = id
+		}
+	}
+
+	result := make(map[string][]string, len(switches))
+	for _, ls := range switches {
+		var containerIDs []string
+		for _, portUUID := range ls.Ports {
+			if id, ok := containerIDByPortUUID[portUUID]; ok {
+				containerIDs = append(containerIDs, id)
+			}
+		}
+		if len(containerIDs) > 0 {
+			result[ls.Name] = containerIDs
+		}
+	}
+	return result, nil
+}
+
+// SwitchReady reports whether networkID's logical switch has everything
+// CreateLogicalSwitch provisions for it: a peer router, the router<->switch
+// port pair, and a DHCP_Options row. Used by the startup reconciliation loop
+// to hold off serving AttachContainer for a network until northd has caught
+// up - see kube-ovn's allSubnetReady for the pattern this mirrors.
+func (c *NBClient) SwitchReady(ctx context.Context, networkID string) (bool, error) {
+	ls, err := c.lookupSwitch(ctx, networkID)
+	if err != nil {
+		return false, fmt.Errorf("ovn: logical switch %s not found: %w", networkID, err)
+	}
+	if ls.OtherConfig["dhcp_options"] == "" {
+		return false, nil
+	}
+	if _, err := c.lookupRouter(ctx, "router-"+networkID); err != nil {
+		return false, nil
+	}
+	if _, err := c.lookupRouterPort(ctx, "lrp-"+networkID); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// CreateLogicalSwitchPort creates a port on networkID's logical switch for
+// containerID. If ipAddress is empty the port is configured for DHCP
+// (linked to the switch's DHCP options) and the call blocks until northd
+// allocates an address or dynamicAddressTimeout elapses; otherwise the
+// given static IP is used directly. Returns the allocated/assigned IP.
+// containerID is stamped into external_ids:container_id so ContainerPorts
+// can reconstruct network<->container attachment straight from OVN state.
+func (c *NBClient) CreateLogicalSwitchPort(ctx context.Context, networkID, portName, mac, ipAddress, containerID string) (string, error) {
+	ls, err := c.lookupSwitch(ctx, networkID)
+	if err != nil {
+		return "", fmt.Errorf("ovn: logical switch %s not found: %w", networkID, err)
+	}
+
+	dynamic := ipAddress == ""
+	addresses := []string{mac, ipAddress}
+	if dynamic {
+		addresses = []string{mac, "dynamic"}
+	}
+
+	lsp := &schema.LogicalSwitchPort{
+		Name:         portName,
+		Addresses:    []string{strings.Join(addresses, " ")},
+		PortSecurity: []string{strings.Join(addresses, " ")},
+		ExternalIDs:  map[string]string{"container_id": containerID},
+	}
+	if dynamic {
+		if dhcpUUID, ok := ls.OtherConfig["dhcp_options"]; ok && dhcpUUID != "" {
+			lsp.DHCPv4Options = &dhcpUUID
+		}
+	}
+
+	ops, err := c.Create(lsp)
+	if err != nil {
+		return "", fmt.Errorf("ovn: building port create op: %w", err)
+	}
+	linkOps, err := c.Where(ls).Mutate(ls,
+		model.Mutation{Field: &ls.Ports, Mutator: ovsdb.MutateOperationInsert, Value: []string{lsp.UUID}},
+	)
+	if err != nil {
+		return "", fmt.Errorf("ovn: building port link op: %w", err)
+	}
+	ops = append(ops, linkOps...)
+
+	if err := c.transact(ctx, ops); err != nil {
+		return "", err
+	}
+
+	if !dynamic {
+		return ipAddress, nil
+	}
+
+	allocated, err := c.awaitDynamicAddress(ctx, portName)
+	if err != nil {
+		return "", err
+	}
+
+	// Port security can't carry the literal "dynamic" keyword - once the
+	// real address is known, update it to match so traffic isn't dropped.
+	port, err := c.lookupPort(ctx, portName)
+	if err != nil {
+		return allocated, nil
+	}
+	updateOps, err := c.Where(port).Update(port, &port.PortSecurity)
+	if err == nil {
+		port.PortSecurity = []string{fmt.Sprintf("%s %s", mac, allocated)}
+		updateOps, _ = c.Where(port).Update(port, &port.PortSecurity)
+		_ = c.transact(ctx, updateOps)
+	}
+
+	return allocated, nil
+}
+
+// awaitDynamicAddress watches the client's local cache (kept current by the
+// monitor started in Connect) for dynamic_addresses to appear on portName,
+// instead of the old fixed 5x100ms ovn-nbctl poll loop.
+func (c *NBClient) awaitDynamicAddress(ctx context.Context, portName string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, dynamicAddressTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if port, err := c.lookupPort(ctx, portName); err == nil && port.DynamicAddresses != nil {
+			parts := strings.Fields(*port.DynamicAddresses)
+			if len(parts) >= 2 {
+				return parts[1], nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("ovn: timed out waiting for dynamic_addresses on port %s: %w", portName, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeleteLogicalSwitchPort removes a port by name; this also releases its
+// DHCP lease and any DNS records tied to it.
+func (c *NBClient) DeleteLogicalSwitchPort(ctx context.Context, portName string) error {
+	lsp, err := c.lookupPort(ctx, portName)
+	if err != nil {
+		// Already gone - matches the old code's --if-exists tolerance.
+		return nil
+	}
+	ops, err := c.Where(lsp).Delete()
+	if err != nil {
+		return fmt.Errorf("ovn: building port delete op: %w", err)
+	}
+	return c.transact(ctx, ops)
+}
+
+func (c *NBClient) lookupSwitch(ctx context.Context, name string) (*schema.LogicalSwitch, error) {
+	ls := &schema.LogicalSwitch{Name: name}
+	if err := c.Get(ctx, ls); err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+func (c *NBClient) lookupRouter(ctx context.Context, name string) (*schema.LogicalRouter, error) {
+	lr := &schema.LogicalRouter{Name: name}
+	if err := c.Get(ctx, lr); err != nil {
+		return nil, err
+	}
+	return lr, nil
+}
+
+func (c *NBClient) lookupPort(ctx context.Context, name string) (*schema.LogicalSwitchPort, error) {
+	lsp := &schema.LogicalSwitchPort{Name: name}
+	if err := c.Get(ctx, lsp); err != nil {
+		return nil, err
+	}
+	return lsp, nil
+}
+
+func (c *NBClient) lookupRouterPort(ctx context.Context, name string) (*schema.LogicalRouterPort, error) {
+	lrp := &schema.LogicalRouterPort{Name: name}
+	if err := c.Get(ctx, lrp); err != nil {
+		return nil, err
+	}
+	return lrp, nil
+}
+
+func (c *NBClient) lookupPortGroup(ctx context.Context, name string) (*schema.PortGroup, error) {
+	pg := &schema.PortGroup{Name: name}
+	if err := c.Get(ctx, pg); err != nil {
+		return nil, err
+	}
+	return pg, nil
+}
+
+// transact runs ops as a single transaction and turns any per-operation
+// failure into an error, so callers never have to reason about partial
+// application the way the old "continue anyway" shell-exec code did.
+func (c *NBClient) transact(ctx context.Context, ops []ovsdb.Operation) error {
+	_, err := c.transactWithResults(ctx, ops)
+	return err
+}
+
+// transactWithResults is transact plus the raw per-operation results, for
+// callers that need the real UUID an insert op was assigned (e.g. to
+// remember it past the lifetime of the named-uuid placeholder the ORM
+// handed out for use within this transaction).
+func (c *NBClient) transactWithResults(ctx context.Context, ops []ovsdb.Operation) ([]ovsdb.OperationResult, error) {
+	results, err := c.Transact(ctx, ops...)
+	if err != nil {
+		return nil, fmt.Errorf("ovn: transaction failed: %w", err)
+	}
+	if _, err := ovsdb.CheckOperationResults(results, ops); err != nil {
+		return nil, fmt.Errorf("ovn: transaction rejected: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,100 @@
+package ovn
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ovn-org/libovsdb/model"
+	"github.com/ovn-org/libovsdb/ovsdb"
+
+	"arca-network-api/internal/ovn/schema"
+)
+
+// SetDNSRecord binds hostname to ipAddress in networkID's logical switch DNS
+// records, creating the switch's DNS row on first use. Replaces the old
+// addDNSRecord, which shelled out to `ovn-nbctl get|create|set ... DNS` and
+// parsed the bracketed UUID-list text it printed back - this mutates the
+// records map and (when creating) links the new row into the switch's
+// dns_records set in one transaction instead.
+//
+// Each branch below is its own atomic transaction, but deciding which branch
+// to take is a read followed by a separate write: two concurrent first-time
+// calls for the same networkID can both see no DNS row and both try to
+// create one. Callers that might run concurrently for the same networkID
+// (e.g. NetworkServer) must serialize around that; see
+// NetworkServer.SetDNSRecord's per-network lock.
+func (c *NBClient) SetDNSRecord(ctx context.Context, networkID, hostname, ipAddress string) error {
+	if hostname == "" || ipAddress == "" {
+		return fmt.Errorf("ovn: hostname and IP address are required")
+	}
+
+	ls, err := c.lookupSwitch(ctx, networkID)
+	if err != nil {
+		return fmt.Errorf("ovn: logical switch %s not found: %w", networkID, err)
+	}
+
+	if len(ls.DNSRecords) == 0 {
+		dns := &schema.DNS{Records: map[string]string{hostname: ipAddress}}
+		ops, err := c.Create(dns)
+		if err != nil {
+			return fmt.Errorf("ovn: building DNS create op: %w", err)
+		}
+		linkOps, err := c.Where(ls).Mutate(ls,
+			model.Mutation{Field: &ls.DNSRecords, Mutator: ovsdb.MutateOperationInsert, Value: []string{dns.UUID}},
+		)
+		if err != nil {
+			return fmt.Errorf("ovn: building DNS link op: %w", err)
+		}
+		ops = append(ops, linkOps...)
+		return c.transact(ctx, ops)
+	}
+
+	dns, err := c.lookupDNS(ctx, ls.DNSRecords[0])
+	if err != nil {
+		return fmt.Errorf("ovn: DNS row %s on switch %s not found: %w", ls.DNSRecords[0], networkID, err)
+	}
+	ops, err := c.Where(dns).Mutate(dns,
+		model.Mutation{Field: &dns.Records, Mutator: ovsdb.MutateOperationInsert, Value: map[string]string{hostname: ipAddress}},
+	)
+	if err != nil {
+		return fmt.Errorf("ovn: building DNS record update op: %w", err)
+	}
+	return c.transact(ctx, ops)
+}
+
+// DeleteDNSRecord removes hostname from networkID's logical switch DNS
+// records, tolerating a switch with no DNS row or a hostname that was never
+// set - matching the old removeDNSRecord's "nothing to remove" tolerance.
+// Like SetDNSRecord, callers that might run concurrently for the same
+// networkID must serialize around it themselves.
+func (c *NBClient) DeleteDNSRecord(ctx context.Context, networkID, hostname string) error {
+	if hostname == "" {
+		return fmt.Errorf("ovn: hostname is required")
+	}
+
+	ls, err := c.lookupSwitch(ctx, networkID)
+	if err != nil || len(ls.DNSRecords) == 0 {
+		return nil
+	}
+
+	dns, err := c.lookupDNS(ctx, ls.DNSRecords[0])
+	if err != nil {
+		return nil
+	}
+
+	ops, err := c.Where(dns).Mutate(dns,
+		model.Mutation{Field: &dns.Records, Mutator: ovsdb.MutateOperationDelete, Value: map[string]string{hostname: dns.Records[hostname]}},
+	)
+	if err != nil {
+		return fmt.Errorf("ovn: building DNS record delete op: %w", err)
+	}
+	return c.transact(ctx, ops)
+}
+
+func (c *NBClient) lookupDNS(ctx context.Context, uuid string) (*schema.DNS, error) {
+	dns := &schema.DNS{UUID: uuid}
+	if err := c.Get(ctx, dns); err != nil {
+		return nil, err
+	}
+	return dns, nil
+}
@@ -0,0 +1,275 @@
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ovn-org/libovsdb/model"
+	"github.com/ovn-org/libovsdb/ovsdb"
+
+	"arca-network-api/internal/ovn/schema"
+)
+
+// PolicyRule is one rule of a network policy, already normalized out of the
+// wire-level pb.NetworkRule into the shape ApplyNetworkPolicy compiles into
+// OVN ACLs.
+type PolicyRule struct {
+	Direction string // "ingress" or "egress"
+	Action    string // "allow" or "deny"
+	Protocol  string // "tcp", "udp", or "" to match any protocol
+	PortMin   uint32
+	PortMax   uint32
+	PeerCIDRs []string // CIDR peers; empty means "any source/destination"
+}
+
+// PolicyHandles is what ApplyNetworkPolicy hands back so NetworkServer can
+// persist policyID -> OVN rows and atomically replace them on the next
+// SetNetworkPolicy call for the same policy.
+type PolicyHandles struct {
+	PortGroup   string
+	ACLUUIDs    []string
+	AddressSets []string
+}
+
+const (
+	policyBasePriority = 1000
+	policyDenyOffset   = 500
+)
+
+// PolicyGroupName returns the Port_Group that holds every logical switch
+// port on networkID that network policies apply to. There's one per
+// network - AttachContainer/DetachContainer add/remove a container's port
+// from it regardless of how many policies are active on the network.
+func PolicyGroupName(networkID string) string {
+	return "pg-policy-" + networkID
+}
+
+func policyAddressSetName(policyID string, ruleIndex int) string {
+	return fmt.Sprintf("as-%s-%d", policyID, ruleIndex)
+}
+
+// ApplyNetworkPolicy compiles rules into a Port_Group (created if it doesn't
+// already exist) plus one ACL and, for rules with peer CIDRs, one Address_Set
+// per rule. If prev is non-nil, the ACLs and address sets it references are
+// deleted in the same transaction, so a policy's ruleset is replaced
+// atomically rather than accumulating stale ACLs across calls.
+func (c *NBClient) ApplyNetworkPolicy(ctx context.Context, networkID, policyID string, rules []PolicyRule, prev *PolicyHandles) (*PolicyHandles, error) {
+	pgName := PolicyGroupName(networkID)
+	pg, err := c.ensurePortGroup(ctx, pgName)
+	if err != nil {
+		return nil, fmt.Errorf("ovn: ensuring port group %s: %w", pgName, err)
+	}
+
+	var ops []ovsdb.Operation
+	newAddressSets := make([]*schema.AddressSet, 0, len(rules))
+	newACLs := make([]*schema.ACL, 0, len(rules))
+
+	for i, rule := range rules {
+		priority := policyBasePriority + i
+		action := "allow-related"
+		if rule.Action == "deny" {
+			action = "drop"
+			priority += policyDenyOffset
+		}
+
+		direction := "to-lport"
+		matches := []string{fmt.Sprintf("outport==@%s", pgName)}
+		peerField := "ip4.src"
+		if rule.Direction == "egress" {
+			direction = "from-lport"
+			matches = []string{fmt.Sprintf("inport==@%s", pgName)}
+			peerField = "ip4.dst"
+		}
+
+		if len(rule.PeerCIDRs) > 0 {
+			asName := policyAddressSetName(policyID, i)
+			as := &schema.AddressSet{Name: asName, Addresses: rule.PeerCIDRs}
+			asOps, err := c.Create(as)
+			if err != nil {
+				return nil, fmt.Errorf("ovn: building address set create op: %w", err)
+			}
+			ops = append(ops, asOps...)
+			newAddressSets = append(newAddressSets, as)
+			matches = append(matches, fmt.Sprintf("%s=={$%s}", peerField, asName))
+		}
+
+		if rule.Protocol != "" {
+			matches = append(matches, portMatch(rule.Protocol, rule.PortMin, rule.PortMax))
+		}
+
+		acl := &schema.ACL{
+			Direction:   direction,
+			Match:       strings.Join(matches, " && "),
+			Action:      action,
+			Priority:    priority,
+			ExternalIDs: map[string]string{"policy_id": policyID},
+		}
+		aclOps, err := c.Create(acl)
+		if err != nil {
+			return nil, fmt.Errorf("ovn: building ACL create op: %w", err)
+		}
+		ops = append(ops, aclOps...)
+		newACLs = append(newACLs, acl)
+	}
+
+	pgMutations := []model.Mutation{
+		{Field: &pg.ACLs, Mutator: ovsdb.MutateOperationInsert, Value: aclUUIDs(newACLs)},
+	}
+	if prev != nil && len(prev.ACLUUIDs) > 0 {
+		pgMutations = append(pgMutations, model.Mutation{Field: &pg.ACLs, Mutator: ovsdb.MutateOperationDelete, Value: prev.ACLUUIDs})
+	}
+	mutateOps, err := c.Where(pg).Mutate(pg, pgMutations...)
+	if err != nil {
+		return nil, fmt.Errorf("ovn: building port group link ops: %w", err)
+	}
+	ops = append(ops, mutateOps...)
+
+	if prev != nil {
+		for _, uuid := range prev.ACLUUIDs {
+			delOps, err := c.Where(&schema.ACL{UUID: uuid}).Delete()
+			if err != nil {
+				return nil, fmt.Errorf("ovn: building stale ACL delete op: %w", err)
+			}
+			ops = append(ops, delOps...)
+		}
+		for _, name := range prev.AddressSets {
+			delOps, err := c.Where(&schema.AddressSet{Name: name}).Delete()
+			if err != nil {
+				return nil, fmt.Errorf("ovn: building stale address set delete op: %w", err)
+			}
+			ops = append(ops, delOps...)
+		}
+	}
+
+	results, err := c.transactWithResults(ctx, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	handles := &PolicyHandles{PortGroup: pgName}
+	for _, acl := range newACLs {
+		handles.ACLUUIDs = append(handles.ACLUUIDs, realUUID(results, ops, acl.UUID))
+	}
+	for _, as := range newAddressSets {
+		handles.AddressSets = append(handles.AddressSets, as.Name)
+	}
+	return handles, nil
+}
+
+// DeleteNetworkPolicy tears down the ACLs and address sets a prior
+// ApplyNetworkPolicy call created, leaving the network's Port_Group (and its
+// membership) untouched since other policies on the same network may still
+// be using it.
+func (c *NBClient) DeleteNetworkPolicy(ctx context.Context, handles *PolicyHandles) error {
+	if handles == nil {
+		return nil
+	}
+	pg, err := c.lookupPortGroup(ctx, handles.PortGroup)
+	if err != nil {
+		return nil
+	}
+
+	var ops []ovsdb.Operation
+	if len(handles.ACLUUIDs) > 0 {
+		mutateOps, err := c.Where(pg).Mutate(pg, model.Mutation{Field: &pg.ACLs, Mutator: ovsdb.MutateOperationDelete, Value: handles.ACLUUIDs})
+		if err != nil {
+			return fmt.Errorf("ovn: building port group unlink op: %w", err)
+		}
+		ops = append(ops, mutateOps...)
+	}
+	for _, uuid := range handles.ACLUUIDs {
+		delOps, err := c.Where(&schema.ACL{UUID: uuid}).Delete()
+		if err != nil {
+			return fmt.Errorf("ovn: building ACL delete op: %w", err)
+		}
+		ops = append(ops, delOps...)
+	}
+	for _, name := range handles.AddressSets {
+		delOps, err := c.Where(&schema.AddressSet{Name: name}).Delete()
+		if err != nil {
+			return fmt.Errorf("ovn: building address set delete op: %w", err)
+		}
+		ops = append(ops, delOps...)
+	}
+
+	return c.transact(ctx, ops)
+}
+
+// AddPortToPolicyGroup adds portName's logical switch port to networkID's
+// policy Port_Group. If no policy has ever been applied to networkID the
+// group doesn't exist yet, which isn't an error - there's simply nothing to
+// enforce against this port until one is.
+func (c *NBClient) AddPortToPolicyGroup(ctx context.Context, networkID, portName string) error {
+	return c.mutatePortGroupMembership(ctx, networkID, portName, ovsdb.MutateOperationInsert)
+}
+
+// RemovePortFromPolicyGroup removes portName's logical switch port from
+// networkID's policy Port_Group, tolerating a missing group or port the same
+// way AddPortToPolicyGroup does.
+func (c *NBClient) RemovePortFromPolicyGroup(ctx context.Context, networkID, portName string) error {
+	return c.mutatePortGroupMembership(ctx, networkID, portName, ovsdb.MutateOperationDelete)
+}
+
+func (c *NBClient) mutatePortGroupMembership(ctx context.Context, networkID, portName string, mutator ovsdb.Mutator) error {
+	pg, err := c.lookupPortGroup(ctx, PolicyGroupName(networkID))
+	if err != nil {
+		return nil
+	}
+	lsp, err := c.lookupPort(ctx, portName)
+	if err != nil {
+		return fmt.Errorf("ovn: port %s not found: %w", portName, err)
+	}
+	ops, err := c.Where(pg).Mutate(pg, model.Mutation{Field: &pg.Ports, Mutator: mutator, Value: []string{lsp.UUID}})
+	if err != nil {
+		return fmt.Errorf("ovn: building port group membership op: %w", err)
+	}
+	return c.transact(ctx, ops)
+}
+
+func (c *NBClient) ensurePortGroup(ctx context.Context, name string) (*schema.PortGroup, error) {
+	if pg, err := c.lookupPortGroup(ctx, name); err == nil {
+		return pg, nil
+	}
+	pg := &schema.PortGroup{Name: name}
+	ops, err := c.Create(pg)
+	if err != nil {
+		return nil, fmt.Errorf("ovn: building port group create op: %w", err)
+	}
+	if err := c.transact(ctx, ops); err != nil {
+		return nil, err
+	}
+	return c.lookupPortGroup(ctx, name)
+}
+
+// portMatch builds a protocol/port-range match clause, e.g. "tcp.dst==443"
+// or "tcp.dst=={8000..9000}" when min and max differ.
+func portMatch(protocol string, min, max uint32) string {
+	if min == max {
+		return fmt.Sprintf("%s.dst==%d", protocol, min)
+	}
+	return fmt.Sprintf("%s.dst=={%d..%d}", protocol, min, max)
+}
+
+func aclUUIDs(acls []*schema.ACL) []string {
+	uuids := make([]string, len(acls))
+	for i, acl := range acls {
+		uuids[i] = acl.UUID
+	}
+	return uuids
+}
+
+// realUUID resolves placeholder's corresponding insert result to the real
+// UUID the server assigned, by finding which op in ops it was the named-uuid
+// for. placeholder is only valid as a cross-reference within the same
+// transaction (see CreateLogicalSwitch's comment on this pattern); once the
+// transaction has committed, this is how callers get a UUID that's still
+// good afterward.
+func realUUID(results []ovsdb.OperationResult, ops []ovsdb.Operation, placeholder string) string {
+	for i, op := range ops {
+		if op.Op == ovsdb.OperationInsert && op.UUIDName == placeholder {
+			return results[i].UUID.GoUUID
+		}
+	}
+	return placeholder
+}
@@ -0,0 +1,129 @@
+package ovn
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ovn-org/libovsdb/model"
+	"github.com/ovn-org/libovsdb/ovsdb"
+
+	"arca-network-api/internal/ovn/schema"
+)
+
+// ProviderHandles is what CreateProviderUplink hands back so NetworkServer
+// can tear down the exact localnet port and NAT rule a provider network
+// binding created, the same way PolicyHandles lets SetNetworkPolicy replace
+// only the rows it owns.
+type ProviderHandles struct {
+	LocalnetPort string
+	NATUUID      string
+}
+
+func localnetPortName(networkID string) string {
+	return "lsp-" + networkID + "-provider"
+}
+
+// CreateProviderUplink wires networkID's logical switch up to a physical
+// network: a localnet-type logical switch port bound to providerName (the
+// name used in the host's external-ids:ovn-bridge-mappings, see
+// NetworkServer.CreateProviderNetwork), and a SNAT rule on the switch's peer
+// router so traffic leaving the network masquerades to externalGatewayIP
+// before it reaches the uplink. Both rows are created in one transaction, so
+// a failure never leaves the network with a localnet port but no SNAT (or
+// vice versa).
+func (c *NBClient) CreateProviderUplink(ctx context.Context, networkID, providerName, externalGatewayIP string) (*ProviderHandles, error) {
+	ls, err := c.lookupSwitch(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("ovn: logical switch %s not found: %w", networkID, err)
+	}
+	lr, err := c.lookupRouter(ctx, "router-"+networkID)
+	if err != nil {
+		return nil, fmt.Errorf("ovn: logical router for %s not found: %w", networkID, err)
+	}
+
+	lsp := &schema.LogicalSwitchPort{
+		Name:      localnetPortName(networkID),
+		Type:      "localnet",
+		Addresses: []string{"unknown"},
+		Options:   map[string]string{"network_name": providerName},
+	}
+	nat := &schema.NAT{
+		Type:       "snat",
+		ExternalIP: externalGatewayIP,
+		LogicalIP:  ls.OtherConfig["subnet"],
+	}
+
+	var ops []ovsdb.Operation
+	for _, m := range []model.Model{lsp, nat} {
+		o, err := c.Create(m)
+		if err != nil {
+			return nil, fmt.Errorf("ovn: building create op for %T: %w", m, err)
+		}
+		ops = append(ops, o...)
+	}
+
+	switchLinkOps, err := c.Where(ls).Mutate(ls,
+		model.Mutation{Field: &ls.Ports, Mutator: ovsdb.MutateOperationInsert, Value: []string{lsp.UUID}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ovn: building localnet port link op: %w", err)
+	}
+	ops = append(ops, switchLinkOps...)
+
+	routerLinkOps, err := c.Where(lr).Mutate(lr,
+		model.Mutation{Field: &lr.Nat, Mutator: ovsdb.MutateOperationInsert, Value: []string{nat.UUID}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ovn: building SNAT link op: %w", err)
+	}
+	ops = append(ops, routerLinkOps...)
+
+	results, err := c.transactWithResults(ctx, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProviderHandles{
+		LocalnetPort: lsp.Name,
+		NATUUID:      realUUID(results, ops, nat.UUID),
+	}, nil
+}
+
+// DeleteProviderUplink removes the localnet port and SNAT rule a prior
+// CreateProviderUplink call created, tolerating rows that are already gone
+// (e.g. the switch itself was already deleted) the same way
+// DeleteLogicalSwitchPort does.
+func (c *NBClient) DeleteProviderUplink(ctx context.Context, networkID string, handles *ProviderHandles) error {
+	if handles == nil {
+		return nil
+	}
+
+	if err := c.DeleteLogicalSwitchPort(ctx, handles.LocalnetPort); err != nil {
+		return fmt.Errorf("ovn: deleting localnet port %s: %w", handles.LocalnetPort, err)
+	}
+
+	lr, err := c.lookupRouter(ctx, "router-"+networkID)
+	if err != nil {
+		// Router is already gone - nothing left to unlink the NAT rule from.
+		return nil
+	}
+
+	var ops []ovsdb.Operation
+	if handles.NATUUID != "" {
+		mutateOps, err := c.Where(lr).Mutate(lr,
+			model.Mutation{Field: &lr.Nat, Mutator: ovsdb.MutateOperationDelete, Value: []string{handles.NATUUID}},
+		)
+		if err != nil {
+			return fmt.Errorf("ovn: building SNAT unlink op: %w", err)
+		}
+		ops = append(ops, mutateOps...)
+
+		delOps, err := c.Where(&schema.NAT{UUID: handles.NATUUID}).Delete()
+		if err != nil {
+			return fmt.Errorf("ovn: building SNAT delete op: %w", err)
+		}
+		ops = append(ops, delOps...)
+	}
+
+	return c.transact(ctx, ops)
+}
@@ -0,0 +1,126 @@
+// Package schema holds the generated Go bindings for the OVN Northbound
+// database (ovn-nb.ovsschema). Do not edit by hand - run `make ovn-schema`
+// (see the repo Makefile) to regenerate this file with libovsdb's modelgen
+// against the pinned schema version.
+package schema
+
+import "github.com/ovn-org/libovsdb/model"
+
+// LogicalSwitch corresponds to the Logical_Switch table.
+type LogicalSwitch struct {
+	UUID        string            `ovsdb:"_uuid"`
+	Name        string            `ovsdb:"name"`
+	Ports       []string          `ovsdb:"ports"`
+	ACLs        []string          `ovsdb:"acls"`
+	QOSRules    []string          `ovsdb:"qos_rules"`
+	LoadBalancer []string         `ovsdb:"load_balancer"`
+	DNSRecords  []string          `ovsdb:"dns_records"`
+	OtherConfig map[string]string `ovsdb:"other_config"`
+	ExternalIDs map[string]string `ovsdb:"external_ids"`
+}
+
+// LogicalSwitchPort corresponds to the Logical_Switch_Port table.
+type LogicalSwitchPort struct {
+	UUID            string            `ovsdb:"_uuid"`
+	Name            string            `ovsdb:"name"`
+	Type            string            `ovsdb:"type"`
+	Addresses       []string          `ovsdb:"addresses"`
+	DynamicAddresses *string          `ovsdb:"dynamic_addresses"`
+	PortSecurity    []string          `ovsdb:"port_security"`
+	DHCPv4Options   *string           `ovsdb:"dhcpv4_options"`
+	DHCPv6Options   *string           `ovsdb:"dhcpv6_options"`
+	Options         map[string]string `ovsdb:"options"`
+	Enabled         *bool             `ovsdb:"enabled"`
+	ExternalIDs     map[string]string `ovsdb:"external_ids"`
+}
+
+// LogicalRouter corresponds to the Logical_Router table.
+type LogicalRouter struct {
+	UUID  string   `ovsdb:"_uuid"`
+	Name  string   `ovsdb:"name"`
+	Ports []string `ovsdb:"ports"`
+	Nat   []string `ovsdb:"nat"`
+	StaticRoutes []string `ovsdb:"static_routes"`
+	ExternalIDs map[string]string `ovsdb:"external_ids"`
+}
+
+// LogicalRouterPort corresponds to the Logical_Router_Port table.
+type LogicalRouterPort struct {
+	UUID     string            `ovsdb:"_uuid"`
+	Name     string            `ovsdb:"name"`
+	MAC      string            `ovsdb:"mac"`
+	Networks []string          `ovsdb:"networks"`
+	GatewayChassis []string    `ovsdb:"gateway_chassis"`
+	Options  map[string]string `ovsdb:"options"`
+}
+
+// DHCPOptions corresponds to the DHCP_Options table.
+type DHCPOptions struct {
+	UUID        string            `ovsdb:"_uuid"`
+	CIDR        string            `ovsdb:"cidr"`
+	Options     map[string]string `ovsdb:"options"`
+	ExternalIDs map[string]string `ovsdb:"external_ids"`
+}
+
+// ACL corresponds to the ACL table.
+type ACL struct {
+	UUID      string `ovsdb:"_uuid"`
+	Name      *string `ovsdb:"name"`
+	Direction string `ovsdb:"direction"`
+	Match     string `ovsdb:"match"`
+	Action    string `ovsdb:"action"`
+	Priority  int    `ovsdb:"priority"`
+	Log       bool   `ovsdb:"log"`
+	Severity  *string `ovsdb:"severity"`
+	ExternalIDs map[string]string `ovsdb:"external_ids"`
+}
+
+// AddressSet corresponds to the Address_Set table.
+type AddressSet struct {
+	UUID      string            `ovsdb:"_uuid"`
+	Name      string            `ovsdb:"name"`
+	Addresses []string          `ovsdb:"addresses"`
+	ExternalIDs map[string]string `ovsdb:"external_ids"`
+}
+
+// PortGroup corresponds to the Port_Group table.
+type PortGroup struct {
+	UUID  string            `ovsdb:"_uuid"`
+	Name  string            `ovsdb:"name"`
+	Ports []string          `ovsdb:"ports"`
+	ACLs  []string          `ovsdb:"acls"`
+	ExternalIDs map[string]string `ovsdb:"external_ids"`
+}
+
+// DNS corresponds to the DNS table.
+type DNS struct {
+	UUID        string            `ovsdb:"_uuid"`
+	Records     map[string]string `ovsdb:"records"`
+	ExternalIDs map[string]string `ovsdb:"external_ids"`
+}
+
+// NAT corresponds to the NAT table.
+type NAT struct {
+	UUID        string            `ovsdb:"_uuid"`
+	Type        string            `ovsdb:"type"`
+	ExternalIP  string            `ovsdb:"external_ip"`
+	LogicalIP   string            `ovsdb:"logical_ip"`
+	ExternalIDs map[string]string `ovsdb:"external_ids"`
+}
+
+// FullDatabaseModel returns the libovsdb ClientDBModel covering every
+// Northbound table this package's client needs.
+func FullDatabaseModel() (model.ClientDBModel, error) {
+	return model.NewClientDBModel("OVN_Northbound", map[string]model.Model{
+		"Logical_Switch":      &LogicalSwitch{},
+		"Logical_Switch_Port": &LogicalSwitchPort{},
+		"Logical_Router":      &LogicalRouter{},
+		"Logical_Router_Port": &LogicalRouterPort{},
+		"DHCP_Options":        &DHCPOptions{},
+		"ACL":                 &ACL{},
+		"Address_Set":         &AddressSet{},
+		"Port_Group":          &PortGroup{},
+		"DNS":                 &DNS{},
+		"NAT":                 &NAT{},
+	})
+}
@@ -0,0 +1,53 @@
+// Package validate checks untrusted strings arriving over the
+// NetworkControl gRPC boundary before they reach OVN or a subprocess -
+// hostnames and IP addresses bound for OVN DNS records, in particular.
+package validate
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// label matches a single RFC 1123 DNS label: 1-63 characters, alphanumeric
+// with interior hyphens.
+var label = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// Hostname rejects anything that isn't a dot-separated sequence of RFC 1123
+// labels, e.g. "web-1.svc".
+func Hostname(name string) error {
+	if name == "" {
+		return fmt.Errorf("validate: hostname is required")
+	}
+	for _, l := range strings.Split(name, ".") {
+		if !label.MatchString(l) {
+			return fmt.Errorf("validate: %q is not a valid RFC 1123 hostname", name)
+		}
+	}
+	return nil
+}
+
+// IPAddress rejects anything netip can't parse as an IPv4 or IPv6 address.
+func IPAddress(addr string) error {
+	if _, err := netip.ParseAddr(addr); err != nil {
+		return fmt.Errorf("validate: %q is not a valid IP address: %w", addr, err)
+	}
+	return nil
+}
+
+// minIDLen is the shortest ContainerId/NetworkId this server will accept.
+// Several call sites derive OVS/OVN port names by slicing these IDs (see
+// attachPortName and the inline slices in AttachContainer/DetachContainer)
+// without re-checking length, so anything shorter would panic with "slice
+// bounds out of range" instead of failing the RPC cleanly.
+const minIDLen = 12
+
+// ID rejects a ContainerId or NetworkId shorter than the server's port-name
+// derivation ever assumes.
+func ID(kind, id string) error {
+	if len(id) < minIDLen {
+		return fmt.Errorf("validate: %s %q is shorter than the minimum %d characters", kind, id, minIDLen)
+	}
+	return nil
+}
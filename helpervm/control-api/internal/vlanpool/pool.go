@@ -0,0 +1,170 @@
+// Package vlanpool allocates the VLAN tags CreateBridge stamps onto OVN
+// logical switches. It replaces the old monotonic nextVLAN counter - which
+// forgot everything on restart and never reclaimed a tag DeleteBridge freed
+// up - with bindings persisted to a JSON file under a data directory (e.g.
+// /var/lib/arca/network/), so the daemon survives a restart without either
+// losing track of tags in use or handing out one already bound to a live
+// network.
+package vlanpool
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// MinTag and MaxTag bound the VLAN tags CreateBridge can hand out;
+	// 1-99 are reserved the same way the old nextVLAN counter reserved them.
+	MinTag = 100
+	MaxTag = 4095
+
+	fileName = "vlan-pool.json"
+)
+
+// Stats summarizes pool occupancy for GetVLANPoolStats.
+type Stats struct {
+	Allocated int
+	Free      int
+	Total     int
+}
+
+// Pool is a tag -> networkID allocator backed by an on-disk JSON file. All
+// exported methods are safe for concurrent use.
+type Pool struct {
+	mu       sync.Mutex
+	path     string
+	bindings map[uint32]string // tag -> networkID
+}
+
+// onDiskState is the JSON document persisted at path.
+type onDiskState struct {
+	Bindings map[string]string `json:"bindings"` // tag (decimal string) -> networkID
+}
+
+// Open loads dir/vlan-pool.json if present, or starts with an empty pool if
+// it doesn't exist yet (e.g. first run). dir is created if missing.
+func Open(dir string) (*Pool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("vlanpool: creating %s: %w", dir, err)
+	}
+
+	p := &Pool{
+		path:     filepath.Join(dir, fileName),
+		bindings: make(map[uint32]string),
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return nil, fmt.Errorf("vlanpool: reading %s: %w", p.path, err)
+	}
+
+	var state onDiskState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("vlanpool: parsing %s: %w", p.path, err)
+	}
+	for tagStr, networkID := range state.Bindings {
+		var tag uint32
+		if _, err := fmt.Sscanf(tagStr, "%d", &tag); err != nil {
+			return nil, fmt.Errorf("vlanpool: invalid tag %q in %s: %w", tagStr, p.path, err)
+		}
+		p.bindings[tag] = networkID
+	}
+	return p, nil
+}
+
+// Reconcile replaces the pool's bindings with truth (e.g. from
+// ovn.NBClient.SwitchVLANTags), so a stale or missing on-disk file never
+// causes a tag still in use by a live logical switch to be handed out again.
+func (p *Pool) Reconcile(truth map[string]uint32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.bindings = make(map[uint32]string, len(truth))
+	for networkID, tag := range truth {
+		p.bindings[tag] = networkID
+	}
+	return p.save()
+}
+
+// Allocate reserves the lowest free tag in [MinTag, MaxTag] for networkID.
+func (p *Pool) Allocate(networkID string) (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for tag := uint32(MinTag); tag <= MaxTag; tag++ {
+		if _, taken := p.bindings[tag]; !taken {
+			p.bindings[tag] = networkID
+			if err := p.save(); err != nil {
+				delete(p.bindings, tag)
+				return 0, err
+			}
+			return tag, nil
+		}
+	}
+	return 0, fmt.Errorf("vlanpool: exhausted - all tags in [%d, %d] are in use", MinTag, MaxTag)
+}
+
+// Reserve binds a specific tag to networkID, failing if it's already bound
+// to a different network. Used to seed the pool from OVN's actual state.
+func (p *Pool) Reserve(tag uint32, networkID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if existing, ok := p.bindings[tag]; ok && existing != networkID {
+		return fmt.Errorf("vlanpool: tag %d already bound to network %s", tag, existing)
+	}
+	p.bindings[tag] = networkID
+	return p.save()
+}
+
+// Release frees tag so a future Allocate can hand it out again.
+func (p *Pool) Release(tag uint32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.bindings, tag)
+	return p.save()
+}
+
+// Stats reports current pool occupancy.
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	total := MaxTag - MinTag + 1
+	return Stats{
+		Allocated: len(p.bindings),
+		Free:      total - len(p.bindings),
+		Total:     total,
+	}
+}
+
+// save persists the pool to disk, writing to a temp file and renaming over
+// the real path so a crash mid-write can never leave a truncated/corrupt
+// file behind. Callers must hold p.mu.
+func (p *Pool) save() error {
+	state := onDiskState{Bindings: make(map[string]string, len(p.bindings))}
+	for tag, networkID := range p.bindings {
+		state.Bindings[fmt.Sprintf("%d", tag)] = networkID
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("vlanpool: encoding state: %w", err)
+	}
+
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("vlanpool: writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("vlanpool: renaming %s to %s: %w", tmp, p.path, err)
+	}
+	return nil
+}
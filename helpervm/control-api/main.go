@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -10,28 +12,92 @@ import (
 
 	"github.com/mdlayher/vsock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 
+	"arca-network-api/internal/ovn"
+	"arca-network-api/internal/vlanpool"
+	"arca-network-api/pkg/inventory"
 	pb "arca-network-api/proto"
 )
 
 var (
 	vsockPort = flag.Uint("vsock-port", 9999, "vsock port to listen on")
 	tcpPort   = flag.Uint("tcp-port", 9999, "TCP port to listen on for container connections")
+	ovnNBDB   = flag.String("ovn-nb-db", "unix:/var/run/ovn/ovnnb_db.sock", "OVN Northbound database endpoint")
+	dataDir   = flag.String("data-dir", "/var/lib/arca/network", "directory for persistent daemon state (VLAN pool, etc.)")
 )
 
+// readyPollInterval is how often waitUntilNetworksReady re-checks and logs
+// not-yet-ready switches, mirroring kube-ovn's allSubnetReady/PollUntil loop.
+const readyPollInterval = 3 * time.Second
+
 func main() {
 	flag.Parse()
 
 	log.Println("Starting Arca Network Control API server...")
 
+	// Connect to the OVN Northbound database up front, with reconnect
+	// backoff, rather than forking ovn-nbctl per call.
+	nb, err := ovn.Connect(context.Background(), *ovnNBDB)
+	if err != nil {
+		log.Fatalf("Failed to connect to OVN Northbound DB at %s: %v", *ovnNBDB, err)
+	}
+	defer nb.Close()
+
+	// Open the persistent VLAN pool and reconcile it against OVN's actual
+	// state, so a stale or missing on-disk file never causes a tag already
+	// bound to a live logical switch to be handed out again.
+	vlans, err := vlanpool.Open(*dataDir)
+	if err != nil {
+		log.Fatalf("Failed to open VLAN pool at %s: %v", *dataDir, err)
+	}
+	truth, err := nb.SwitchVLANTags(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to read VLAN tags from OVN: %v", err)
+	}
+	if err := vlans.Reconcile(truth); err != nil {
+		log.Fatalf("Failed to reconcile VLAN pool: %v", err)
+	}
+	log.Printf("VLAN pool reconciled: %d network(s) already bound", len(truth))
+
+	// Don't start accepting gRPC traffic until every logical switch already
+	// in the pool has a router, router port and DHCP_Options row - otherwise
+	// an AttachContainer landing mid-startup could race northd.
+	if err := waitUntilNetworksReady(context.Background(), nb, truth); err != nil {
+		log.Fatalf("Failed waiting for networks to become ready: %v", err)
+	}
+
+	// Open the persistent container<->network inventory and reconcile it
+	// against OVN's actual state, the same way the VLAN pool is reconciled
+	// above, so stale or missing on-disk bookkeeping never diverges from
+	// what's actually wired up in OVN.
+	inv, err := inventory.Open(*dataDir)
+	if err != nil {
+		log.Fatalf("Failed to open inventory at %s: %v", *dataDir, err)
+	}
+	defer inv.Close()
+	containerPorts, err := nb.ContainerPorts(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to read container ports from OVN: %v", err)
+	}
+	drifted, err := inv.Reconcile(containerPorts)
+	if err != nil {
+		log.Fatalf("Failed to reconcile inventory: %v", err)
+	}
+	if len(drifted) > 0 {
+		log.Printf("Inventory reconciled: corrected drift for %d network(s): %v", len(drifted), drifted)
+	}
+
 	// Initialize the network server
-	networkServer := NewNetworkServer()
+	networkServer := NewNetworkServer(nb, vlans, inv)
 
 	// Create gRPC server
 	grpcServer := grpc.NewServer(
 		grpc.ConnectionTimeout(time.Second * 10),
+		grpc.UnaryInterceptor(recoveryInterceptor),
 	)
 
 	// Register services
@@ -68,3 +134,47 @@ func main() {
 
 	log.Println("Server stopped")
 }
+
+// recoveryInterceptor turns a panic in a unary RPC handler into a
+// codes.Internal error instead of crashing the whole process - this server
+// handles every container's networking, so one malformed request (e.g. a
+// ContainerId too short for a handler's slicing) taking down the process
+// would take every other container's networking down with it.
+func recoveryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic in %s: %v", info.FullMethod, r)
+			err = status.Errorf(codes.Internal, "internal error handling %s", info.FullMethod)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// waitUntilNetworksReady blocks until every network in truth (networkID ->
+// VLAN tag, as returned by SwitchVLANTags) has a router, router port and
+// DHCP_Options row in OVN, logging which ones are still missing every
+// readyPollInterval - modeled on kube-ovn's allSubnetReady/PollUntil startup
+// reconciliation loop.
+func waitUntilNetworksReady(ctx context.Context, nb *ovn.NBClient, truth map[string]uint32) error {
+	for {
+		var notReady []string
+		for networkID := range truth {
+			ready, err := nb.SwitchReady(ctx, networkID)
+			if err != nil {
+				return fmt.Errorf("checking readiness of network %s: %w", networkID, err)
+			}
+			if !ready {
+				notReady = append(notReady, networkID)
+			}
+		}
+		if len(notReady) == 0 {
+			return nil
+		}
+		log.Printf("Waiting for %d network(s) to become ready: %v", len(notReady), notReady)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(readyPollInterval):
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package exec runs external commands as argument vectors, never through a
+// shell, so caller-controlled strings (e.g. a hostname from a gRPC request)
+// can't be interpreted as shell metacharacters the way this package's old
+// `sh -c fmt.Sprintf(...)` callsites could.
+package exec
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Cmd is an argument vector ready to run, plus any extra environment
+// variables to set alongside the process's inherited environment.
+type Cmd struct {
+	Name string
+	Args []string
+	Env  []string
+}
+
+// New builds a Cmd for name with args, both passed straight to exec.Command -
+// never through a shell.
+func New(name string, args ...string) *Cmd {
+	return &Cmd{Name: name, Args: args}
+}
+
+// WithEnv appends env (in os/exec's "KEY=value" form) to the process's
+// inherited environment.
+func (c *Cmd) WithEnv(env ...string) *Cmd {
+	c.Env = append(c.Env, env...)
+	return c
+}
+
+// Run runs the command, discarding its output.
+func (c *Cmd) Run() error {
+	_, err := c.Output()
+	return err
+}
+
+// Output runs the command and returns its combined stdout/stderr.
+func (c *Cmd) Output() (string, error) {
+	cmd := exec.Command(c.Name, c.Args...)
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("exec: %s %v: %w (output: %s)", c.Name, c.Args, err, out)
+	}
+	return string(out), nil
+}
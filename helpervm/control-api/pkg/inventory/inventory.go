@@ -0,0 +1,282 @@
+// Package inventory persists the container<->network attachment state that
+// used to live only in NetworkServer.containerMap, a plain in-memory map.
+// It's backed by an embedded bbolt database with two indexes -
+// network->containers and container->networks - so a server restart doesn't
+// forget every Attach call's bookkeeping and GetContainerNetworks becomes an
+// O(1) index lookup instead of an O(N*M) scan.
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	fileName = "inventory.db"
+
+	// networksBucket maps networkID -> JSON array of attached containerIDs.
+	networksBucket = "networks"
+	// containersBucket maps containerID -> JSON array of attached networkIDs.
+	// Kept in lockstep with networksBucket by every method below.
+	containersBucket = "containers"
+)
+
+// Store is a container<->network attachment inventory backed by a bbolt
+// file. All exported methods are safe for concurrent use.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (creating if necessary) dir/inventory.db and ensures both
+// indexes exist.
+func Open(dir string) (*Store, error) {
+	path := filepath.Join(dir, fileName)
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("inventory: opening %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(networksBucket)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists([]byte(containersBucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("inventory: initializing %s: %w", path, err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying bbolt file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Attach records that containerID is attached to networkID, in both
+// indexes, in one transaction.
+func (s *Store) Attach(networkID, containerID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := addMember(tx.Bucket([]byte(networksBucket)), networkID, containerID); err != nil {
+			return err
+		}
+		return addMember(tx.Bucket([]byte(containersBucket)), containerID, networkID)
+	})
+}
+
+// Detach removes the networkID<->containerID association, tolerating one
+// that was never recorded.
+func (s *Store) Detach(networkID, containerID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := removeMember(tx.Bucket([]byte(networksBucket)), networkID, containerID); err != nil {
+			return err
+		}
+		return removeMember(tx.Bucket([]byte(containersBucket)), containerID, networkID)
+	})
+}
+
+// DeleteNetwork detaches every container recorded against networkID, for
+// when DeleteBridge/DeleteProviderNetwork tears the network itself down.
+func (s *Store) DeleteNetwork(networkID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		networks := tx.Bucket([]byte(networksBucket))
+		containerIDs, err := members(networks, networkID)
+		if err != nil {
+			return err
+		}
+		containers := tx.Bucket([]byte(containersBucket))
+		for _, containerID := range containerIDs {
+			if err := removeMember(containers, containerID, networkID); err != nil {
+				return err
+			}
+		}
+		return networks.Delete([]byte(networkID))
+	})
+}
+
+// NetworksFor returns every networkID containerID is attached to.
+func (s *Store) NetworksFor(containerID string) ([]string, error) {
+	var ids []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		ids, err = members(tx.Bucket([]byte(containersBucket)), containerID)
+		return err
+	})
+	return ids, err
+}
+
+// ContainersOn returns every containerID attached to networkID.
+func (s *Store) ContainersOn(networkID string) ([]string, error) {
+	var ids []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		ids, err = members(tx.Bucket([]byte(networksBucket)), networkID)
+		return err
+	})
+	return ids, err
+}
+
+// ListNetworks returns up to pageSize networkIDs with at least one attached
+// container, in key order, starting after afterNetworkID ("" for the first
+// page). The returned cursor is the afterNetworkID to pass for the next
+// page, or "" once there isn't one.
+func (s *Store) ListNetworks(afterNetworkID string, pageSize int) (ids []string, cursor string, err error) {
+	return paginate(s.db, networksBucket, afterNetworkID, pageSize)
+}
+
+// ListContainers returns up to pageSize containerIDs with at least one
+// attached network, paginated the same way as ListNetworks.
+func (s *Store) ListContainers(afterContainerID string, pageSize int) (ids []string, cursor string, err error) {
+	return paginate(s.db, containersBucket, afterContainerID, pageSize)
+}
+
+// Reconcile compares truth (as returned by ovn.NBClient.ContainerPorts)
+// against the networks index and corrects any drift: a network OVN says has
+// containerIDs the inventory doesn't (or vice versa) is overwritten with
+// OVN's view, since OVN is the system of record for what's actually wired
+// up. It returns the networkIDs that needed correcting.
+func (s *Store) Reconcile(truth map[string][]string) ([]string, error) {
+	var drifted []string
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		networks := tx.Bucket([]byte(networksBucket))
+		containers := tx.Bucket([]byte(containersBucket))
+
+		seen := make(map[string]bool, len(truth))
+		for networkID, containerIDs := range truth {
+			seen[networkID] = true
+			existing, err := members(networks, networkID)
+			if err != nil {
+				return err
+			}
+			if sameMembers(existing, containerIDs) {
+				continue
+			}
+			drifted = append(drifted, networkID)
+			for _, containerID := range existing {
+				if err := removeMember(containers, containerID, networkID); err != nil {
+					return err
+				}
+			}
+			for _, containerID := range containerIDs {
+				if err := addMember(containers, containerID, networkID); err != nil {
+					return err
+				}
+			}
+			if err := putMembers(networks, networkID, containerIDs); err != nil {
+				return err
+			}
+		}
+
+		return networks.ForEach(func(k, v []byte) error {
+			networkID := string(k)
+			if seen[networkID] {
+				return nil
+			}
+			drifted = append(drifted, networkID)
+			existing, err := members(networks, networkID)
+			if err != nil {
+				return err
+			}
+			for _, containerID := range existing {
+				if err := removeMember(containers, containerID, networkID); err != nil {
+					return err
+				}
+			}
+			return networks.Delete(k)
+		})
+	})
+	return drifted, err
+}
+
+func sameMembers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, m := range a {
+		set[m] = true
+	}
+	for _, m := range b {
+		if !set[m] {
+			return false
+		}
+	}
+	return true
+}
+
+func paginate(db *bolt.DB, bucket, after string, pageSize int) (ids []string, cursor string, err error) {
+	err = db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(bucket)).Cursor()
+		var k []byte
+		if after == "" {
+			k, _ = c.First()
+		} else {
+			c.Seek([]byte(after))
+			k, _ = c.Next()
+		}
+		for ; k != nil && len(ids) < pageSize; k, _ = c.Next() {
+			ids = append(ids, string(k))
+		}
+		if k != nil {
+			cursor = string(k)
+		}
+		return nil
+	})
+	return ids, cursor, err
+}
+
+func addMember(bucket *bolt.Bucket, key, member string) error {
+	existing, err := members(bucket, key)
+	if err != nil {
+		return err
+	}
+	for _, m := range existing {
+		if m == member {
+			return nil
+		}
+	}
+	return putMembers(bucket, key, append(existing, member))
+}
+
+func removeMember(bucket *bolt.Bucket, key, member string) error {
+	existing, err := members(bucket, key)
+	if err != nil {
+		return err
+	}
+	filtered := existing[:0]
+	for _, m := range existing {
+		if m != member {
+			filtered = append(filtered, m)
+		}
+	}
+	if len(filtered) == 0 {
+		return bucket.Delete([]byte(key))
+	}
+	return putMembers(bucket, key, filtered)
+}
+
+func members(bucket *bolt.Bucket, key string) ([]string, error) {
+	raw := bucket.Get([]byte(key))
+	if raw == nil {
+		return nil, nil
+	}
+	var ids []string
+	if err := json.Unmarshal(raw, &ids); err != nil {
+		return nil, fmt.Errorf("inventory: decoding %s: %w", key, err)
+	}
+	return ids, nil
+}
+
+func putMembers(bucket *bolt.Bucket, key string, ids []string) error {
+	raw, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("inventory: encoding %s: %w", key, err)
+	}
+	return bucket.Put([]byte(key), raw)
+}
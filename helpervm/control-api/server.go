@@ -2,27 +2,66 @@ package main
 
 import (
 	"context"
+	"crypto/md5"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"arca-network-api/internal/ovn"
+	"arca-network-api/internal/validate"
+	"arca-network-api/internal/vlanpool"
+	arcaexec "arca-network-api/pkg/exec"
+	"arca-network-api/pkg/inventory"
 	pb "arca-network-api/proto"
 )
 
+// defaultInterfaceMTU is returned for every interface AttachContainerInterfaces
+// provisions; the TAP relay path doesn't yet support per-network MTU overrides.
+const defaultInterfaceMTU = 1500
+
 // NetworkServer implements the NetworkControl gRPC service
 type NetworkServer struct {
 	pb.UnimplementedNetworkControlServer
-	mu            sync.RWMutex
-	bridges       map[string]*BridgeMetadata
-	containerMap  map[string]map[string]bool // networkID -> containerID -> exists
-	relayManager  *TAPRelayManager
-	containerPort map[string]uint32 // containerID -> helperPort (for TAP relay cleanup)
-	startTime     time.Time
-	nextVLAN      uint32 // Next available VLAN tag (starts at 100)
+	mu                  sync.RWMutex
+	nb                  *ovn.NBClient
+	bridges             map[string]*BridgeMetadata
+	inv                 *inventory.Store // persistent network<->container attachment index
+	relayManager        *TAPRelayManager
+	containerPort       map[string]uint32                    // containerID -> helperPort (for TAP relay cleanup)
+	policies            map[string]*networkPolicy            // policyID -> last-applied OVN state
+	containerInterfaces map[string][]*attachedInterfaceState // containerID -> interfaces from AttachContainerInterfaces
+	startTime           time.Time
+	vlans               *vlanpool.Pool // persistent, reclaimable VLAN tag allocator
+	dnsLocksMu          sync.Mutex
+	dnsLocks            map[string]*sync.Mutex // networkID -> lock serializing its DNS record mutations
+}
+
+// attachedInterfaceState is the bookkeeping AttachContainerInterfaces keeps
+// per interface so a later no-networkID DetachContainer can tear all of them
+// down; it mirrors pb.AttachedInterface plus the relay port that isn't part
+// of the wire response.
+type attachedInterfaceState struct {
+	networkID      string
+	interfaceName  string
+	portName       string
+	macAddress     string
+	defaultGateway bool
+	helperPort     uint32 // 0 if no TAP relay was started for this interface
+}
+
+// StaticRoute is a destination/gateway pair pushed to a container interface
+// over the TAP relay's control channel.
+type StaticRoute struct {
+	Destination string
+	Gateway     string
 }
 
 // BridgeMetadata stores metadata about a network bridge
@@ -31,18 +70,97 @@ type BridgeMetadata struct {
 	BridgeName string // The actual br-XXXX name
 	Subnet     string
 	Gateway    string
+	Provider   *ProviderBinding // non-nil if this network has a physical VLAN uplink
 }
 
-// NewNetworkServer creates a new NetworkServer
-func NewNetworkServer() *NetworkServer {
+// ProviderBinding records the physical-network side of a CreateProviderNetwork
+// call, so DeleteProviderNetwork and ListProviderNetworks can report and
+// reverse it without re-deriving it from OVS state.
+type ProviderBinding struct {
+	PhysicalInterface string
+	VLANID            uint32
+	OVSBridge         string // br-provider-<name>
+	handles           *ovn.ProviderHandles
+}
+
+// networkPolicy tracks which network a policy was applied to and the OVN
+// rows (Port_Group, ACLs, Address_Sets) it produced, so a later
+// SetNetworkPolicy call for the same policyID can replace them atomically.
+type networkPolicy struct {
+	networkID string
+	handles   *ovn.PolicyHandles
+}
+
+// NewNetworkServer creates a new NetworkServer backed by an OVN Northbound
+// client that's already connected (see ovn.Connect), a VLAN pool that's
+// already been opened and reconciled against OVN's actual state (see
+// vlanpool.Open and ovn.NBClient.SwitchVLANTags), and an inventory store
+// that's already been opened and reconciled the same way (see
+// inventory.Open and ovn.NBClient.ContainerPorts).
+func NewNetworkServer(nb *ovn.NBClient, vlans *vlanpool.Pool, inv *inventory.Store) *NetworkServer {
 	return &NetworkServer{
-		bridges:       make(map[string]*BridgeMetadata),
-		containerMap:  make(map[string]map[string]bool),
-		relayManager:  NewTAPRelayManager(),
-		containerPort: make(map[string]uint32),
-		startTime:     time.Now(),
-		nextVLAN:      100, // VLAN tags 100-4095 available (1-99 reserved)
+		nb:                  nb,
+		bridges:             make(map[string]*BridgeMetadata),
+		inv:                 inv,
+		relayManager:        NewTAPRelayManager(),
+		containerPort:       make(map[string]uint32),
+		policies:            make(map[string]*networkPolicy),
+		containerInterfaces: make(map[string][]*attachedInterfaceState),
+		startTime:           time.Now(),
+		vlans:               vlans,
+		dnsLocks:            make(map[string]*sync.Mutex),
+	}
+}
+
+// dnsLock returns the mutex serializing DNS record mutations for networkID,
+// creating it on first use. ovn.NBClient.SetDNSRecord only makes the
+// steady-state "append to the existing DNS row" path atomic; two concurrent
+// first-time calls for the same network would otherwise both observe no DNS
+// row yet and race to create one. Routing every call for a given networkID
+// through this lock (see SetDNSRecord/DeleteDNSRecord below) closes that
+// window without having to serialize unrelated networks against each other.
+func (s *NetworkServer) dnsLock(networkID string) *sync.Mutex {
+	s.dnsLocksMu.Lock()
+	defer s.dnsLocksMu.Unlock()
+	l, ok := s.dnsLocks[networkID]
+	if !ok {
+		l = &sync.Mutex{}
+		s.dnsLocks[networkID] = l
+	}
+	return l
+}
+
+// SetDNSRecord binds hostname to ipAddress on networkID's logical switch,
+// serializing with every other DNS mutation for the same network (see
+// dnsLock) so concurrent callers can't race each other into creating two DNS
+// rows for the same switch. hostname and ipAddress are gRPC-request input,
+// so they're validated here - at the boundary - before anything downstream
+// treats them as trusted.
+func (s *NetworkServer) SetDNSRecord(ctx context.Context, networkID, hostname, ipAddress string) error {
+	if err := validate.Hostname(hostname); err != nil {
+		return err
+	}
+	if err := validate.IPAddress(ipAddress); err != nil {
+		return err
+	}
+
+	l := s.dnsLock(networkID)
+	l.Lock()
+	defer l.Unlock()
+	return s.nb.SetDNSRecord(ctx, networkID, hostname, ipAddress)
+}
+
+// DeleteDNSRecord removes hostname from networkID's DNS records, serializing
+// with every other DNS mutation for the same network (see dnsLock).
+func (s *NetworkServer) DeleteDNSRecord(ctx context.Context, networkID, hostname string) error {
+	if err := validate.Hostname(hostname); err != nil {
+		return err
 	}
+
+	l := s.dnsLock(networkID)
+	l.Lock()
+	defer l.Unlock()
+	return s.nb.DeleteDNSRecord(ctx, networkID, hostname)
 }
 
 // CreateBridge creates an OVN logical switch with VLAN tag (OVN-native architecture)
@@ -50,273 +168,366 @@ func NewNetworkServer() *NetworkServer {
 func (s *NetworkServer) CreateBridge(ctx context.Context, req *pb.CreateBridgeRequest) (*pb.CreateBridgeResponse, error) {
 	log.Printf("CreateBridge: networkID=%s, subnet=%s, gateway=%s", req.NetworkId, req.Subnet, req.Gateway)
 
+	if err := validate.ID("network_id", req.NetworkId); err != nil {
+		return &pb.CreateBridgeResponse{Success: false, Error: err.Error()}, nil
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Check if logical switch already exists (idempotency)
-	if _, err := runCommandWithOutput("ovn-nbctl", "get", "logical_switch", req.NetworkId, "name"); err == nil {
-		// Logical switch exists - get existing VLAN tag
-		vlanStr, err := runCommandWithOutput("ovn-nbctl", "get", "logical_switch", req.NetworkId, "external_ids:vlan_tag")
-		vlanStr = strings.Trim(strings.TrimSpace(vlanStr), "\"")
-		if err != nil || vlanStr == "" {
-			log.Printf("WARNING: Logical switch %s exists but has no VLAN tag", req.NetworkId)
-			vlanStr = "0"
-		}
-
-		log.Printf("Logical switch %s already exists with VLAN tag %s (idempotent)", req.NetworkId, vlanStr)
-
+	if vlanTag, err := s.nb.VLANTag(ctx, req.NetworkId); err == nil {
+		log.Printf("Logical switch %s already exists with VLAN tag %d (idempotent)", req.NetworkId, vlanTag)
 		return &pb.CreateBridgeResponse{
 			BridgeName: "br-int", // All networks use br-int now
 			Success:    true,
 		}, nil
 	}
 
-	// Allocate VLAN tag for this network (100-4095)
-	vlanTag := s.nextVLAN
-	if vlanTag > 4095 {
+	// Allocate a VLAN tag for this network from the persistent pool (100-4095)
+	vlanTag, err := s.vlans.Allocate(req.NetworkId)
+	if err != nil {
 		return &pb.CreateBridgeResponse{
 			Success: false,
-			Error:   "VLAN tag exhaustion: maximum 3996 networks reached (100-4095)",
+			Error:   fmt.Sprintf("Failed to allocate VLAN tag: %v", err),
 		}, nil
 	}
-	s.nextVLAN++
 
 	log.Printf("Allocated VLAN tag %d for network %s", vlanTag, req.NetworkId)
 
-	// Create OVN logical switch
-	if err := runCommand("ovn-nbctl", "ls-add", req.NetworkId); err != nil {
+	// Create the logical switch, its DHCP options, a peer logical router and
+	// the switch<->router port pair in one atomic transaction - if anything
+	// fails, OVN's state is untouched and there's no partial bridge to clean
+	// up the way the old ovn-nbctl-per-step version needed to.
+	if err := s.nb.CreateLogicalSwitch(ctx, req.NetworkId, vlanTag, req.Subnet, req.Gateway); err != nil {
+		if releaseErr := s.vlans.Release(vlanTag); releaseErr != nil {
+			log.Printf("Warning: Failed to release VLAN tag %d after failed CreateLogicalSwitch: %v", vlanTag, releaseErr)
+		}
 		return &pb.CreateBridgeResponse{
 			Success: false,
 			Error:   fmt.Sprintf("Failed to create OVN logical switch: %v", err),
 		}, nil
 	}
 
-	// Store VLAN tag in logical switch external_ids for TAP relay to read
-	if err := runCommand("ovn-nbctl", "set", "logical_switch", req.NetworkId,
-		fmt.Sprintf("external_ids:vlan_tag=%d", vlanTag)); err != nil {
-		// Cleanup
-		_ = runCommand("ovn-nbctl", "ls-del", req.NetworkId)
-		return &pb.CreateBridgeResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to set VLAN tag on logical switch: %v", err),
-		}, nil
+	// Store metadata (still tracking for compatibility, but bridgeName is always br-int now)
+	s.bridges[req.NetworkId] = &BridgeMetadata{
+		NetworkID:  req.NetworkId,
+		BridgeName: "br-int", // All networks use br-int with VLAN tags
+		Subnet:     req.Subnet,
+		Gateway:    req.Gateway,
 	}
 
-	// Set subnet, gateway, and exclude IPs in OVN
-	// exclude_ips prevents OVN from allocating the gateway IP to containers
-	if err := runCommand("ovn-nbctl", "set", "logical_switch", req.NetworkId,
-		fmt.Sprintf("other_config:subnet=%s", req.Subnet),
-		fmt.Sprintf("other_config:gateway=%s", req.Gateway),
-		fmt.Sprintf("other_config:exclude_ips=%s", req.Gateway)); err != nil {
-		// Cleanup
-		_ = runCommand("ovn-nbctl", "ls-del", req.NetworkId)
-		return &pb.CreateBridgeResponse{
+	log.Printf("Successfully created OVN logical switch %s with VLAN tag %d", req.NetworkId, vlanTag)
+
+	return &pb.CreateBridgeResponse{
+		BridgeName: "br-int", // All networks use br-int now
+		Success:    true,
+	}, nil
+}
+
+// DeleteBridge removes an OVN logical switch (no manual bridge to delete)
+func (s *NetworkServer) DeleteBridge(ctx context.Context, req *pb.DeleteBridgeRequest) (*pb.DeleteBridgeResponse, error) {
+	log.Printf("DeleteBridge: networkID=%s", req.NetworkId)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Look up the VLAN tag before the switch is gone, so it can be released
+	// back to the pool once the delete succeeds.
+	vlanTag, vlanErr := s.nb.VLANTag(ctx, req.NetworkId)
+
+	// Delete the logical switch and its peer router in one transaction; the
+	// switch delete cascades to its ports, DHCP options and DNS records.
+	if err := s.nb.DeleteLogicalSwitch(ctx, req.NetworkId); err != nil {
+		return &pb.DeleteBridgeResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to configure OVN logical switch: %v", err),
+			Error:   fmt.Sprintf("Failed to delete OVN logical switch: %v", err),
 		}, nil
 	}
 
-	// Configure OVN DHCP options for this network
-	log.Printf("Configuring OVN DHCP for network %s (subnet: %s, gateway: %s, VLAN: %d)", req.NetworkId, req.Subnet, req.Gateway, vlanTag)
+	// Remove metadata
+	delete(s.bridges, req.NetworkId)
+	if err := s.inv.DeleteNetwork(req.NetworkId); err != nil {
+		log.Printf("Warning: Failed to clear inventory for %s: %v", req.NetworkId, err)
+	}
+
+	if vlanErr != nil {
+		log.Printf("Warning: Could not determine VLAN tag for %s, pool entry left allocated: %v", req.NetworkId, vlanErr)
+	} else if err := s.vlans.Release(vlanTag); err != nil {
+		log.Printf("Warning: Failed to release VLAN tag %d for %s: %v", vlanTag, req.NetworkId, err)
+	}
 
-	// Generate a MAC address for the DHCP server (gateway) based on VLAN tag
-	// This ensures uniqueness across networks
-	serverMAC := fmt.Sprintf("00:00:00:00:%02x:%02x", (vlanTag>>8)&0xff, vlanTag&0xff)
+	log.Printf("Successfully deleted OVN logical switch %s", req.NetworkId)
 
-	// Create DHCP options using 'ovn-nbctl create' which returns the UUID directly
-	// Note: 'dhcp-options-create' does NOT return a UUID (known OVN limitation)
-	// Format: create dhcp_options cidr=SUBNET options="key1"="value1" "key2"="value2" ...
-	dhcpUUID, err := runCommandWithOutput("ovn-nbctl", "create", "dhcp_options",
-		fmt.Sprintf("cidr=%s", req.Subnet),
-		fmt.Sprintf(`options="lease_time"="3600" "router"="%s" "server_id"="%s" "server_mac"="%s" "dns_server"="{%s}"`,
-			req.Gateway, req.Gateway, serverMAC, req.Gateway))
+	return &pb.DeleteBridgeResponse{
+		Success: true,
+	}, nil
+}
 
-	if err != nil {
-		log.Printf("ERROR: Failed to create DHCP options for subnet %s: %v (output: %q)", req.Subnet, err, dhcpUUID)
-		// Continue anyway - DHCP is optional enhancement
-	} else {
-		// Trim whitespace from UUID
-		dhcpUUID = strings.TrimSpace(dhcpUUID)
-		log.Printf("Created DHCP options with UUID: %s", dhcpUUID)
-
-		// Validate UUID is not empty
-		if dhcpUUID == "" {
-			log.Printf("ERROR: DHCP UUID is empty! Command succeeded but returned no output.")
-		} else {
-			log.Printf("DHCP options configured successfully for network %s", req.NetworkId)
+// providerBridgeName returns the OVS bridge CreateProviderNetwork creates
+// for a provider network, e.g. "br-provider-uplink0".
+func providerBridgeName(name string) string {
+	return "br-provider-" + name
+}
 
-			// Store DHCP UUID in logical switch other_config for later use
-			if err := runCommand("ovn-nbctl", "set", "logical_switch", req.NetworkId,
-				fmt.Sprintf("other_config:dhcp_options=%s", dhcpUUID)); err != nil {
-				log.Printf("Warning: Failed to store DHCP UUID in logical switch: %v", err)
-			}
-		}
+// CreateProviderNetwork bridges an OVN logical switch to a physical VLAN on
+// the host - analogous to the ovn4nfv provider-network and kube-ovn external
+// gateway switch patterns. It wires up, in order: (1) an OVS bridge with
+// PhysicalInterface added as a VLAN-tagged port, (2) the host's
+// external-ids:ovn-bridge-mappings so ovn-controller knows which OVS bridge
+// serves this provider name, (3) an OVN logical switch (same shape as
+// CreateBridge) plus a localnet port and SNAT rule binding it to that
+// bridge. Containers can attach to a provider network the same way they
+// attach to any other - AttachContainer/AttachContainerInterfaces don't need
+// to know it has a physical uplink.
+func (s *NetworkServer) CreateProviderNetwork(ctx context.Context, req *pb.CreateProviderNetworkRequest) (*pb.CreateProviderNetworkResponse, error) {
+	log.Printf("CreateProviderNetwork: name=%s, physicalInterface=%s, vlanID=%d, subnet=%s, gateway=%s",
+		req.Name, req.PhysicalInterface, req.VlanId, req.Subnet, req.Gateway)
+
+	if err := validate.ID("name", req.Name); err != nil {
+		return &pb.CreateProviderNetworkResponse{Success: false, Error: err.Error()}, nil
 	}
 
-	// Create OVN logical router for this network
-	// This provides the gateway functionality (responds to pings, ARP, etc.)
-	routerName := fmt.Sprintf("router-%s", req.NetworkId)
-	log.Printf("Creating logical router %s for network %s", routerName, req.NetworkId)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if err := runCommand("ovn-nbctl", "lr-add", routerName); err != nil {
-		log.Printf("ERROR: Failed to create logical router: %v", err)
-		// Cleanup
-		_ = runCommand("ovn-nbctl", "ls-del", req.NetworkId)
-		return &pb.CreateBridgeResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to create logical router: %v", err),
+	if existing, ok := s.bridges[req.Name]; ok && existing.Provider != nil {
+		log.Printf("Provider network %s already exists (idempotent)", req.Name)
+		return &pb.CreateProviderNetworkResponse{
+			BridgeName: existing.Provider.OVSBridge,
+			Success:    true,
 		}, nil
 	}
 
-	// Create router port with gateway IP/MAC
-	// Format: lrp-{networkID} with gateway IP (e.g., 172.17.0.1/24)
-	routerPortName := fmt.Sprintf("lrp-%s", req.NetworkId)
-	routerMAC := fmt.Sprintf("00:00:00:00:%02x:%02x", (vlanTag>>8)&0xff, vlanTag&0xff)
-
-	if err := runCommand("ovn-nbctl", "lrp-add", routerName, routerPortName,
-		routerMAC, req.Gateway+"/"+strings.Split(req.Subnet, "/")[1]); err != nil {
-		log.Printf("ERROR: Failed to create router port: %v", err)
-		// Cleanup
-		_ = runCommand("ovn-nbctl", "lr-del", routerName)
-		_ = runCommand("ovn-nbctl", "ls-del", req.NetworkId)
-		return &pb.CreateBridgeResponse{
+	bridgeName := providerBridgeName(req.Name)
+	if err := runCommand("ovs-vsctl", "--may-exist", "add-br", bridgeName); err != nil {
+		return &pb.CreateProviderNetworkResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to create router port: %v", err),
+			Error:   fmt.Sprintf("Failed to create OVS bridge %s: %v", bridgeName, err),
 		}, nil
 	}
 
-	// Create switch port to connect to router
-	// Format: lsp-{networkID}-router
-	switchPortName := fmt.Sprintf("lsp-%s-router", req.NetworkId)
-
-	if err := runCommand("ovn-nbctl", "lsp-add", req.NetworkId, switchPortName); err != nil {
-		log.Printf("ERROR: Failed to create switch port for router: %v", err)
-		// Cleanup
-		_ = runCommand("ovn-nbctl", "lr-del", routerName)
-		_ = runCommand("ovn-nbctl", "ls-del", req.NetworkId)
-		return &pb.CreateBridgeResponse{
+	if err := runCommand("ovs-vsctl", "--may-exist", "add-port", bridgeName, req.PhysicalInterface,
+		fmt.Sprintf("tag=%d", req.VlanId)); err != nil {
+		return &pb.CreateProviderNetworkResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to create switch port: %v", err),
+			Error:   fmt.Sprintf("Failed to add %s to bridge %s: %v", req.PhysicalInterface, bridgeName, err),
 		}, nil
 	}
 
-	// Set switch port type to router and connect to router port
-	if err := runCommand("ovn-nbctl", "lsp-set-type", switchPortName, "router"); err != nil {
-		log.Printf("ERROR: Failed to set switch port type: %v", err)
-		// Cleanup
-		_ = runCommand("ovn-nbctl", "lr-del", routerName)
-		_ = runCommand("ovn-nbctl", "ls-del", req.NetworkId)
-		return &pb.CreateBridgeResponse{
+	if err := addBridgeMapping(req.Name, bridgeName); err != nil {
+		return &pb.CreateProviderNetworkResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to set switch port type: %v", err),
+			Error:   fmt.Sprintf("Failed to set ovn-bridge-mappings: %v", err),
 		}, nil
 	}
 
-	if err := runCommand("ovn-nbctl", "lsp-set-addresses", switchPortName, "router"); err != nil {
-		log.Printf("ERROR: Failed to set switch port addresses: %v", err)
-		// Cleanup
-		_ = runCommand("ovn-nbctl", "lr-del", routerName)
-		_ = runCommand("ovn-nbctl", "ls-del", req.NetworkId)
-		return &pb.CreateBridgeResponse{
+	// Reserve req.VlanId in the pool so CreateBridge never hands out the same
+	// tag to an unrelated network - a provider network's VLAN ID is chosen by
+	// the physical uplink, not the pool, but it still has to come out of the
+	// same namespace.
+	if err := s.vlans.Reserve(req.VlanId, req.Name); err != nil {
+		return &pb.CreateProviderNetworkResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to set switch port addresses: %v", err),
+			Error:   fmt.Sprintf("Failed to reserve VLAN tag %d: %v", req.VlanId, err),
 		}, nil
 	}
 
-	if err := runCommand("ovn-nbctl", "lsp-set-options", switchPortName,
-		fmt.Sprintf("router-port=%s", routerPortName)); err != nil {
-		log.Printf("ERROR: Failed to connect switch port to router: %v", err)
-		// Cleanup
-		_ = runCommand("ovn-nbctl", "lr-del", routerName)
-		_ = runCommand("ovn-nbctl", "ls-del", req.NetworkId)
-		return &pb.CreateBridgeResponse{
+	if err := s.nb.CreateLogicalSwitch(ctx, req.Name, req.VlanId, req.Subnet, req.Gateway); err != nil {
+		return &pb.CreateProviderNetworkResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to connect switch to router: %v", err),
+			Error:   fmt.Sprintf("Failed to create OVN logical switch: %v", err),
 		}, nil
 	}
 
-	log.Printf("Successfully created and connected logical router %s", routerName)
+	handles, err := s.nb.CreateProviderUplink(ctx, req.Name, req.Name, req.Gateway)
+	if err != nil {
+		return &pb.CreateProviderNetworkResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to bind provider uplink: %v", err),
+		}, nil
+	}
 
-	// Store metadata (still tracking for compatibility, but bridgeName is always br-int now)
-	s.bridges[req.NetworkId] = &BridgeMetadata{
-		NetworkID:  req.NetworkId,
-		BridgeName: "br-int", // All networks use br-int with VLAN tags
+	s.bridges[req.Name] = &BridgeMetadata{
+		NetworkID:  req.Name,
+		BridgeName: "br-int",
 		Subnet:     req.Subnet,
 		Gateway:    req.Gateway,
+		Provider: &ProviderBinding{
+			PhysicalInterface: req.PhysicalInterface,
+			VLANID:            req.VlanId,
+			OVSBridge:         bridgeName,
+			handles:           handles,
+		},
 	}
-	s.containerMap[req.NetworkId] = make(map[string]bool)
 
-	log.Printf("Successfully created OVN logical switch %s with VLAN tag %d", req.NetworkId, vlanTag)
+	log.Printf("Successfully created provider network %s (bridge=%s, uplink=%s, vlan=%d)",
+		req.Name, bridgeName, req.PhysicalInterface, req.VlanId)
 
-	return &pb.CreateBridgeResponse{
-		BridgeName: "br-int", // All networks use br-int now
+	return &pb.CreateProviderNetworkResponse{
+		BridgeName: bridgeName,
 		Success:    true,
 	}, nil
 }
 
-// DeleteBridge removes an OVN logical switch (no manual bridge to delete)
-func (s *NetworkServer) DeleteBridge(ctx context.Context, req *pb.DeleteBridgeRequest) (*pb.DeleteBridgeResponse, error) {
-	log.Printf("DeleteBridge: networkID=%s", req.NetworkId)
+// DeleteProviderNetwork reverses CreateProviderNetwork: the localnet port
+// and SNAT rule, the logical switch/router, the ovn-bridge-mappings entry
+// and finally the OVS bridge itself.
+func (s *NetworkServer) DeleteProviderNetwork(ctx context.Context, req *pb.DeleteProviderNetworkRequest) (*pb.DeleteProviderNetworkResponse, error) {
+	log.Printf("DeleteProviderNetwork: name=%s", req.Name)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Delete OVN logical router (if exists)
-	routerName := fmt.Sprintf("router-%s", req.NetworkId)
-	if err := runCommand("ovn-nbctl", "--if-exists", "lr-del", routerName); err != nil {
-		log.Printf("Warning: Failed to delete logical router %s: %v", routerName, err)
-		// Continue anyway - switch deletion will cascade
-	}
-
-	// Delete OVN logical switch (this removes all ports, DHCP, etc.)
-	if err := runCommand("ovn-nbctl", "ls-del", req.NetworkId); err != nil {
-		return &pb.DeleteBridgeResponse{
+	metadata, ok := s.bridges[req.Name]
+	if !ok || metadata.Provider == nil {
+		return &pb.DeleteProviderNetworkResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to delete OVN logical switch: %v", err),
+			Error:   fmt.Sprintf("%s is not a provider network", req.Name),
 		}, nil
 	}
 
-	// Remove metadata
-	delete(s.bridges, req.NetworkId)
-	delete(s.containerMap, req.NetworkId)
+	if err := s.nb.DeleteProviderUplink(ctx, req.Name, metadata.Provider.handles); err != nil {
+		log.Printf("Warning: Failed to delete provider uplink for %s: %v", req.Name, err)
+	}
 
-	// Note: We don't reclaim VLAN tags (nextVLAN counter keeps growing)
-	// This is fine - 3996 VLANs should be enough for any single daemon lifetime
-	// If we need to reclaim, would need to track allocated VLANs in a set
+	if err := s.nb.DeleteLogicalSwitch(ctx, req.Name); err != nil {
+		log.Printf("Warning: Failed to delete OVN logical switch %s: %v", req.Name, err)
+	}
 
-	log.Printf("Successfully deleted OVN logical switch %s", req.NetworkId)
+	if err := removeBridgeMapping(req.Name); err != nil {
+		log.Printf("Warning: Failed to remove ovn-bridge-mappings entry for %s: %v", req.Name, err)
+	}
 
-	return &pb.DeleteBridgeResponse{
-		Success: true,
+	if err := s.vlans.Release(metadata.Provider.VLANID); err != nil {
+		log.Printf("Warning: Failed to release VLAN tag %d for %s: %v", metadata.Provider.VLANID, req.Name, err)
+	}
+
+	if err := runCommand("ovs-vsctl", "--if-exists", "del-br", metadata.Provider.OVSBridge); err != nil {
+		log.Printf("Warning: Failed to delete OVS bridge %s: %v", metadata.Provider.OVSBridge, err)
+	}
+
+	delete(s.bridges, req.Name)
+	if err := s.inv.DeleteNetwork(req.Name); err != nil {
+		log.Printf("Warning: Failed to clear inventory for %s: %v", req.Name, err)
+	}
+
+	log.Printf("Successfully deleted provider network %s", req.Name)
+
+	return &pb.DeleteProviderNetworkResponse{Success: true}, nil
+}
+
+// ListProviderNetworks returns every network that has a physical VLAN
+// uplink (i.e. was created via CreateProviderNetwork).
+func (s *NetworkServer) ListProviderNetworks(ctx context.Context, req *pb.ListProviderNetworksRequest) (*pb.ListProviderNetworksResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var networks []*pb.ProviderNetworkInfo
+	for name, metadata := range s.bridges {
+		if metadata.Provider == nil {
+			continue
+		}
+		networks = append(networks, &pb.ProviderNetworkInfo{
+			Name:              name,
+			PhysicalInterface: metadata.Provider.PhysicalInterface,
+			VlanId:            metadata.Provider.VLANID,
+			BridgeName:        metadata.Provider.OVSBridge,
+			Subnet:            metadata.Subnet,
+			Gateway:           metadata.Gateway,
+		})
+	}
+
+	return &pb.ListProviderNetworksResponse{
+		Networks: networks,
+		Success:  true,
 	}, nil
 }
 
+// addBridgeMapping merges name:bridgeName into the host's
+// external-ids:ovn-bridge-mappings on the OVS database, preserving any
+// other provider networks' entries already set there.
+func addBridgeMapping(name, bridgeName string) error {
+	mappings, err := readBridgeMappings()
+	if err != nil {
+		return err
+	}
+	mappings[name] = bridgeName
+	return writeBridgeMappings(mappings)
+}
+
+// removeBridgeMapping drops name's entry from ovn-bridge-mappings, leaving
+// every other provider network's mapping untouched.
+func removeBridgeMapping(name string) error {
+	mappings, err := readBridgeMappings()
+	if err != nil {
+		return err
+	}
+	delete(mappings, name)
+	return writeBridgeMappings(mappings)
+}
+
+func readBridgeMappings() (map[string]string, error) {
+	output, err := runCommandWithOutput("ovs-vsctl", "get", "Open_vSwitch", ".", "external_ids:ovn-bridge-mappings")
+	mappings := make(map[string]string)
+	if err != nil {
+		// Key not set yet - nothing mapped, not an error.
+		return mappings, nil
+	}
+	output = strings.Trim(strings.TrimSpace(output), `"`)
+	if output == "" || output == "[]" {
+		return mappings, nil
+	}
+	for _, pair := range strings.Split(output, ",") {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) == 2 {
+			mappings[parts[0]] = parts[1]
+		}
+	}
+	return mappings, nil
+}
+
+func writeBridgeMappings(mappings map[string]string) error {
+	if len(mappings) == 0 {
+		return runCommand("ovs-vsctl", "remove", "Open_vSwitch", ".", "external_ids", "ovn-bridge-mappings")
+	}
+	pairs := make([]string, 0, len(mappings))
+	for name, bridgeName := range mappings {
+		pairs = append(pairs, name+":"+bridgeName)
+	}
+	sort.Strings(pairs)
+	return runCommand("ovs-vsctl", "set", "Open_vSwitch", ".", fmt.Sprintf("external_ids:ovn-bridge-mappings=%s", strings.Join(pairs, ",")))
+}
+
 // AttachContainer attaches a container to a network
 func (s *NetworkServer) AttachContainer(ctx context.Context, req *pb.AttachContainerRequest) (*pb.AttachContainerResponse, error) {
 	log.Printf("AttachContainer: containerID=%s, networkID=%s, ip=%s, mac=%s",
 		req.ContainerId, req.NetworkId, req.IpAddress, req.MacAddress)
 
+	if err := validate.ID("container_id", req.ContainerId); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := validate.ID("network_id", req.NetworkId); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if ready, err := s.nb.SwitchReady(ctx, req.NetworkId); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "checking readiness of network %s: %v", req.NetworkId, err)
+	} else if !ready {
+		return nil, status.Errorf(codes.FailedPrecondition, "network %s is not yet ready (router/router port/DHCP options still converging)", req.NetworkId)
+	}
+
 	// Get VLAN tag from OVN logical switch
-	vlanStr, err := runCommandWithOutput("ovn-nbctl", "get", "logical_switch", req.NetworkId, "external_ids:vlan_tag")
+	vlanTag, err := s.nb.VLANTag(ctx, req.NetworkId)
 	if err != nil {
 		return &pb.AttachContainerResponse{
 			Success: false,
 			Error:   fmt.Sprintf("Failed to get VLAN tag for network %s: %v", req.NetworkId, err),
 		}, nil
 	}
-	vlanStr = strings.Trim(strings.TrimSpace(vlanStr), "\"")
-
-	// Parse VLAN tag as uint32
-	var vlanTag uint32
-	if _, err := fmt.Sscanf(vlanStr, "%d", &vlanTag); err != nil {
-		return &pb.AttachContainerResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Invalid VLAN tag '%s' for network %s: %v", vlanStr, req.NetworkId, err),
-		}, nil
-	}
 
 	log.Printf("Network %s uses VLAN tag %d", req.NetworkId, vlanTag)
 
@@ -328,142 +539,23 @@ func (s *NetworkServer) AttachContainer(ctx context.Context, req *pb.AttachConta
 	// OVS internal ports become Linux interfaces, so we must keep names <= 15 chars
 	portName := fmt.Sprintf("p-%s%s", req.ContainerId[:6], req.NetworkId[:6])
 
-	// Create OVN logical switch port for DHCP/DNS
+	// Create the logical switch port and (for dynamic allocation) block until
+	// northd has populated dynamic_addresses - via the client's monitored
+	// cache, not a fixed poll loop - all in one call.
 	log.Printf("Creating OVN logical switch port %s on network %s", portName, req.NetworkId)
-	if err := runCommand("ovn-nbctl", "lsp-add", req.NetworkId, portName); err != nil {
-		log.Printf("Warning: Failed to create logical switch port: %v", err)
-		// Continue anyway - port may already exist
-	}
-
-	// Configure port addresses (MAC + IP)
-	var portAddress string
-	var allocatedIP string
-
-	if req.IpAddress == "" {
-		// Dynamic DHCP allocation
-		// Format: "MAC dynamic" - OVN will allocate IP and populate dynamic_addresses
-		// Note: OVN does NOT support "MAC dynamic hostname" syntax - hostname must be set separately
-		portAddress = fmt.Sprintf("%s dynamic", req.MacAddress)
-		if req.Hostname != "" {
-			log.Printf("Configuring port %s for dynamic DHCP with hostname %s (MAC: %s)", portName, req.Hostname, req.MacAddress)
-		} else {
-			log.Printf("Configuring port %s for dynamic DHCP (MAC: %s)", portName, req.MacAddress)
-		}
-
-		// Set port addresses - this triggers OVN to allocate an IP
-		if err := runCommand("ovn-nbctl", "lsp-set-addresses", portName, portAddress); err != nil {
-			log.Printf("Warning: Failed to set port addresses: %v", err)
-		}
-
-		// Set port security to allow packets from this MAC
-		// Without port security, OVN drops all packets from the port
-		if err := runCommand("ovn-nbctl", "lsp-set-port-security", portName, portAddress); err != nil {
-			log.Printf("Warning: Failed to set port security: %v", err)
-		}
-
-		// Link DHCP options to this port BEFORE querying for allocated IP
-		// OVN requires DHCP options to be linked before it will allocate an IP
-		dhcpUUID, err := runCommandWithOutput("ovn-nbctl", "get", "logical_switch", req.NetworkId, "other_config:dhcp_options")
-		if err == nil && dhcpUUID != "" {
-			dhcpUUID = strings.Trim(strings.TrimSpace(dhcpUUID), "\"")
-			log.Printf("Linking DHCP options %s to port %s", dhcpUUID, portName)
-			if err := runCommand("ovn-nbctl", "lsp-set-dhcpv4-options", portName, dhcpUUID); err != nil {
-				log.Printf("Warning: Failed to link DHCP options: %v", err)
-			}
-		} else {
-			log.Printf("Warning: No DHCP options found for network %s", req.NetworkId)
-		}
-
-		// Query the dynamically allocated IP from OVN with retry
-		// OVN stores it in the port's dynamic_addresses field
-		// ovn-northd may need a moment to process the allocation
-		var dynamicAddr string
-		for i := 0; i < 5; i++ {
-			time.Sleep(100 * time.Millisecond) // Wait for ovn-northd to process
-
-			dynamicAddr, err = runCommandWithOutput("ovn-nbctl", "get", "logical_switch_port", portName, "dynamic_addresses")
-			if err == nil && dynamicAddr != "" {
-				// Parse dynamic_addresses: "MAC IP"
-				dynamicAddr = strings.Trim(strings.TrimSpace(dynamicAddr), "\"")
-				parts := strings.Fields(dynamicAddr)
-				if len(parts) >= 2 {
-					allocatedIP = parts[1]
-					log.Printf("OVN allocated IP %s for port %s (attempt %d)", allocatedIP, portName, i+1)
-
-					// CRITICAL: Update port_security with the actual allocated IP
-					// OVN doesn't support "dynamic" keyword in port_security - it needs the real IP
-					actualPortSecurity := fmt.Sprintf("%s %s", parts[0], parts[1])
-					if err := runCommand("ovn-nbctl", "lsp-set-port-security", portName, actualPortSecurity); err != nil {
-						log.Printf("Warning: Failed to update port security with allocated IP: %v", err)
-					} else {
-						log.Printf("Updated port security to: %s", actualPortSecurity)
-					}
-
-					break
-				}
-			}
-			if i < 4 {
-				log.Printf("Waiting for OVN to allocate IP (attempt %d/5)", i+1)
-			}
-		}
-
-		if allocatedIP == "" {
-			log.Printf("Warning: Could not retrieve dynamically allocated IP for port %s after 5 attempts", portName)
-
-			// Diagnostic: dump OVN state to understand why allocation failed
-			log.Printf("=== DHCP Allocation Failure Diagnostics ===")
-
-			// Check logical switch configuration
-			lsConfig, err := runCommandWithOutput("ovn-nbctl", "list", "logical_switch", req.NetworkId)
-			if err == nil {
-				log.Printf("Logical switch %s config:\n%s", req.NetworkId, lsConfig)
-			} else {
-				log.Printf("Failed to get logical switch config: %v", err)
-			}
-
-			// Check logical switch port configuration
-			portConfig, err := runCommandWithOutput("ovn-nbctl", "list", "logical_switch_port", portName)
-			if err == nil {
-				log.Printf("Logical switch port %s config:\n%s", portName, portConfig)
-			} else {
-				log.Printf("Failed to get port config: %v", err)
-			}
-
-			// Check DHCP options
-			dhcpList, err := runCommandWithOutput("ovn-nbctl", "list", "dhcp_options")
-			if err == nil {
-				log.Printf("All DHCP options:\n%s", dhcpList)
-			} else {
-				log.Printf("Failed to list DHCP options: %v", err)
-			}
-
-			// Check ovn-northd logs for allocation errors
-			northdLogs, err := runCommandWithOutput("tail", "-50", "/var/log/ovn/ovn-northd.log")
-			if err == nil {
-				log.Printf("ovn-northd recent logs:\n%s", northdLogs)
-			} else {
-				log.Printf("Failed to read ovn-northd logs: %v", err)
-			}
-
-			log.Printf("=== End Diagnostics ===")
-		}
-	} else {
-		// Static IP reservation
-		portAddress = fmt.Sprintf("%s %s", req.MacAddress, req.IpAddress)
-		allocatedIP = req.IpAddress
-		log.Printf("Configuring port %s with static IP %s (MAC: %s)", portName, req.IpAddress, req.MacAddress)
-
-		if err := runCommand("ovn-nbctl", "lsp-set-addresses", portName, portAddress); err != nil {
-			log.Printf("Warning: Failed to set port addresses: %v", err)
-		}
-
-		// Set port security to allow packets from this MAC/IP
-		// Without port security, OVN drops all packets from the port
-		if err := runCommand("ovn-nbctl", "lsp-set-port-security", portName, portAddress); err != nil {
-			log.Printf("Warning: Failed to set port security: %v", err)
-		}
+	allocatedIP, err := s.nb.CreateLogicalSwitchPort(ctx, req.NetworkId, portName, req.MacAddress, req.IpAddress, req.ContainerId)
+	if err != nil {
+		return &pb.AttachContainerResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to create logical switch port: %v", err),
+		}, nil
+	}
+	log.Printf("Port %s configured with IP %s (MAC: %s)", portName, allocatedIP, req.MacAddress)
 
-		// Note: For static IPs, we don't link DHCP options since the IP is already configured
+	// Add the port to the network's policy group, if one exists, so any
+	// network policies already set on this network start applying to it.
+	if err := s.nb.AddPortToPolicyGroup(ctx, req.NetworkId, portName); err != nil {
+		log.Printf("Warning: Failed to add port %s to network policy group: %v", portName, err)
 	}
 
 	// DNS resolution is handled by embedded-DNS in each container
@@ -471,10 +563,9 @@ func (s *NetworkServer) AttachContainer(ctx context.Context, req *pb.AttachConta
 	// DNS topology is pushed from Arca daemon directly to containers via tap-forwarder
 
 	// Track container attachment
-	if s.containerMap[req.NetworkId] == nil {
-		s.containerMap[req.NetworkId] = make(map[string]bool)
+	if err := s.inv.Attach(req.NetworkId, req.ContainerId); err != nil {
+		log.Printf("Warning: Failed to record attachment of %s to %s in inventory: %v", req.ContainerId, req.NetworkId, err)
 	}
-	s.containerMap[req.NetworkId][req.ContainerId] = true
 
 	// Start TAP relay for packet forwarding (if vsock port provided)
 	if req.VsockPort > 0 {
@@ -499,13 +590,261 @@ func (s *NetworkServer) AttachContainer(ctx context.Context, req *pb.AttachConta
 	}, nil
 }
 
-// DetachContainer detaches a container from a network
+// AttachContainerInterfaces provisions every interface in req.Interfaces for
+// a container as a single OVN transaction per port, instead of making the
+// caller issue one AttachContainer round-trip per network. It borrows the
+// ovn4nfv AddLogicalPorts shape: one request, one response listing every
+// interface's allocated IP, port name and gateway. At most one interface may
+// set DefaultGateway; if none do, the first interface in the list is treated
+// as the default-route provider.
+func (s *NetworkServer) AttachContainerInterfaces(ctx context.Context, req *pb.AttachContainerInterfacesRequest) (*pb.AttachContainerInterfacesResponse, error) {
+	log.Printf("AttachContainerInterfaces: containerID=%s, interfaces=%d", req.ContainerId, len(req.Interfaces))
+
+	if err := validate.ID("container_id", req.ContainerId); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, spec := range req.Interfaces {
+		if err := validate.ID("network_id", spec.NetworkId); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defaultIdx, err := defaultGatewayIndex(req.Interfaces)
+	if err != nil {
+		return &pb.AttachContainerInterfacesResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	attached := make([]*pb.AttachedInterface, 0, len(req.Interfaces))
+	states := make([]*attachedInterfaceState, 0, len(req.Interfaces))
+
+	for i, spec := range req.Interfaces {
+		if ready, err := s.nb.SwitchReady(ctx, spec.NetworkId); err != nil {
+			s.rollbackInterfaces(ctx, req.ContainerId, states)
+			return nil, status.Errorf(codes.FailedPrecondition, "checking readiness of network %s: %v", spec.NetworkId, err)
+		} else if !ready {
+			s.rollbackInterfaces(ctx, req.ContainerId, states)
+			return nil, status.Errorf(codes.FailedPrecondition, "network %s is not yet ready (router/router port/DHCP options still converging)", spec.NetworkId)
+		}
+
+		vlanTag, err := s.nb.VLANTag(ctx, spec.NetworkId)
+		if err != nil {
+			s.rollbackInterfaces(ctx, req.ContainerId, states)
+			return &pb.AttachContainerInterfacesResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to get VLAN tag for network %s: %v", spec.NetworkId, err),
+			}, nil
+		}
+
+		macAddress := spec.MacAddress
+		if macAddress == "" {
+			macAddress = deterministicMAC(req.ContainerId, spec.InterfaceName)
+		}
+
+		portName := attachPortName(req.ContainerId, spec.NetworkId, spec.InterfaceName)
+
+		log.Printf("Creating OVN logical switch port %s on network %s for interface %s", portName, spec.NetworkId, spec.InterfaceName)
+		allocatedIP, err := s.nb.CreateLogicalSwitchPort(ctx, spec.NetworkId, portName, macAddress, spec.IpAddress, req.ContainerId)
+		if err != nil {
+			s.rollbackInterfaces(ctx, req.ContainerId, states)
+			return &pb.AttachContainerInterfacesResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to create logical switch port for network %s: %v", spec.NetworkId, err),
+			}, nil
+		}
+
+		if err := s.nb.AddPortToPolicyGroup(ctx, spec.NetworkId, portName); err != nil {
+			log.Printf("Warning: Failed to add port %s to network policy group: %v", portName, err)
+		}
+
+		if err := s.inv.Attach(spec.NetworkId, req.ContainerId); err != nil {
+			log.Printf("Warning: Failed to record attachment of %s to %s in inventory: %v", req.ContainerId, spec.NetworkId, err)
+		}
+
+		var gateway string
+		if bridge, ok := s.bridges[spec.NetworkId]; ok {
+			gateway = bridge.Gateway
+		}
+		isDefault := i == defaultIdx
+
+		var helperPort uint32
+		if req.VsockPort > 0 {
+			// Helper VM listens on host_port + 10000; offset by index so each
+			// interface of a multi-network attach gets its own relay port.
+			helperPort = req.VsockPort + 10000 + uint32(i)
+			if err := s.relayManager.StartRelay(helperPort, vlanTag, spec.NetworkId, req.ContainerId, macAddress, portName); err != nil {
+				log.Printf("Warning: Failed to start TAP relay for interface %s: %v", spec.InterfaceName, err)
+				helperPort = 0
+			} else {
+				log.Printf("Started TAP relay on helper VM port %d for container %s interface %s (VLAN: %d)", helperPort, req.ContainerId, spec.InterfaceName, vlanTag)
+				if len(spec.Routes) > 0 {
+					routes := make([]StaticRoute, 0, len(spec.Routes))
+					for _, r := range spec.Routes {
+						routes = append(routes, StaticRoute{Destination: r.Destination, Gateway: r.Gateway})
+					}
+					if err := s.relayManager.PushRoutes(helperPort, routes); err != nil {
+						log.Printf("Warning: Failed to push static routes for interface %s: %v", spec.InterfaceName, err)
+					}
+				}
+			}
+		}
+
+		states = append(states, &attachedInterfaceState{
+			networkID:      spec.NetworkId,
+			interfaceName:  spec.InterfaceName,
+			portName:       portName,
+			macAddress:     macAddress,
+			defaultGateway: isDefault,
+			helperPort:     helperPort,
+		})
+
+		attached = append(attached, &pb.AttachedInterface{
+			NetworkId:      spec.NetworkId,
+			InterfaceName:  spec.InterfaceName,
+			PortName:       portName,
+			IpAddress:      allocatedIP,
+			MacAddress:     macAddress,
+			Mtu:            defaultInterfaceMTU,
+			Gateway:        gateway,
+			DefaultGateway: isDefault,
+		})
+
+		log.Printf("Attached interface %s (network %s) for container %s: IP=%s MAC=%s default=%v",
+			spec.InterfaceName, spec.NetworkId, req.ContainerId, allocatedIP, macAddress, isDefault)
+	}
+
+	s.containerInterfaces[req.ContainerId] = append(s.containerInterfaces[req.ContainerId], states...)
+
+	return &pb.AttachContainerInterfacesResponse{
+		Interfaces: attached,
+		Success:    true,
+	}, nil
+}
+
+// defaultGatewayIndex validates that at most one interface spec sets
+// DefaultGateway, returning its index. If none do, the first interface (if
+// any) is chosen as the default-route provider.
+func defaultGatewayIndex(interfaces []*pb.InterfaceSpec) (int, error) {
+	idx := -1
+	for i, spec := range interfaces {
+		if spec.DefaultGateway {
+			if idx != -1 {
+				return -1, fmt.Errorf("at most one interface may set default_gateway=true")
+			}
+			idx = i
+		}
+	}
+	if idx == -1 && len(interfaces) > 0 {
+		idx = 0
+	}
+	return idx, nil
+}
+
+// attachPortName derives the OVS/OVN port name for one interface of a
+// container attach. Linux interface names are capped at 15 characters
+// (IFNAMSIZ=16 including the null terminator), so a multi-interface attach
+// can't concatenate containerID+networkID+interfaceName the way the original
+// single-network AttachContainer did - the network/interface pair is folded
+// into a short hash instead so each interface still gets a distinct name.
+func attachPortName(containerID, networkID, interfaceName string) string {
+	h := md5.Sum([]byte(networkID + "/" + interfaceName))
+	return fmt.Sprintf("p-%s%x", containerID[:6], h[:3])
+}
+
+// deterministicMAC derives a locally-administered unicast MAC address from
+// containerID+interfaceName, so an InterfaceSpec that omits MacAddress still
+// gets a stable address across retries instead of a random one.
+func deterministicMAC(containerID, interfaceName string) string {
+	h := md5.Sum([]byte(containerID + "/" + interfaceName))
+	h[0] = (h[0] &^ 0x01) | 0x02 // clear multicast bit, set locally-administered bit
+	return fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x", h[0], h[1], h[2], h[3], h[4], h[5])
+}
+
+// rollbackInterfaces tears down every interface already provisioned in a
+// partially-failed AttachContainerInterfaces call, so callers never observe
+// a container attached to some but not all of its requested networks.
+func (s *NetworkServer) rollbackInterfaces(ctx context.Context, containerID string, states []*attachedInterfaceState) {
+	for _, st := range states {
+		s.teardownInterface(ctx, containerID, st)
+	}
+}
+
+// teardownInterface releases everything AttachContainerInterfaces provisioned
+// for one interface: its TAP relay, OVN logical switch port, policy group
+// membership and OVS port. Callers must hold s.mu.
+func (s *NetworkServer) teardownInterface(ctx context.Context, containerID string, st *attachedInterfaceState) {
+	if st.helperPort != 0 {
+		if err := s.relayManager.StopRelay(st.helperPort); err != nil {
+			log.Printf("Warning: Failed to stop TAP relay on port %d: %v", st.helperPort, err)
+		}
+	}
+
+	if err := s.nb.RemovePortFromPolicyGroup(ctx, st.networkID, st.portName); err != nil {
+		log.Printf("Warning: Failed to remove port %s from network policy group: %v", st.portName, err)
+	}
+
+	if err := s.nb.DeleteLogicalSwitchPort(ctx, st.portName); err != nil {
+		log.Printf("Warning: Failed to delete logical switch port %s: %v", st.portName, err)
+	}
+
+	if err := deleteOVSPort("br-int", st.portName); err != nil {
+		log.Printf("Warning: Failed to delete OVS port %s: %v (may have already been cleaned up by relay)", st.portName, err)
+	}
+
+	if err := s.inv.Detach(st.networkID, containerID); err != nil {
+		log.Printf("Warning: Failed to clear inventory for %s/%s: %v", st.networkID, containerID, err)
+	}
+}
+
+// DetachContainer detaches a container from a network. If req.NetworkId is
+// empty, every interface AttachContainerInterfaces provisioned for the
+// container (across all networks) is torn down instead of just one.
 func (s *NetworkServer) DetachContainer(ctx context.Context, req *pb.DetachContainerRequest) (*pb.DetachContainerResponse, error) {
 	log.Printf("DetachContainer: containerID=%s, networkID=%s", req.ContainerId, req.NetworkId)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if req.NetworkId == "" {
+		states := s.containerInterfaces[req.ContainerId]
+		for _, st := range states {
+			s.teardownInterface(ctx, req.ContainerId, st)
+		}
+		delete(s.containerInterfaces, req.ContainerId)
+		log.Printf("Successfully detached all %d interface(s) for container %s", len(states), req.ContainerId)
+		return &pb.DetachContainerResponse{Success: true}, nil
+	}
+
+	// Below this point ContainerId and NetworkId get sliced to derive OVN/OVS
+	// port names, so both must meet the minimum length those slices assume.
+	if err := validate.ID("container_id", req.ContainerId); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := validate.ID("network_id", req.NetworkId); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Drop any AttachContainerInterfaces bookkeeping for this network so a
+	// later no-networkID DetachContainer doesn't try to tear it down again.
+	if ifaces, ok := s.containerInterfaces[req.ContainerId]; ok {
+		remaining := ifaces[:0]
+		for _, st := range ifaces {
+			if st.networkID != req.NetworkId {
+				remaining = append(remaining, st)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(s.containerInterfaces, req.ContainerId)
+		} else {
+			s.containerInterfaces[req.ContainerId] = remaining
+		}
+	}
+
 	// Stop TAP relay if one was started for this container
 	if helperPort, exists := s.containerPort[req.ContainerId]; exists {
 		if err := s.relayManager.StopRelay(helperPort); err != nil {
@@ -516,10 +855,16 @@ func (s *NetworkServer) DetachContainer(ctx context.Context, req *pb.DetachConta
 		delete(s.containerPort, req.ContainerId)
 	}
 
-	// Remove OVN logical switch port (this also removes DHCP lease and DNS records)
+	// Remove the port from the network's policy group before deleting it, so
+	// there's no window where a deleted port's UUID lingers in a Port_Group.
 	ovnPortName := fmt.Sprintf("lsp-%s", req.ContainerId[:12])
+	if err := s.nb.RemovePortFromPolicyGroup(ctx, req.NetworkId, ovnPortName); err != nil {
+		log.Printf("Warning: Failed to remove port %s from network policy group: %v", ovnPortName, err)
+	}
+
+	// Remove OVN logical switch port (this also removes DHCP lease and DNS records)
 	log.Printf("Removing OVN logical switch port %s from network %s", ovnPortName, req.NetworkId)
-	if err := runCommand("ovn-nbctl", "lsp-del", ovnPortName); err != nil {
+	if err := s.nb.DeleteLogicalSwitchPort(ctx, ovnPortName); err != nil {
 		log.Printf("Warning: Failed to delete logical switch port: %v", err)
 		// Continue anyway - port may not exist
 	}
@@ -540,8 +885,8 @@ func (s *NetworkServer) DetachContainer(ctx context.Context, req *pb.DetachConta
 	}
 
 	// Update tracking
-	if s.containerMap[req.NetworkId] != nil {
-		delete(s.containerMap[req.NetworkId], req.ContainerId)
+	if err := s.inv.Detach(req.NetworkId, req.ContainerId); err != nil {
+		log.Printf("Warning: Failed to clear inventory for %s/%s: %v", req.NetworkId, req.ContainerId, err)
 	}
 
 	log.Printf("Successfully detached container %s from network %s", req.ContainerId, req.NetworkId)
@@ -558,20 +903,27 @@ func (s *NetworkServer) ListBridges(ctx context.Context, req *pb.ListBridgesRequ
 
 	var bridges []*pb.BridgeInfo
 	for networkID, metadata := range s.bridges {
-		containers := make([]string, 0)
-		if containerMap := s.containerMap[networkID]; containerMap != nil {
-			for containerID := range containerMap {
-				containers = append(containers, containerID)
-			}
+		containers, err := s.inv.ContainersOn(networkID)
+		if err != nil {
+			log.Printf("Warning: Failed to read inventory for %s: %v", networkID, err)
+			containers = nil
 		}
 
-		bridges = append(bridges, &pb.BridgeInfo{
+		info := &pb.BridgeInfo{
 			NetworkId:  networkID,
 			BridgeName: fmt.Sprintf("arca-br-%s", networkID[:12]),
 			Subnet:     metadata.Subnet,
 			Gateway:    metadata.Gateway,
 			Containers: containers,
-		})
+		}
+		if metadata.Provider != nil {
+			info.Provider = &pb.ProviderBinding{
+				PhysicalInterface: metadata.Provider.PhysicalInterface,
+				VlanId:            metadata.Provider.VLANID,
+				BridgeName:        metadata.Provider.OVSBridge,
+			}
+		}
+		bridges = append(bridges, info)
 	}
 
 	return &pb.ListBridgesResponse{
@@ -580,151 +932,120 @@ func (s *NetworkServer) ListBridges(ctx context.Context, req *pb.ListBridgesRequ
 	}, nil
 }
 
-// SetNetworkPolicy sets network policies
+// SetNetworkPolicy compiles req.Rules into OVN ACLs attached to the
+// network's policy Port_Group, with one Address_Set per rule's CIDR peers.
+// A second call with the same PolicyId atomically replaces the ACLs and
+// address sets the first call created.
 func (s *NetworkServer) SetNetworkPolicy(ctx context.Context, req *pb.SetNetworkPolicyRequest) (*pb.SetNetworkPolicyResponse, error) {
-	log.Printf("SetNetworkPolicy: networkID=%s, rules=%d", req.NetworkId, len(req.Rules))
+	log.Printf("SetNetworkPolicy: networkID=%s, policyID=%s, rules=%d", req.NetworkId, req.PolicyId, len(req.Rules))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := make([]ovn.PolicyRule, 0, len(req.Rules))
+	for _, r := range req.Rules {
+		rules = append(rules, ovn.PolicyRule{
+			Direction: strings.ToLower(r.Direction),
+			Action:    strings.ToLower(r.Action),
+			Protocol:  strings.ToLower(r.Protocol),
+			PortMin:   r.PortMin,
+			PortMax:   r.PortMax,
+			PeerCIDRs: r.PeerCidrs,
+		})
+	}
+
+	var prev *ovn.PolicyHandles
+	if existing, ok := s.policies[req.PolicyId]; ok {
+		prev = existing.handles
+	}
+
+	handles, err := s.nb.ApplyNetworkPolicy(ctx, req.NetworkId, req.PolicyId, rules, prev)
+	if err != nil {
+		return &pb.SetNetworkPolicyResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to apply network policy: %v", err),
+		}, nil
+	}
+
+	s.policies[req.PolicyId] = &networkPolicy{networkID: req.NetworkId, handles: handles}
 
-	// TODO: Implement OVN ACLs for network policies
-	// For now, return success but log that it's not implemented
-	log.Printf("Warning: Network policies not yet implemented")
+	log.Printf("Successfully applied network policy %s on network %s (%d rules)", req.PolicyId, req.NetworkId, len(rules))
 
 	return &pb.SetNetworkPolicyResponse{
 		Success: true,
 	}, nil
 }
 
-// GetHealth returns health status
+// ListNetworkPolicies returns the policies currently applied, optionally
+// filtered to a single network.
+func (s *NetworkServer) ListNetworkPolicies(ctx context.Context, req *pb.ListNetworkPoliciesRequest) (*pb.ListNetworkPoliciesResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var policies []*pb.NetworkPolicyInfo
+	for policyID, p := range s.policies {
+		if req.NetworkId != "" && p.networkID != req.NetworkId {
+			continue
+		}
+		policies = append(policies, &pb.NetworkPolicyInfo{
+			PolicyId:  policyID,
+			NetworkId: p.networkID,
+			RuleCount: uint32(len(p.handles.ACLUUIDs)),
+		})
+	}
+
+	return &pb.ListNetworkPoliciesResponse{
+		Policies: policies,
+		Success:  true,
+	}, nil
+}
+
+// GetHealth returns health status. NbdbConnected reports the persistent
+// libovsdb connection ovn.Connect established at startup - the replacement
+// for the per-call ovn-nbctl process this server no longer forks, so this is
+// the equivalent of the "daemon state" an ovn-nbctl --detach socket would
+// have reported.
 func (s *NetworkServer) GetHealth(ctx context.Context, req *pb.GetHealthRequest) (*pb.GetHealthResponse, error) {
 	ovsStatus := checkServiceStatus("ovs-vswitchd")
 	ovnStatus := checkServiceStatus("ovn-controller")
+	nbdbConnected := s.nb.Connected()
 
-	healthy := ovsStatus == "running" && ovnStatus == "running"
+	healthy := ovsStatus == "running" && ovnStatus == "running" && nbdbConnected
 	uptime := uint64(time.Since(s.startTime).Seconds())
 
 	return &pb.GetHealthResponse{
 		Healthy:       healthy,
 		OvsStatus:     ovsStatus,
 		OvnStatus:     ovnStatus,
+		NbdbConnected: nbdbConnected,
 		UptimeSeconds: uptime,
 	}, nil
 }
 
-// Helper functions
-
-// addDNSRecord adds a DNS record (hostname -> IP) to an OVN logical switch
-// This handles existing DNS records properly by merging them
-func addDNSRecord(networkID, hostname, ipAddress string) error {
-	if hostname == "" || ipAddress == "" {
-		return fmt.Errorf("hostname and IP address are required")
-	}
-
-	log.Printf("addDNSRecord: Starting for network=%s hostname=%s ip=%s", networkID, hostname, ipAddress)
-
-	// OVN DNS records are stored as a UUID reference in the logical switch
-	// We need to create or update the DNS record in the DNS table
-
-	// Check if DNS record already exists for this logical switch
-	log.Printf("addDNSRecord: Querying existing DNS records for network %s", networkID)
-	dnsUUIDs, err := runCommandWithOutput("ovn-nbctl", "get", "logical_switch", networkID, "dns_records")
-	log.Printf("addDNSRecord: Query result - error=%v dnsUUIDs=%q", err, dnsUUIDs)
-	if err != nil {
-		// No DNS records yet, create a new one
-		log.Printf("Creating new DNS record set for network %s", networkID)
-
-		// Create DNS record with hostname -> IP mapping
-		createCmd := fmt.Sprintf(`ovn-nbctl create DNS records='{"%s"="%s"}'`, hostname, ipAddress)
-		output, err := runCommandWithOutput("sh", "-c", createCmd)
-		if err != nil {
-			return fmt.Errorf("failed to create DNS record: %v", err)
-		}
-
-		dnsRecordUUID := strings.TrimSpace(output)
-		log.Printf("Created DNS record UUID: %s", dnsRecordUUID)
-
-		// Link DNS record to logical switch
-		if err := runCommand("ovn-nbctl", "add", "logical_switch", networkID, "dns_records", dnsRecordUUID); err != nil {
-			return fmt.Errorf("failed to link DNS record to logical switch: %v", err)
-		}
-
-		log.Printf("DNS record added: %s -> %s on network %s", hostname, ipAddress, networkID)
-		return nil
-	}
-
-	// DNS records exist, update them
-	dnsUUIDs = strings.TrimSpace(dnsUUIDs)
-	if dnsUUIDs == "[]" {
-		// Empty list, create new DNS record
-		return addDNSRecord(networkID, hostname, ipAddress) // Recurse to create path
-	}
-
-	// Extract first UUID from the list (format: [uuid1, uuid2, ...])
-	dnsUUIDs = strings.Trim(dnsUUIDs, "[]")
-	parts := strings.Split(dnsUUIDs, ",")
-	if len(parts) == 0 {
-		return fmt.Errorf("invalid DNS UUID list: %s", dnsUUIDs)
-	}
-
-	dnsRecordUUID := strings.TrimSpace(parts[0])
-	log.Printf("Updating existing DNS record UUID: %s", dnsRecordUUID)
-
-	// Add hostname -> IP mapping to existing DNS record
-	setCmd := fmt.Sprintf(`ovn-nbctl set DNS %s records:"%s"="%s"`, dnsRecordUUID, hostname, ipAddress)
-	if err := runCommand("sh", "-c", setCmd); err != nil {
-		return fmt.Errorf("failed to update DNS record: %v", err)
-	}
-
-	log.Printf("DNS record updated: %s -> %s on network %s", hostname, ipAddress, networkID)
-	return nil
-}
-
-// removeDNSRecord removes a DNS record (hostname) from an OVN logical switch
-func removeDNSRecord(networkID, hostname string) error {
-	if hostname == "" {
-		return fmt.Errorf("hostname is required")
-	}
-
-	// Get DNS record UUIDs for this logical switch
-	dnsUUIDs, err := runCommandWithOutput("ovn-nbctl", "get", "logical_switch", networkID, "dns_records")
-	if err != nil || dnsUUIDs == "" || dnsUUIDs == "[]" {
-		// No DNS records, nothing to remove
-		return nil
-	}
-
-	// Extract first UUID
-	dnsUUIDs = strings.Trim(strings.TrimSpace(dnsUUIDs), "[]")
-	parts := strings.Split(dnsUUIDs, ",")
-	if len(parts) == 0 {
-		return nil
-	}
-
-	dnsRecordUUID := strings.TrimSpace(parts[0])
-
-	// Remove hostname from DNS record
-	removeCmd := fmt.Sprintf(`ovn-nbctl remove DNS %s records "%s"`, dnsRecordUUID, hostname)
-	if err := runCommand("sh", "-c", removeCmd); err != nil {
-		log.Printf("Warning: Failed to remove DNS record for %s: %v", hostname, err)
-		// Don't fail - record may not exist
-	} else {
-		log.Printf("DNS record removed: %s from network %s", hostname, networkID)
-	}
-
-	return nil
+// GetVLANPoolStats reports the persistent VLAN pool's occupancy, so an
+// operator can see how close a daemon is to the 100-4095 tag ceiling before
+// CreateBridge/CreateProviderNetwork starts failing with exhaustion.
+func (s *NetworkServer) GetVLANPoolStats(ctx context.Context, req *pb.GetVLANPoolStatsRequest) (*pb.GetVLANPoolStatsResponse, error) {
+	stats := s.vlans.Stats()
+	return &pb.GetVLANPoolStatsResponse{
+		Allocated: uint32(stats.Allocated),
+		Free:      uint32(stats.Free),
+		Total:     uint32(stats.Total),
+	}, nil
 }
 
+// Helper functions
 
-// GetContainerNetworks returns the list of networks a container is attached to
+// GetContainerNetworks returns the list of networks a container is attached
+// to - an O(1) lookup against the inventory's container->networks index,
+// rather than the O(N*M) scan of every network's container set this used to
+// do against the in-memory containerMap.
 func (s *NetworkServer) GetContainerNetworks(ctx context.Context, req *pb.GetContainerNetworksRequest) (*pb.GetContainerNetworksResponse, error) {
 	log.Printf("GetContainerNetworks: container_id=%s", req.ContainerId)
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	// Search through all networks to find which ones this container is attached to
-	var networkIDs []string
-	for networkID, containers := range s.containerMap {
-		if containers[req.ContainerId] {
-			networkIDs = append(networkIDs, networkID)
-		}
+	networkIDs, err := s.inv.NetworksFor(req.ContainerId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "reading inventory for container %s: %v", req.ContainerId, err)
 	}
 
 	log.Printf("GetContainerNetworks: container %s is on networks: %v", req.ContainerId, networkIDs)
@@ -734,33 +1055,51 @@ func (s *NetworkServer) GetContainerNetworks(ctx context.Context, req *pb.GetCon
 	}, nil
 }
 
-func runCommand(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	// Ensure OVN environment variables are set for ovn-nbctl commands
-	if name == "ovn-nbctl" || name == "ovn-sbctl" {
-		cmd.Env = append(os.Environ(),
-			"OVN_NB_DB=unix:/var/run/ovn/ovnnb_db.sock",
-			"OVN_SB_DB=unix:/var/run/ovn/ovnsb_db.sock",
-		)
+// defaultListPageSize bounds how many entries ListNetworks/ListContainers
+// return per call when the caller doesn't ask for a specific page size.
+const defaultListPageSize = 100
+
+// ListNetworks pages through every networkID with at least one attached
+// container. pageSize <= 0 uses defaultListPageSize; afterNetworkID is ""
+// for the first page and the previous call's cursor thereafter. The
+// returned cursor is "" once there are no more pages.
+func (s *NetworkServer) ListNetworks(ctx context.Context, afterNetworkID string, pageSize int) (networkIDs []string, cursor string, err error) {
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
 	}
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("command failed: %s %v: %v (output: %s)", name, args, err, string(output))
+	return s.inv.ListNetworks(afterNetworkID, pageSize)
+}
+
+// ListContainers pages through every containerID with at least one attached
+// network, the same way ListNetworks pages through networks.
+func (s *NetworkServer) ListContainers(ctx context.Context, afterContainerID string, pageSize int) (containerIDs []string, cursor string, err error) {
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
 	}
-	return nil
+	return s.inv.ListContainers(afterContainerID, pageSize)
 }
 
-func runCommandWithOutput(name string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
-	// Ensure OVN environment variables are set for ovn-nbctl commands
+// ovnEnv returns the extra environment variables runCommand/
+// runCommandWithOutput set for ovn-nbctl/ovn-sbctl, the only commands this
+// file still forks (everything else speaks OVSDB directly through
+// ovn.NBClient).
+func ovnEnv(name string) []string {
 	if name == "ovn-nbctl" || name == "ovn-sbctl" {
-		cmd.Env = append(os.Environ(),
+		return []string{
 			"OVN_NB_DB=unix:/var/run/ovn/ovnnb_db.sock",
 			"OVN_SB_DB=unix:/var/run/ovn/ovnsb_db.sock",
-		)
+		}
 	}
-	output, err := cmd.CombinedOutput()
-	return string(output), err
+	return nil
+}
+
+func runCommand(name string, args ...string) error {
+	_, err := arcaexec.New(name, args...).WithEnv(ovnEnv(name)...).Output()
+	return err
+}
+
+func runCommandWithOutput(name string, args ...string) (string, error) {
+	return arcaexec.New(name, args...).WithEnv(ovnEnv(name)...).Output()
 }
 
 func checkServiceStatus(serviceName string) string {
@@ -772,13 +1111,22 @@ func checkServiceStatus(serviceName string) string {
 }
 
 func appendToFile(filename, content string) error {
-	cmd := exec.Command("sh", "-c", fmt.Sprintf("echo '%s' >> %s", content, filename))
-	return cmd.Run()
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening %s for append: %w", filename, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content + "\n"); err != nil {
+		return fmt.Errorf("appending to %s: %w", filename, err)
+	}
+	return nil
 }
 
 func writeFile(filename, content string) error {
-	cmd := exec.Command("sh", "-c", fmt.Sprintf("cat > %s <<'EOF'\n%s\nEOF", filename, content))
-	return cmd.Run()
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", filename, err)
+	}
+	return nil
 }
 
 func readFile(filename string) (string, error) {
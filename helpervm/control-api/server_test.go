@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestDNSLockSerializesConcurrentWrites fires 100 concurrent writes for
+// different hostnames on the same network through dnsLock, the mutex
+// SetDNSRecord/DeleteDNSRecord use to serialize DNS record mutations per
+// networkID (see dnsLock's doc comment for the race it closes). It stands
+// in for ovn.NBClient's read-modify-write DNS row with a plain map, since
+// exercising OVN's own transaction behavior isn't possible without a live
+// OVN instance - the property under test is dnsLock's mutual exclusion, not
+// OVN's.
+func TestDNSLockSerializesConcurrentWrites(t *testing.T) {
+	s := &NetworkServer{dnsLocks: make(map[string]*sync.Mutex)}
+
+	const networkID = "net-under-test"
+	const n = 100
+	records := make(map[string]string)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hostname := fmt.Sprintf("host-%d", i)
+			ip := fmt.Sprintf("10.0.0.%d", i%256)
+
+			lock := s.dnsLock(networkID)
+			lock.Lock()
+			defer lock.Unlock()
+			records[hostname] = ip
+		}()
+	}
+	wg.Wait()
+
+	if len(records) != n {
+		t.Fatalf("got %d records, want %d - a lost update means dnsLock isn't serializing concurrent writers", len(records), n)
+	}
+	for i := 0; i < n; i++ {
+		hostname := fmt.Sprintf("host-%d", i)
+		want := fmt.Sprintf("10.0.0.%d", i%256)
+		if got := records[hostname]; got != want {
+			t.Errorf("records[%q] = %q, want %q", hostname, got, want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"log"
@@ -10,7 +11,10 @@ import (
 	"sync"
 
 	"github.com/mdlayher/vsock"
+	"golang.org/x/net/bpf"
 	"golang.org/x/sys/unix"
+
+	"arca-network-api/internal/capture"
 )
 
 // TAPRelayManager manages vsock listeners for TAP packet relay
@@ -18,13 +22,19 @@ type TAPRelayManager struct {
 	mu        sync.RWMutex
 	listeners map[uint32]*vsock.Listener // port -> listener
 	relays    map[uint32]chan struct{}   // port -> stop channel
+	captures  map[uint32]*capture.Hub    // port -> live capture subscribers
+
+	fileCapturesMu sync.Mutex
+	fileCaptures   map[uint32]*RelayFileCapture
 }
 
 // NewTAPRelayManager creates a new TAP relay manager
 func NewTAPRelayManager() *TAPRelayManager {
 	return &TAPRelayManager{
-		listeners: make(map[uint32]*vsock.Listener),
-		relays:    make(map[uint32]chan struct{}),
+		listeners:    make(map[uint32]*vsock.Listener),
+		relays:       make(map[uint32]chan struct{}),
+		captures:     make(map[uint32]*capture.Hub),
+		fileCaptures: make(map[uint32]*RelayFileCapture),
 	}
 }
 
@@ -51,6 +61,8 @@ func (m *TAPRelayManager) StartRelay(port uint32, networkID string, containerID
 	m.listeners[port] = listener
 	stopChan := make(chan struct{})
 	m.relays[port] = stopChan
+	hub := capture.NewHub()
+	m.captures[port] = hub
 
 	// Start accepting connections in background
 	go func() {
@@ -59,6 +71,7 @@ func (m *TAPRelayManager) StartRelay(port uint32, networkID string, containerID
 			m.mu.Lock()
 			delete(m.listeners, port)
 			delete(m.relays, port)
+			delete(m.captures, port)
 			m.mu.Unlock()
 		}()
 
@@ -89,6 +102,26 @@ func (m *TAPRelayManager) StartRelay(port uint32, networkID string, containerID
 	return nil
 }
 
+// PushRoutes sends a container interface's static routes to the guest over
+// the relay's vsock connection. Doing that for real requires the relay to
+// speak a framed control protocol distinguishable from the raw Ethernet
+// frames StartRelay already shuttles on the same connection - until that
+// lands, this just validates the relay is still up and logs the routes so
+// the request isn't silently dropped.
+func (m *TAPRelayManager) PushRoutes(port uint32, routes []StaticRoute) error {
+	m.mu.RLock()
+	_, exists := m.listeners[port]
+	m.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("no relay running on port %d", port)
+	}
+
+	for _, r := range routes {
+		log.Printf("TAP relay port %d: route %s via %s queued (control channel not yet implemented)", port, r.Destination, r.Gateway)
+	}
+	return nil
+}
+
 // StopRelay stops a vsock relay
 func (m *TAPRelayManager) StopRelay(port uint32) error {
 	m.mu.Lock()
@@ -103,6 +136,104 @@ func (m *TAPRelayManager) StopRelay(port uint32) error {
 	return nil
 }
 
+// RelayFileCapture is a running local-mode capture started by StartCapture.
+// Call Stop to end it early; it also stops on its own once maxBytes bytes
+// have been written.
+type RelayFileCapture struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Stop ends the capture and waits for its goroutine to finish flushing and
+// closing the output file.
+func (c *RelayFileCapture) Stop() {
+	c.cancel()
+	<-c.done
+}
+
+// StartCapture subscribes to port's relay traffic in both directions and
+// writes every matching frame straight to a pcap file at path - the
+// TAPRelayManager equivalent of arca-tap-forwarder-go's
+// Forwarder.StartFileCapture, for debugging this side of the relay without
+// needing a streaming capture client. filter may be nil to capture
+// everything. The capture stops once maxBytes bytes have been written (0
+// means unlimited) or Stop is called.
+func (m *TAPRelayManager) StartCapture(port uint32, path string, filter []bpf.Instruction, maxBytes int64) (*RelayFileCapture, error) {
+	m.mu.RLock()
+	hub, exists := m.captures[port]
+	m.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("no relay running on port %d", port)
+	}
+
+	m.fileCapturesMu.Lock()
+	defer m.fileCapturesMu.Unlock()
+	if _, running := m.fileCaptures[port]; running {
+		return nil, fmt.Errorf("file capture already running on port %d", port)
+	}
+
+	sub, err := capture.NewSubscriber(filter, capture.DefaultSnaplen)
+	if err != nil {
+		return nil, err
+	}
+
+	fw, err := capture.CreateFile(path, capture.DefaultSnaplen)
+	if err != nil {
+		return nil, err
+	}
+
+	hub.Subscribe(sub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer fw.Close()
+		defer hub.Unsubscribe(sub)
+
+		var written int64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case rec, ok := <-sub.Packets():
+				if !ok {
+					return
+				}
+				if err := fw.Write(rec); err != nil {
+					log.Printf("TAP relay port %d: file capture write to %s failed: %v", port, path, err)
+					return
+				}
+				written += int64(len(rec.Data))
+				if maxBytes > 0 && written >= maxBytes {
+					return
+				}
+			}
+		}
+	}()
+
+	fc := &RelayFileCapture{cancel: cancel, done: done}
+	m.fileCaptures[port] = fc
+	return fc, nil
+}
+
+// StopCapture ends a capture started by StartCapture on port.
+func (m *TAPRelayManager) StopCapture(port uint32) error {
+	m.fileCapturesMu.Lock()
+	fc, exists := m.fileCaptures[port]
+	if exists {
+		delete(m.fileCaptures, port)
+	}
+	m.fileCapturesMu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("no file capture running on port %d", port)
+	}
+
+	fc.Stop()
+	return nil
+}
+
 // handleConnection handles a single vsock connection for TAP packet relay
 func (m *TAPRelayManager) handleConnection(conn net.Conn, networkID string, containerID string, macAddress string, port uint32) {
 	defer conn.Close()
@@ -140,6 +271,10 @@ func (m *TAPRelayManager) handleConnection(conn net.Conn, networkID string, cont
 
 	log.Printf("Started packet relay: vsock port %d <-> OVS port %s on bridge %s", port, portName, bridgeName)
 
+	m.mu.RLock()
+	hub := m.captures[port]
+	m.mu.RUnlock()
+
 	// Relay packets bidirectionally
 	done := make(chan struct{}, 2)
 
@@ -155,6 +290,11 @@ func (m *TAPRelayManager) handleConnection(conn net.Conn, networkID string, cont
 				}
 				return
 			}
+			if hub != nil && hub.HasSubscribers() {
+				if drops := hub.Offer(capture.DirectionFromContainer, buffer[:n]); drops > 0 {
+					log.Printf("TAP relay port %d: %d capture subscriber(s) dropped a frame", port, drops)
+				}
+			}
 			if _, err := tapFile.Write(buffer[:n]); err != nil {
 				log.Printf("Error writing to TAP: %v", err)
 				return
@@ -174,6 +314,11 @@ func (m *TAPRelayManager) handleConnection(conn net.Conn, networkID string, cont
 				}
 				return
 			}
+			if hub != nil && hub.HasSubscribers() {
+				if drops := hub.Offer(capture.DirectionToContainer, buffer[:n]); drops > 0 {
+					log.Printf("TAP relay port %d: %d capture subscriber(s) dropped a frame", port, drops)
+				}
+			}
 			if _, err := conn.Write(buffer[:n]); err != nil {
 				log.Printf("Error writing to vsock: %v", err)
 				return
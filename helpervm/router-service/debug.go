@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/Liquescent-Development/arca/helpervm/router-service/internal/rpclog"
+	pb "github.com/Liquescent-Development/arca/helpervm/router-service/proto"
+)
+
+// statuszTemplate renders the debug statusz page: active VLANs, routes, and
+// the most recent RPCs rpclog.Recorder has observed. It's deliberately
+// plain text/table HTML rather than anything with client-side JS, so it
+// stays readable over a port-forwarded debug-addr with nothing but a
+// browser or curl.
+var statuszTemplate = template.Must(template.New("statusz").Parse(`<!doctype html>
+<title>arca router-service statusz</title>
+<h1>arca router-service</h1>
+<p>uptime: {{.Uptime}}</p>
+
+<h2>VLANs ({{len .VLANs}})</h2>
+<table border="1" cellpadding="4">
+<tr><th>VLAN ID</th><th>Interface</th><th>Subnet</th><th>Gateway</th></tr>
+{{range .VLANs}}<tr><td>{{.VlanId}}</td><td>{{.InterfaceName}}</td><td>{{.Subnet}}</td><td>{{.Gateway}}</td></tr>
+{{end}}</table>
+
+<h2>Routes ({{len .Routes}})</h2>
+<table border="1" cellpadding="4">
+<tr><th>ID</th><th>Dst</th><th>Gateway</th><th>Interface</th><th>Metric</th></tr>
+{{range .Routes}}<tr><td>{{.RouteId}}</td><td>{{.Dst}}</td><td>{{.Gateway}}</td><td>{{.DevInterface}}</td><td>{{.Metric}}</td></tr>
+{{end}}</table>
+
+<h2>Dataplane plugins ({{len .DataplanePlugins}})</h2>
+<ul>{{range .DataplanePlugins}}<li>{{.}}</li>
+{{end}}</ul>
+
+<h2>Recent RPCs ({{len .RPCs}})</h2>
+<table border="1" cellpadding="4">
+<tr><th>Time</th><th>Method</th><th>Peer</th><th>Duration</th><th>Error</th></tr>
+{{range .RPCs}}<tr><td>{{.Start.Format "15:04:05.000"}}</td><td>{{.Method}}</td><td>{{.Peer}}</td><td>{{.Duration}}</td><td>{{if .Err}}{{.Err}}{{end}}</td></tr>
+{{end}}</table>
+`))
+
+// statuszData is statuszTemplate's template context.
+type statuszData struct {
+	Uptime           time.Duration
+	VLANs            []*pb.VLANInterface
+	Routes           []*pb.RouteEntry
+	DataplanePlugins []string
+	RPCs             []rpclog.Entry
+}
+
+// NewDebugMux builds the HTTP mux served on -debug-addr: Go's standard
+// pprof profiles plus a statusz page summarizing s's live state and the
+// RPCs recorder has recently observed. It's bound to a separate address
+// from the gRPC listener so it can be firewalled off independently (or
+// left on localhost-only) in production.
+func NewDebugMux(s *RouterServer, recorder *rpclog.Recorder) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	mux.HandleFunc("/statusz", func(w http.ResponseWriter, r *http.Request) {
+		vlans, err := s.ListVLANs(r.Context(), &pb.ListVLANsRequest{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		routes, err := s.ListRoutes(r.Context(), &pb.ListRoutesRequest{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var plugins []string
+		if s.dataplane != nil {
+			plugins = s.dataplane.Names()
+		}
+
+		data := statuszData{
+			Uptime:           time.Since(s.startTime).Round(time.Second),
+			VLANs:            vlans.Vlans,
+			Routes:           routes.Routes,
+			DataplanePlugins: plugins,
+			RPCs:             recorder.Entries(),
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := statuszTemplate.Execute(w, data); err != nil {
+			log.Printf("statusz: rendering template: %v", err)
+		}
+	})
+
+	return mux
+}
+
+// ServeDebugMux listens on addr and serves mux until ctx is canceled,
+// matching the Run(ctx, ...) task signature so it can run alongside the
+// gRPC server under the same errgroup.
+func ServeDebugMux(addr string, mux *http.ServeMux) func(context.Context) error {
+	return func(ctx context.Context) error {
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("debug HTTP listen on %s: %w", addr, err)
+		}
+
+		srv := &http.Server{Handler: mux}
+		errCh := make(chan error, 1)
+		go func() { errCh <- srv.Serve(lis) }()
+
+		log.Printf("Debug HTTP server listening on %s (pprof, statusz)", addr)
+
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return srv.Shutdown(shutdownCtx)
+		case err := <-errCh:
+			if err != nil && err != http.ErrServerClosed {
+				return fmt.Errorf("debug HTTP serve: %w", err)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package dataplane lets RouterServer delegate forwarding-engine work
+// (routes, NAT, stats, flow events) to an out-of-process plugin instead of
+// the netlink/nftables logic built into server.go, so an operator can swap
+// in an eBPF/XDP, VPP, DPDK, or proprietary dataplane without recompiling
+// arca. A plugin is a regular gRPC service implementing the Dataplane proto
+// (see proto/dataplane); this package is the client side - dialing a
+// plugin, health-checking it over the standard gRPC health protocol, and
+// presenting it to server.go as a Plugin. It's modeled on the firewall
+// package's Backend interface, but plugins run out-of-process rather than
+// being linked into the router binary.
+package dataplane
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Route is a single forwarding-table entry a plugin installs or removes.
+type Route struct {
+	Dst       string // destination CIDR, or "" for a default route
+	Gateway   string // empty for an on-link route
+	Interface string
+	Metric    int
+}
+
+// NATRule is one SNAT/MASQUERADE rule a plugin installs for a network's
+// egress traffic; TargetIP is empty for a MASQUERADE rule.
+type NATRule struct {
+	NetworkID uint32
+	Subnet    string
+	TargetIP  string
+}
+
+// Stats reports a plugin's packet/byte counters for one network.
+type Stats struct {
+	NetworkID      uint32
+	RxPackets      uint64
+	TxPackets      uint64
+	RxBytes        uint64
+	TxBytes        uint64
+	DroppedPackets uint64
+}
+
+// Event is a single forwarding event a plugin streams back via
+// StreamEvents (a new flow, a dropped packet, an interface state change).
+// Kind/Detail are opaque, backend-defined strings rather than a typed union,
+// since different dataplanes report fundamentally different event shapes.
+type Event struct {
+	Kind      string
+	NetworkID uint32
+	Detail    string
+	At        time.Time
+}
+
+// Plugin is the forwarding engine RouterServer dispatches calls to.
+// Implementations must make AddRoute/DelRoute/InstallNAT idempotent, since
+// server.go replays them during reconciliation after a restart.
+type Plugin interface {
+	AddRoute(ctx context.Context, r Route) error
+	DelRoute(ctx context.Context, r Route) error
+	InstallNAT(ctx context.Context, rule NATRule) error
+	GetStats(ctx context.Context, networkID uint32) (Stats, error)
+	// StreamEvents calls onEvent for every event the plugin emits until ctx
+	// is canceled or the stream ends.
+	StreamEvents(ctx context.Context, onEvent func(Event)) error
+	// Name identifies the plugin for logging - the operator-assigned name
+	// from its PluginConfig.
+	Name() string
+	// Healthy reports whether the plugin currently answers the standard
+	// gRPC health check as SERVING, so callers like the router's periodic
+	// health-status task can reflect a plugin going down after NewManager
+	// dialed it successfully.
+	Healthy(ctx context.Context) error
+	// Close releases the plugin's underlying connection.
+	Close() error
+}
+
+// PluginConfig names and locates one out-of-process dataplane plugin.
+type PluginConfig struct {
+	// Name is how RouterServer callers refer to this plugin elsewhere in
+	// the RouterService API (e.g. a request's dataplane_plugin field).
+	Name string
+	// Target is a standard grpc.NewClient dial target, e.g.
+	// "unix:///run/arca/plugins/vpp.sock" or "vsock://3:9000".
+	Target string
+	// TLS, if set, authenticates the plugin connection with mTLS instead
+	// of dialing plaintext.
+	TLS *tls.Config
+}
+
+// Manager dials, health-checks, and holds every configured dataplane
+// plugin, so RouterServer can look one up by name when dispatching a call
+// and close them all together on shutdown.
+type Manager struct {
+	mu      sync.RWMutex
+	plugins map[string]Plugin
+}
+
+// dialFunc is swapped out in tests so NewManager can be exercised without a
+// real plugin process listening.
+var dialFunc = dialGRPCPlugin
+
+// NewManager dials and health-checks every configured plugin, failing
+// closed: a plugin that can't be reached or doesn't report SERVING is
+// treated as a configuration error rather than silently dropped, since a
+// RouterService call naming it would otherwise fail confusingly later.
+func NewManager(ctx context.Context, configs []PluginConfig) (*Manager, error) {
+	m := &Manager{plugins: make(map[string]Plugin, len(configs))}
+	for _, cfg := range configs {
+		if _, exists := m.plugins[cfg.Name]; exists {
+			m.Close()
+			return nil, fmt.Errorf("dataplane: duplicate plugin name %q", cfg.Name)
+		}
+		p, err := dialFunc(ctx, cfg)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("dataplane: dialing plugin %q at %s: %w", cfg.Name, cfg.Target, err)
+		}
+		m.plugins[cfg.Name] = p
+	}
+	return m, nil
+}
+
+// Plugin returns the named plugin, or ok=false if no plugin by that name is
+// configured.
+func (m *Manager) Plugin(name string) (Plugin, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.plugins[name]
+	return p, ok
+}
+
+// Names returns every configured plugin's name.
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.plugins))
+	for name := range m.plugins {
+		names = append(names, name)
+	}
+	return names
+}
+
+// HealthCheck re-checks every configured plugin and returns the error (nil
+// on success) each one's health check returned, keyed by plugin name.
+func (m *Manager) HealthCheck(ctx context.Context) map[string]error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make(map[string]error, len(m.plugins))
+	for name, p := range m.plugins {
+		results[name] = p.Healthy(ctx)
+	}
+	return results
+}
+
+// Close closes every configured plugin's underlying connection, collecting
+// (not short-circuiting on) individual failures so one stuck plugin doesn't
+// prevent the others from shutting down.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for name, p := range m.plugins {
+		if err := p.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("plugin %q: %w", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("dataplane: closing plugins: %v", errs)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package dataplane
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	dataplanepb "github.com/Liquescent-Development/arca/helpervm/router-service/proto/dataplane"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// dataplaneServiceName is the fully-qualified gRPC service name plugins
+// register with the standard health-checking protocol, so Check can ask
+// for that service's status specifically rather than the whole process's.
+const dataplaneServiceName = "arca.dataplane.Dataplane"
+
+// healthCheckTimeout bounds how long dialGRPCPlugin waits for a plugin's
+// initial health check before giving up on it.
+const healthCheckTimeout = 5 * time.Second
+
+// grpcPlugin implements Plugin over a gRPC connection to an out-of-process
+// dataplane plugin.
+type grpcPlugin struct {
+	name   string
+	conn   *grpc.ClientConn
+	client dataplanepb.DataplaneClient
+	health grpc_health_v1.HealthClient
+}
+
+// dialGRPCPlugin connects to cfg.Target and confirms the plugin reports
+// SERVING for the Dataplane service before handing it back, so a
+// misconfigured or not-yet-ready plugin fails at startup instead of on the
+// first RouterService call that needs it.
+func dialGRPCPlugin(ctx context.Context, cfg PluginConfig) (Plugin, error) {
+	creds := insecure.NewCredentials()
+	if cfg.TLS != nil {
+		creds = credentials.NewTLS(cfg.TLS)
+	}
+
+	conn, err := grpc.NewClient(cfg.Target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+
+	health := grpc_health_v1.NewHealthClient(conn)
+	p := &grpcPlugin{
+		name:   cfg.Name,
+		conn:   conn,
+		client: dataplanepb.NewDataplaneClient(conn),
+		health: health,
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	if err := p.Healthy(checkCtx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *grpcPlugin) Name() string { return p.name }
+
+func (p *grpcPlugin) Close() error { return p.conn.Close() }
+
+// Healthy asks the plugin's standard gRPC health service whether the
+// Dataplane service is SERVING.
+func (p *grpcPlugin) Healthy(ctx context.Context) error {
+	resp, err := p.health.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: dataplaneServiceName})
+	if err != nil {
+		return fmt.Errorf("health check: %w", err)
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health check: plugin reports status %s", resp.Status)
+	}
+	return nil
+}
+
+func (p *grpcPlugin) AddRoute(ctx context.Context, r Route) error {
+	_, err := p.client.AddRoute(ctx, &dataplanepb.AddRouteRequest{
+		Dst:       r.Dst,
+		Gateway:   r.Gateway,
+		Interface: r.Interface,
+		Metric:    int32(r.Metric),
+	})
+	return err
+}
+
+func (p *grpcPlugin) DelRoute(ctx context.Context, r Route) error {
+	_, err := p.client.DelRoute(ctx, &dataplanepb.DelRouteRequest{
+		Dst:       r.Dst,
+		Gateway:   r.Gateway,
+		Interface: r.Interface,
+		Metric:    int32(r.Metric),
+	})
+	return err
+}
+
+func (p *grpcPlugin) InstallNAT(ctx context.Context, rule NATRule) error {
+	_, err := p.client.InstallNat(ctx, &dataplanepb.InstallNatRequest{
+		NetworkId: rule.NetworkID,
+		Subnet:    rule.Subnet,
+		TargetIp:  rule.TargetIP,
+	})
+	return err
+}
+
+func (p *grpcPlugin) GetStats(ctx context.Context, networkID uint32) (Stats, error) {
+	resp, err := p.client.GetStats(ctx, &dataplanepb.GetStatsRequest{NetworkId: networkID})
+	if err != nil {
+		return Stats{}, err
+	}
+	return Stats{
+		NetworkID:      networkID,
+		RxPackets:      resp.RxPackets,
+		TxPackets:      resp.TxPackets,
+		RxBytes:        resp.RxBytes,
+		TxBytes:        resp.TxBytes,
+		DroppedPackets: resp.DroppedPackets,
+	}, nil
+}
+
+// StreamEvents opens the plugin's event stream and calls onEvent for each
+// message until ctx is canceled or the plugin closes the stream.
+func (p *grpcPlugin) StreamEvents(ctx context.Context, onEvent func(Event)) error {
+	stream, err := p.client.StreamEvents(ctx, &dataplanepb.StreamEventsRequest{})
+	if err != nil {
+		return fmt.Errorf("opening event stream: %w", err)
+	}
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("receiving event: %w", err)
+		}
+		onEvent(Event{
+			Kind:      msg.Kind,
+			NetworkID: msg.NetworkId,
+			Detail:    msg.Detail,
+			At:        msg.At.AsTime(),
+		})
+	}
+}
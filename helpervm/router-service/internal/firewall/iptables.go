@@ -0,0 +1,275 @@
+package firewall
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/coreos/go-iptables/iptables"
+)
+
+// postChain, preChain and iptablesFwdChain are dedicated chains this backend owns
+// entirely. The built-in POSTROUTING/PREROUTING/FORWARD chains each get one
+// static jump rule into ours (added once, via go-iptables), so Apply's
+// --noflush restore only ever replaces our own chains and leaves every
+// other table/chain - including rules this process doesn't own - untouched.
+const (
+	postChain        = "ARCA-POSTROUTING"
+	preChain         = "ARCA-PREROUTING"
+	iptablesFwdChain = "ARCA-FORWARD"
+)
+
+// snatChain and fwdNetChain name a network's dedicated per-VLAN chains -
+// ARCA-SNAT-<id> in the nat table and ARCA-FWD-<id> in filter - that hold
+// its ordered egress policy, so one VLAN's rules can never be mistaken for
+// another's when an operator is reading `iptables -L`.
+func snatChain(id uint32) string   { return fmt.Sprintf("ARCA-SNAT-%d", id) }
+func fwdNetChain(id uint32) string { return fmt.Sprintf("ARCA-FWD-%d", id) }
+
+// iptablesBackend is the legacy fallback for kernels without nftables. It
+// renders RuleSets into iptables-restore syntax and applies the whole nat
+// table's worth of our own chains in one `iptables-restore --noflush`
+// invocation instead of one exec.Command per -A/-D rule.
+type iptablesBackend struct {
+	ipt *iptables.IPTables
+}
+
+// newIPTablesBackend confirms the iptables binary and kernel module are
+// present, then ensures postChain/preChain exist and are jumped to from the
+// builtin chains.
+func newIPTablesBackend() (*iptablesBackend, error) {
+	ipt, err := iptables.New()
+	if err != nil {
+		return nil, fmt.Errorf("firewall: iptables unavailable: %w", err)
+	}
+
+	for _, spec := range []struct{ table, chain, builtin string }{
+		{"nat", postChain, "POSTROUTING"},
+		{"nat", preChain, "PREROUTING"},
+		{"filter", iptablesFwdChain, "FORWARD"},
+	} {
+		exists, err := ipt.ChainExists(spec.table, spec.chain)
+		if err != nil {
+			return nil, fmt.Errorf("firewall: checking chain %s: %w", spec.chain, err)
+		}
+		if !exists {
+			if err := ipt.NewChain(spec.table, spec.chain); err != nil {
+				return nil, fmt.Errorf("firewall: creating chain %s: %w", spec.chain, err)
+			}
+		}
+		if err := ipt.AppendUnique(spec.table, spec.builtin, "-j", spec.chain); err != nil {
+			return nil, fmt.Errorf("firewall: jumping %s to %s: %w", spec.builtin, spec.chain, err)
+		}
+	}
+
+	return &iptablesBackend{ipt: ipt}, nil
+}
+
+func (b *iptablesBackend) Name() string { return "iptables" }
+
+// Apply renders sets into iptables-restore syntax covering only the chains
+// this backend owns - postChain/preChain plus one ARCA-SNAT-<id> per
+// network in the nat table, and iptablesFwdChain plus one ARCA-FWD-<id> per
+// network in filter - and applies each table with its own `iptables-restore
+// --noflush`, so every owned chain is replaced atomically without touching
+// anything else.
+func (b *iptablesBackend) Apply(sets map[uint32]*RuleSet) error {
+	ids := make([]uint32, 0, len(sets))
+	for id := range sets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	if err := b.applyNAT(sets, ids); err != nil {
+		return err
+	}
+	return b.applyForward(sets, ids)
+}
+
+// applyNAT renders the nat table: preChain's DNAT port forwards, and
+// postChain jumping into each network's ARCA-SNAT-<id> chain, which holds
+// that network's egress policy translated into SNAT/MASQUERADE/ACCEPT/
+// REJECT/DROP rules.
+func (b *iptablesBackend) applyNAT(sets map[uint32]*RuleSet, ids []uint32) error {
+	var buf strings.Builder
+	buf.WriteString("*nat\n")
+	buf.WriteString(fmt.Sprintf(":%s - [0:0]\n", postChain))
+	buf.WriteString(fmt.Sprintf(":%s - [0:0]\n", preChain))
+	for _, id := range ids {
+		if sets[id].Subnet != "" {
+			buf.WriteString(fmt.Sprintf(":%s - [0:0]\n", snatChain(id)))
+		}
+	}
+
+	for _, id := range ids {
+		set := sets[id]
+		if set.Subnet != "" {
+			buf.WriteString(fmt.Sprintf(
+				"-A %s -s %s -j %s -m comment --comment network-%d\n",
+				postChain, set.Subnet, snatChain(id), id))
+			for _, rule := range egressRulesOrDefault(set.EgressRules) {
+				line, err := natEgressLine(snatChain(id), rule)
+				if err != nil {
+					return fmt.Errorf("firewall: network %d: %w", id, err)
+				}
+				buf.WriteString(line)
+			}
+		}
+		for _, pm := range set.PortMaps {
+			line, err := dnatLine(pm, id)
+			if err != nil {
+				return fmt.Errorf("firewall: network %d: %w", id, err)
+			}
+			buf.WriteString(line)
+		}
+	}
+	buf.WriteString("COMMIT\n")
+
+	return restore(buf.String(), "--table=nat")
+}
+
+// applyForward renders the filter table: iptablesFwdChain jumping into each
+// network's ARCA-FWD-<id> chain, which holds the same egress policy
+// translated into ACCEPT/REJECT/DROP decisions (a SNAT/MASQUERADE action
+// has already happened in the nat table by the time FORWARD sees the
+// packet, so it's treated as ACCEPT here).
+func (b *iptablesBackend) applyForward(sets map[uint32]*RuleSet, ids []uint32) error {
+	var buf strings.Builder
+	buf.WriteString("*filter\n")
+	buf.WriteString(fmt.Sprintf(":%s - [0:0]\n", iptablesFwdChain))
+	for _, id := range ids {
+		if sets[id].Subnet != "" {
+			buf.WriteString(fmt.Sprintf(":%s - [0:0]\n", fwdNetChain(id)))
+		}
+	}
+
+	for _, id := range ids {
+		set := sets[id]
+		if set.Subnet == "" {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf(
+			"-A %s -s %s -j %s -m comment --comment network-%d\n",
+			iptablesFwdChain, set.Subnet, fwdNetChain(id), id))
+		for _, rule := range egressRulesOrDefault(set.EgressRules) {
+			line, err := forwardEgressLine(fwdNetChain(id), rule)
+			if err != nil {
+				return fmt.Errorf("firewall: network %d: %w", id, err)
+			}
+			buf.WriteString(line)
+		}
+	}
+	buf.WriteString("COMMIT\n")
+
+	return restore(buf.String(), "--table=filter")
+}
+
+// dnatLine renders one PortMap as a preChain -A line, the iptables-restore
+// equivalent of nftablesBackend.addDNATRule. ContainerIP and Protocol reach
+// here straight from AddPortMapping's request fields, so both are validated
+// the same way the nftables backend validates them before anything is
+// formatted into the restore buffer - an unvalidated ContainerIP containing
+// an embedded newline would otherwise let a caller inject arbitrary extra
+// lines into the nat table.
+func dnatLine(pm PortMap, id uint32) (string, error) {
+	if net.ParseIP(pm.ContainerIP) == nil {
+		return "", fmt.Errorf("invalid container IP %s", pm.ContainerIP)
+	}
+	switch pm.Protocol {
+	case "tcp", "udp":
+	default:
+		return "", fmt.Errorf("unsupported protocol %s", pm.Protocol)
+	}
+	return fmt.Sprintf(
+		"-A %s -p %s --dport %d -j DNAT --to-destination %s:%d -m comment --comment port-%d-vlan-%d\n",
+		preChain, pm.Protocol, pm.HostPort, pm.ContainerIP, pm.ContainerPort, pm.HostPort, id), nil
+}
+
+// matchArgs renders an EgressRule's destination/protocol/port match as
+// iptables arguments, shared by both the nat and filter chain renderers.
+// DstCIDR and Protocol are validated the same way egressMatchExprs
+// validates them for the nftables backend, since both ultimately come from
+// the same SetEgressPolicy request fields.
+func matchArgs(rule EgressRule) (string, error) {
+	var b strings.Builder
+	if rule.DstCIDR != "" {
+		if _, _, err := net.ParseCIDR(rule.DstCIDR); err != nil {
+			return "", fmt.Errorf("invalid destination %s: %w", rule.DstCIDR, err)
+		}
+		fmt.Fprintf(&b, " -d %s", rule.DstCIDR)
+	}
+	if rule.Protocol != "" {
+		switch rule.Protocol {
+		case "tcp", "udp":
+		default:
+			return "", fmt.Errorf("unsupported protocol %s", rule.Protocol)
+		}
+		fmt.Fprintf(&b, " -p %s", rule.Protocol)
+		if rule.DstPort != 0 {
+			fmt.Fprintf(&b, " --dport %d", rule.DstPort)
+		}
+	}
+	return b.String(), nil
+}
+
+// natEgressLine renders one EgressRule as an -A line inside a network's
+// ARCA-SNAT-<id> chain.
+func natEgressLine(chain string, rule EgressRule) (string, error) {
+	match, err := matchArgs(rule)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case rule.Action == ActionMasquerade:
+		return fmt.Sprintf("-A %s%s -j MASQUERADE\n", chain, match), nil
+	case strings.HasPrefix(rule.Action, ActionSNATPrefix):
+		ip := strings.TrimPrefix(rule.Action, ActionSNATPrefix)
+		if net.ParseIP(ip) == nil {
+			return "", fmt.Errorf("invalid SNAT target in action %q", rule.Action)
+		}
+		return fmt.Sprintf("-A %s%s -j SNAT --to-source %s\n", chain, match, ip), nil
+	case rule.Action == ActionAccept:
+		return fmt.Sprintf("-A %s%s -j ACCEPT\n", chain, match), nil
+	case rule.Action == ActionReject:
+		return fmt.Sprintf("-A %s%s -j REJECT\n", chain, match), nil
+	case rule.Action == ActionDrop:
+		return fmt.Sprintf("-A %s%s -j DROP\n", chain, match), nil
+	default:
+		return "", fmt.Errorf("unsupported egress action %q", rule.Action)
+	}
+}
+
+// forwardEgressLine renders one EgressRule as an -A line inside a
+// network's ARCA-FWD-<id> chain. MASQUERADE and SNAT-to-<ip> describe a nat
+// table decision that's already been made by the time FORWARD sees the
+// packet, so both just mean "let it through" here.
+func forwardEgressLine(chain string, rule EgressRule) (string, error) {
+	match, err := matchArgs(rule)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case rule.Action == ActionMasquerade, strings.HasPrefix(rule.Action, ActionSNATPrefix), rule.Action == ActionAccept:
+		return fmt.Sprintf("-A %s%s -j ACCEPT\n", chain, match), nil
+	case rule.Action == ActionReject:
+		return fmt.Sprintf("-A %s%s -j REJECT\n", chain, match), nil
+	case rule.Action == ActionDrop:
+		return fmt.Sprintf("-A %s%s -j DROP\n", chain, match), nil
+	default:
+		return "", fmt.Errorf("unsupported egress action %q", rule.Action)
+	}
+}
+
+// restore pipes rules into iptables-restore for a single table, leaving
+// every other table untouched (--noflush also leaves this table's
+// non-owned chains alone).
+func restore(rules string, tableArg string) error {
+	cmd := exec.Command("iptables-restore", "--noflush", tableArg)
+	cmd.Stdin = strings.NewReader(rules)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("firewall: iptables-restore failed: %w (output: %s)", err, output)
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package firewall
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	tableName   = "arca"
+	natChain    = "postrouting"
+	dnatChain   = "prerouting"
+	nftFwdChain = "forward"
+)
+
+// snatChainName and fwdNetChainName name a network's dedicated per-VLAN
+// regular chains - snat-<id> in the nat-type postrouting path and
+// fwd-<id> in the filter-type forward path - jumped into from natChain and
+// nftFwdChain respectively, so one VLAN's egress policy can never be mistaken
+// for another's when an operator is reading `nft list ruleset`.
+func snatChainName(id uint32) string   { return fmt.Sprintf("snat-%d", id) }
+func fwdNetChainName(id uint32) string { return fmt.Sprintf("fwd-%d", id) }
+
+// nftablesBackend renders RuleSets into a dedicated "arca" table, replacing
+// its postrouting (MASQUERADE) and prerouting (DNAT) chains wholesale on
+// every Apply inside a single netlink batch - either every rule in the
+// batch lands, or (if the kernel rejects it) none of them do.
+type nftablesBackend struct {
+	conn *nftables.Conn
+}
+
+// newNFTablesBackend opens a netlink/nftables connection and confirms the
+// kernel actually has the nf_tables module loaded by listing the existing
+// tables, which fails outright on a kernel without nf_tables support.
+func newNFTablesBackend() (*nftablesBackend, error) {
+	conn, err := nftables.New()
+	if err != nil {
+		return nil, fmt.Errorf("firewall: nftables unavailable: %w", err)
+	}
+	if _, err := conn.ListTables(); err != nil {
+		return nil, fmt.Errorf("firewall: nftables probe failed: %w", err)
+	}
+	return &nftablesBackend{conn: conn}, nil
+}
+
+func (b *nftablesBackend) Name() string { return "nftables" }
+
+// Apply replaces the arca table's postrouting and prerouting chains with
+// exactly the rules sets describes, then commits everything - table,
+// chains, and rules - in a single netlink batch.
+func (b *nftablesBackend) Apply(sets map[uint32]*RuleSet) error {
+	table := b.conn.AddTable(&nftables.Table{
+		Name:   tableName,
+		Family: nftables.TableFamilyIPv4,
+	})
+
+	// Flushing the table inside the same batch as the rules we're about to
+	// add is what makes this one transaction instead of a flush that
+	// commits (and briefly drops all NAT) before the new rules arrive.
+	b.conn.FlushTable(table)
+
+	post := b.conn.AddChain(&nftables.Chain{
+		Name:     natChain,
+		Table:    table,
+		Type:     nftables.ChainTypeNAT,
+		Hooknum:  nftables.ChainHookPostrouting,
+		Priority: nftables.ChainPriorityNATSource,
+	})
+	pre := b.conn.AddChain(&nftables.Chain{
+		Name:     dnatChain,
+		Table:    table,
+		Type:     nftables.ChainTypeNAT,
+		Hooknum:  nftables.ChainHookPrerouting,
+		Priority: nftables.ChainPriorityNATDest,
+	})
+	fwd := b.conn.AddChain(&nftables.Chain{
+		Name:     nftFwdChain,
+		Table:    table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookForward,
+		Priority: nftables.ChainPriorityFilter,
+	})
+
+	ids := make([]uint32, 0, len(sets))
+	for id := range sets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		set := sets[id]
+		if set.Subnet != "" {
+			snat := b.conn.AddChain(&nftables.Chain{
+				Name:  snatChainName(id),
+				Table: table,
+				Type:  nftables.ChainTypeNAT,
+			})
+			fwdNet := b.conn.AddChain(&nftables.Chain{
+				Name:  fwdNetChainName(id),
+				Table: table,
+				Type:  nftables.ChainTypeFilter,
+			})
+			if err := b.addSourceJumpRule(table, post, set.Subnet, snatChainName(id)); err != nil {
+				return fmt.Errorf("firewall: network %d: %w", id, err)
+			}
+			if err := b.addSourceJumpRule(table, fwd, set.Subnet, fwdNetChainName(id)); err != nil {
+				return fmt.Errorf("firewall: network %d: %w", id, err)
+			}
+			for _, rule := range egressRulesOrDefault(set.EgressRules) {
+				if err := b.addNATEgressRule(table, snat, rule); err != nil {
+					return fmt.Errorf("firewall: network %d: %w", id, err)
+				}
+				if err := b.addForwardEgressRule(table, fwdNet, rule); err != nil {
+					return fmt.Errorf("firewall: network %d: %w", id, err)
+				}
+			}
+		}
+		for _, pm := range set.PortMaps {
+			if err := b.addDNATRule(table, pre, pm); err != nil {
+				return fmt.Errorf("firewall: network %d: %w", id, err)
+			}
+		}
+	}
+
+	if err := b.conn.Flush(); err != nil {
+		return fmt.Errorf("firewall: nftables commit failed: %w", err)
+	}
+	return nil
+}
+
+// addSourceJumpRule matches packets whose source address falls within
+// subnet and jumps to target, the nftables equivalent of
+// `iptables -A postChain -s subnet -j target`.
+func (b *nftablesBackend) addSourceJumpRule(table *nftables.Table, chain *nftables.Chain, subnet, target string) error {
+	_, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return fmt.Errorf("invalid subnet %s: %w", subnet, err)
+	}
+
+	b.conn.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: []expr.Any{
+			&expr.Payload{
+				DestRegister: 1,
+				Base:         expr.PayloadBaseNetworkHeader,
+				Offset:       12,
+				Len:          4,
+			},
+			&expr.Bitwise{
+				SourceRegister: 1,
+				DestRegister:   1,
+				Len:            4,
+				Mask:           []byte(ipnet.Mask),
+				Xor:            []byte{0, 0, 0, 0},
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ipnet.IP.To4(),
+			},
+			&expr.Verdict{Kind: expr.VerdictJump, Chain: target},
+		},
+	})
+	return nil
+}
+
+// egressMatchExprs renders an EgressRule's optional destination/protocol/
+// port match, shared by addNATEgressRule and addForwardEgressRule. It
+// always starts a fresh register 1, since the caller's rule hasn't used
+// one yet.
+func egressMatchExprs(rule EgressRule) ([]expr.Any, error) {
+	var exprs []expr.Any
+
+	if rule.DstCIDR != "" {
+		_, ipnet, err := net.ParseCIDR(rule.DstCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination %s: %w", rule.DstCIDR, err)
+		}
+		exprs = append(exprs,
+			&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: 16, Len: 4},
+			&expr.Bitwise{SourceRegister: 1, DestRegister: 1, Len: 4, Mask: []byte(ipnet.Mask), Xor: []byte{0, 0, 0, 0}},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: ipnet.IP.To4()},
+		)
+	}
+
+	if rule.Protocol != "" {
+		var l4proto byte
+		switch rule.Protocol {
+		case "tcp":
+			l4proto = unix.IPPROTO_TCP
+		case "udp":
+			l4proto = unix.IPPROTO_UDP
+		default:
+			return nil, fmt.Errorf("unsupported protocol %s", rule.Protocol)
+		}
+		exprs = append(exprs,
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{l4proto}},
+		)
+		if rule.DstPort != 0 {
+			exprs = append(exprs,
+				&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.BigEndian.PutUint16(uint16(rule.DstPort))},
+			)
+		}
+	}
+
+	return exprs, nil
+}
+
+// addNATEgressRule adds one EgressRule to a network's snat-<id> chain,
+// translating Action into the matching nftables NAT/verdict expression.
+func (b *nftablesBackend) addNATEgressRule(table *nftables.Table, chain *nftables.Chain, rule EgressRule) error {
+	match, err := egressMatchExprs(rule)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case rule.Action == ActionMasquerade:
+		b.conn.AddRule(&nftables.Rule{Table: table, Chain: chain, Exprs: append(match, &expr.Masq{})})
+	case strings.HasPrefix(rule.Action, ActionSNATPrefix):
+		ip := net.ParseIP(strings.TrimPrefix(rule.Action, ActionSNATPrefix)).To4()
+		if ip == nil {
+			return fmt.Errorf("invalid SNAT target in action %q", rule.Action)
+		}
+		b.conn.AddRule(&nftables.Rule{Table: table, Chain: chain, Exprs: append(match,
+			&expr.Immediate{Register: 1, Data: ip},
+			&expr.NAT{Type: expr.NATTypeSourceNAT, Family: unix.NFPROTO_IPV4, RegAddrMin: 1},
+		)})
+	case rule.Action == ActionAccept:
+		b.conn.AddRule(&nftables.Rule{Table: table, Chain: chain, Exprs: append(match, &expr.Verdict{Kind: expr.VerdictAccept})})
+	case rule.Action == ActionReject:
+		b.conn.AddRule(&nftables.Rule{Table: table, Chain: chain, Exprs: append(match, &expr.Reject{})})
+	case rule.Action == ActionDrop:
+		b.conn.AddRule(&nftables.Rule{Table: table, Chain: chain, Exprs: append(match, &expr.Verdict{Kind: expr.VerdictDrop})})
+	default:
+		return fmt.Errorf("unsupported egress action %q", rule.Action)
+	}
+	return nil
+}
+
+// addForwardEgressRule adds one EgressRule to a network's fwd-<id> chain.
+// MASQUERADE and SNAT-to-<ip> describe a nat table decision that's already
+// been made by the time the forward hook sees the packet, so both just
+// mean "let it through" here.
+func (b *nftablesBackend) addForwardEgressRule(table *nftables.Table, chain *nftables.Chain, rule EgressRule) error {
+	match, err := egressMatchExprs(rule)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case rule.Action == ActionMasquerade, strings.HasPrefix(rule.Action, ActionSNATPrefix), rule.Action == ActionAccept:
+		b.conn.AddRule(&nftables.Rule{Table: table, Chain: chain, Exprs: append(match, &expr.Verdict{Kind: expr.VerdictAccept})})
+	case rule.Action == ActionReject:
+		b.conn.AddRule(&nftables.Rule{Table: table, Chain: chain, Exprs: append(match, &expr.Reject{})})
+	case rule.Action == ActionDrop:
+		b.conn.AddRule(&nftables.Rule{Table: table, Chain: chain, Exprs: append(match, &expr.Verdict{Kind: expr.VerdictDrop})})
+	default:
+		return fmt.Errorf("unsupported egress action %q", rule.Action)
+	}
+	return nil
+}
+
+// addMasqueradeRule matches packets whose source address falls within
+// subnet and masquerades them, the nftables equivalent of
+// `iptables -t nat -A POSTROUTING -s subnet -j MASQUERADE`.
+func (b *nftablesBackend) addMasqueradeRule(table *nftables.Table, chain *nftables.Chain, subnet string) error {
+	_, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return fmt.Errorf("invalid subnet %s: %w", subnet, err)
+	}
+
+	b.conn.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: []expr.Any{
+			// [ payload load 4b @ network header + 12 => reg 1 ]  (ip saddr)
+			&expr.Payload{
+				DestRegister: 1,
+				Base:         expr.PayloadBaseNetworkHeader,
+				Offset:       12,
+				Len:          4,
+			},
+			// [ bitwise reg 1 = ( reg 1 & subnet mask ) ]
+			&expr.Bitwise{
+				SourceRegister: 1,
+				DestRegister:   1,
+				Len:            4,
+				Mask:           []byte(ipnet.Mask),
+				Xor:            []byte{0, 0, 0, 0},
+			},
+			// [ cmp eq reg 1 subnet network address ]
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ipnet.IP.To4(),
+			},
+			&expr.Masq{},
+		},
+	})
+	return nil
+}
+
+// addDNATRule matches TCP/UDP traffic on pm.HostPort and redirects it to
+// pm.ContainerIP:pm.ContainerPort, the nftables equivalent of
+// `iptables -t nat -A PREROUTING -p proto --dport hostPort -j DNAT
+// --to-destination containerIP:containerPort`.
+func (b *nftablesBackend) addDNATRule(table *nftables.Table, chain *nftables.Chain, pm PortMap) error {
+	containerIP := net.ParseIP(pm.ContainerIP).To4()
+	if containerIP == nil {
+		return fmt.Errorf("invalid container IP %s", pm.ContainerIP)
+	}
+
+	var l4proto byte
+	switch pm.Protocol {
+	case "tcp":
+		l4proto = unix.IPPROTO_TCP
+	case "udp":
+		l4proto = unix.IPPROTO_UDP
+	default:
+		return fmt.Errorf("unsupported protocol %s", pm.Protocol)
+	}
+
+	b.conn.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: []expr.Any{
+			// [ meta load l4proto => reg 1 ]
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{l4proto}},
+			// [ payload load 2b @ transport header + 2 => reg 1 ]  (tcp/udp dport)
+			&expr.Payload{
+				DestRegister: 1,
+				Base:         expr.PayloadBaseTransportHeader,
+				Offset:       2,
+				Len:          2,
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     binaryutil.BigEndian.PutUint16(uint16(pm.HostPort)),
+			},
+			// [ immediate reg 1 containerIP ]
+			&expr.Immediate{Register: 1, Data: containerIP},
+			// [ immediate reg 2 containerPort ]
+			&expr.Immediate{Register: 2, Data: binary.BigEndian.AppendUint16(nil, uint16(pm.ContainerPort))},
+			&expr.NAT{
+				Type:        expr.NATTypeDestNAT,
+				Family:      unix.NFPROTO_IPV4,
+				RegAddrMin:  1,
+				RegProtoMin: 2,
+			},
+		},
+	})
+	return nil
+}
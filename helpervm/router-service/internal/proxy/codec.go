@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// Name is the registered name of codec, passed to grpc.CallContentSubtype
+// and grpc.ForceServerCodec so the server negotiates it for every RPC.
+const Name = "proxy"
+
+// frame holds one undecoded message as the raw bytes gRPC read off the
+// wire. codec never unmarshals into a proto message, which is what lets
+// TransparentHandler forward RPCs for methods it has no generated stub
+// for.
+type frame struct {
+	payload []byte
+}
+
+// codec implements encoding.Codec by passing message bytes straight
+// through. It's installed server-wide via grpc.ForceServerCodec, so a
+// server using it must route every method through TransparentHandler -
+// handlers expecting a typed proto message would get a *frame instead.
+type codec struct{}
+
+// init registers codec under Name so a client using
+// grpc.CallContentSubtype(Name) (see Pool.conn) negotiates the same
+// pass-through (de)serialization the proxying server's
+// grpc.ForceServerCodec uses.
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	f, ok := v.(*frame)
+	if !ok {
+		return nil, fmt.Errorf("proxy: codec.Marshal called with %T, want *frame", v)
+	}
+	return f.payload, nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	f, ok := v.(*frame)
+	if !ok {
+		return fmt.Errorf("proxy: codec.Unmarshal called with %T, want *frame", v)
+	}
+	f.payload = data
+	return nil
+}
+
+func (codec) Name() string { return Name }
+
+// AsCodec returns codec typed as encoding.Codec for grpc.ForceServerCodec.
+func AsCodec() encoding.Codec { return codec{} }
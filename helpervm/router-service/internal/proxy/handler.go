@@ -0,0 +1,135 @@
+// Package proxy lets router-service front a fleet of other RouterService
+// instances without generating a stub for every method it forwards: it
+// installs a grpc.UnknownServiceHandler that reads each RPC's frames as
+// opaque bytes (via codec, registered through grpc.ForceServerCodec) and
+// relays them to whichever upstream a Router selects, based only on the
+// method name and incoming metadata. A server running in this mode never
+// decodes a request, so it forwards methods added to RouterService's proto
+// in the future with no changes here.
+//
+// This mirrors the well-known mwitkow/grpc-proxy transparent-proxying
+// pattern; the code here is a from-scratch implementation scoped to
+// router-service's needs (a Router keyed on method + metadata) rather than
+// a dependency on that package.
+package proxy
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// clientStreamDesc is used for every proxied call regardless of the
+// upstream method's actual streaming shape - ServerStreams/ClientStreams
+// both true lets TransparentHandler relay unary, server-streaming,
+// client-streaming, and bidi-streaming RPCs through the same code path.
+var clientStreamDesc = &grpc.StreamDesc{
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+// Director picks the upstream connection a proxied call should be
+// forwarded to. fullMethodName is the incoming RPC's full method name
+// (e.g. "/arca.RouterService/ListRoutes"); the returned context is used to
+// issue the outgoing call, so a Director can strip or rewrite metadata
+// (e.g. dropping a credential meant only for this proxy) before forwarding
+// it upstream.
+type Director func(ctx context.Context, fullMethodName string) (context.Context, *grpc.ClientConn, error)
+
+// TransparentHandler returns the grpc.StreamHandler to install via
+// grpc.UnknownServiceHandler: it asks director for an upstream connection
+// per call and pipes frames to and from it without ever deserializing a
+// payload.
+func TransparentHandler(director Director) grpc.StreamHandler {
+	return (&proxyHandler{director}).handle
+}
+
+type proxyHandler struct {
+	director Director
+}
+
+func (h *proxyHandler) handle(srv interface{}, serverStream grpc.ServerStream) error {
+	fullMethodName, ok := grpc.MethodFromServerStream(serverStream)
+	if !ok {
+		return status.Error(codes.Internal, "proxy: method name not found in incoming stream context")
+	}
+
+	outgoingCtx, backendConn, err := h.director(serverStream.Context(), fullMethodName)
+	if err != nil {
+		return err
+	}
+
+	clientCtx, clientCancel := context.WithCancel(outgoingCtx)
+	defer clientCancel()
+
+	clientStream, err := grpc.NewClientStream(clientCtx, clientStreamDesc, backendConn, fullMethodName)
+	if err != nil {
+		return err
+	}
+
+	s2cErr := make(chan error, 1)
+	c2sErr := make(chan error, 1)
+	go forward(serverStream, clientStream, s2cErr)
+	go forward(clientStream, serverStream, c2sErr)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-s2cErr:
+			if err == io.EOF {
+				// Client done sending; half-close the upstream and wait for
+				// its response(s) on the other direction.
+				clientStream.CloseSend()
+				continue
+			}
+			clientCancel()
+			return status.Errorf(codes.Internal, "proxy: relaying client->backend: %v", err)
+
+		case err := <-c2sErr:
+			serverStream.SetTrailer(clientStream.Trailer())
+			if err != io.EOF {
+				return err
+			}
+			return nil
+		}
+	}
+	return status.Error(codes.Internal, "proxy: handler reached unreachable state")
+}
+
+// halfStream is the subset of grpc.ServerStream/grpc.ClientStream forward
+// needs to relay one direction of a proxied call.
+type halfStream interface {
+	SendMsg(m interface{}) error
+	RecvMsg(m interface{}) error
+}
+
+// forward copies frames from src to dst until src returns an error (io.EOF
+// on a clean end), reporting that error on done.
+func forward(dst, src halfStream, done chan<- error) {
+	for {
+		f := &frame{}
+		if err := src.RecvMsg(f); err != nil {
+			done <- err
+			return
+		}
+		if err := dst.SendMsg(f); err != nil {
+			done <- err
+			return
+		}
+	}
+}
+
+// outgoingContext copies md from an incoming call into a fresh outgoing
+// context, for Directors that forward metadata verbatim. Header-based
+// routing typically starts here and then strips or rewrites the headers
+// it used to choose a backend.
+func outgoingContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, md.Copy())
+}
@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// BackendConfig names and locates one upstream RouterService instance a
+// Router can send a proxied call to.
+type BackendConfig struct {
+	// Name is how a Router refers to this backend.
+	Name string
+	// Target is a standard grpc.NewClient dial target, e.g.
+	// "dns:///router-west.internal:50052" or "unix:///run/arca/router.sock".
+	Target string
+	// TLS, if set, authenticates the backend connection with mTLS instead
+	// of dialing plaintext. The same *tls.Config - and the single pooled
+	// connection built from it - is reused for every proxied call, rather
+	// than handshaking per RPC.
+	TLS *tls.Config
+}
+
+// Router picks which configured backend(s) a proxied call should be sent
+// to, based on the call's method and incoming metadata (e.g. a tenant ID,
+// VRF, or geography header). It returns candidates in priority order so a
+// Director can fail over if the first choice isn't currently reachable.
+type Router interface {
+	Route(ctx context.Context, fullMethodName string) (backends []string, err error)
+}
+
+// HeaderRouter routes on a single metadata header's value, looking it up
+// in a static name->backend map; Default is used when the header is
+// absent or unmapped. This is the common case - tenant ID, VRF, or region
+// header set by whatever sits in front of the proxy - so it doesn't need
+// a full expression language.
+type HeaderRouter struct {
+	// Header is the (lowercase) metadata key to route on.
+	Header string
+	// Routes maps a header value to the backend name it should reach.
+	Routes map[string]string
+	// Default names the backend used when Header is absent or its value
+	// isn't in Routes. Empty rejects unmatched calls instead.
+	Default string
+}
+
+// Route implements Router.
+func (r HeaderRouter) Route(ctx context.Context, fullMethodName string) ([]string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(r.Header) {
+		if backend, ok := r.Routes[v]; ok {
+			if r.Default != "" && r.Default != backend {
+				return []string{backend, r.Default}, nil
+			}
+			return []string{backend}, nil
+		}
+	}
+	if r.Default != "" {
+		return []string{r.Default}, nil
+	}
+	return nil, fmt.Errorf("proxy: no backend mapped for header %q on %s", r.Header, fullMethodName)
+}
+
+// Pool lazily dials and caches one *grpc.ClientConn per configured
+// backend, so repeated proxied calls to the same backend reuse a single
+// pooled connection (and its mTLS handshake) instead of dialing per RPC.
+type Pool struct {
+	mu       sync.Mutex
+	backends map[string]BackendConfig
+	conns    map[string]*grpc.ClientConn
+}
+
+// NewPool returns a Pool over the given backends. Connections are dialed
+// lazily on first use, not eagerly here.
+func NewPool(backends []BackendConfig) *Pool {
+	p := &Pool{
+		backends: make(map[string]BackendConfig, len(backends)),
+		conns:    make(map[string]*grpc.ClientConn, len(backends)),
+	}
+	for _, b := range backends {
+		p.backends[b.Name] = b
+	}
+	return p
+}
+
+// conn returns the pooled connection for name, dialing it on first use.
+func (p *Pool) conn(name string) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if conn, ok := p.conns[name]; ok {
+		return conn, nil
+	}
+	cfg, ok := p.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("proxy: no backend named %q configured", name)
+	}
+
+	creds := insecure.NewCredentials()
+	if cfg.TLS != nil {
+		creds = credentials.NewTLS(cfg.TLS)
+	}
+	conn, err := grpc.NewClient(cfg.Target, grpc.WithTransportCredentials(creds), grpc.WithDefaultCallOptions(grpc.CallContentSubtype(Name)))
+	if err != nil {
+		return nil, fmt.Errorf("proxy: dialing backend %q at %s: %w", name, cfg.Target, err)
+	}
+	p.conns[name] = conn
+	return conn, nil
+}
+
+// Close closes every dialed backend connection, collecting (not
+// short-circuiting on) individual failures so one stuck backend doesn't
+// prevent the others from shutting down.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+	for name, conn := range p.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("backend %q: %w", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("proxy: closing backend connections: %v", errs)
+	}
+	return nil
+}
+
+// NewDirector builds a Director that asks router for candidate backends
+// and hands TransparentHandler the first one that's dialed and not
+// currently in transient failure, falling over to the next candidate
+// otherwise. The last candidate is always used regardless of its state,
+// so a routing decision never comes back empty-handed.
+func NewDirector(router Router, pool *Pool) Director {
+	return func(ctx context.Context, fullMethodName string) (context.Context, *grpc.ClientConn, error) {
+		names, err := router.Route(ctx, fullMethodName)
+		if err != nil {
+			return ctx, nil, fmt.Errorf("proxy: routing %s: %w", fullMethodName, err)
+		}
+		if len(names) == 0 {
+			return ctx, nil, fmt.Errorf("proxy: no backend candidates for %s", fullMethodName)
+		}
+
+		var lastErr error
+		for i, name := range names {
+			conn, err := pool.conn(name)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if i < len(names)-1 && conn.GetState() == connectivity.TransientFailure {
+				lastErr = fmt.Errorf("backend %q is in transient failure", name)
+				continue
+			}
+			return outgoingContext(ctx), conn, nil
+		}
+		return ctx, nil, fmt.Errorf("proxy: no reachable backend for %s: %w", fullMethodName, lastErr)
+	}
+}
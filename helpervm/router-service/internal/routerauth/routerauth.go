@@ -0,0 +1,145 @@
+// Package routerauth builds the mTLS grpc.ServerOptions RouterService
+// requires before accepting RPCs, so any client that can reach the vsock
+// or TCP listener can no longer mutate forwarding state without a cert the
+// router trusts. It supports two ways to obtain that trust: a static CA
+// bundle plus server cert/key read from disk (StaticConfig), or a SPIFFE
+// Workload API source that fetches and auto-rotates the router's own
+// X.509-SVID (see spiffe.go). Either way, every connection is additionally
+// authorized against an allow-list of identities via a unary/stream
+// interceptor, so holding any cert the CA signs isn't by itself enough to
+// drive router-control RPCs.
+package routerauth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// StaticConfig points at a PEM CA bundle and server cert/key on disk, the
+// mode used when a router isn't deployed in a SPIFFE mesh.
+type StaticConfig struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+// AllowedIdentities is the set of SPIFFE IDs (or other URI-SAN identities)
+// permitted to call RouterService RPCs. A nil/empty list allows any
+// identity the CA bundle verifies, for deployments that terminate mTLS at
+// the transport but haven't adopted per-identity authorization yet.
+type AllowedIdentities []string
+
+// allowed reports whether id appears in the allow-list.
+func (a AllowedIdentities) allowed(id string) bool {
+	if len(a) == 0 {
+		return true
+	}
+	for _, want := range a {
+		if want == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ServerOptions loads cfg's CA bundle and server cert/key and returns the
+// grpc.ServerOptions that require and verify a client certificate on every
+// connection, authorizing each one against allowed.
+func ServerOptions(cfg StaticConfig, allowed AllowedIdentities) ([]grpc.ServerOption, error) {
+	caPEM, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("routerauth: reading CA bundle: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("routerauth: no certificates found in %s", cfg.CAFile)
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("routerauth: loading server cert/key: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    caPool,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	return serverOptions(credentials.NewTLS(tlsConfig), allowed), nil
+}
+
+// serverOptions wraps creds with the transport credentials option plus the
+// unary/stream interceptors that enforce allowed against each connection's
+// peer identity.
+func serverOptions(creds credentials.TransportCredentials, allowed AllowedIdentities) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.Creds(creds),
+		grpc.UnaryInterceptor(unaryAuthInterceptor(allowed)),
+		grpc.StreamInterceptor(streamAuthInterceptor(allowed)),
+	}
+}
+
+// peerIdentity extracts the authenticated identity (the first URI SAN,
+// which for a SPIFFE SVID is its spiffe:// ID) from p's verified TLS peer
+// certificate.
+func peerIdentity(p *peer.Peer) (string, error) {
+	if p == nil || p.AuthInfo == nil {
+		return "", fmt.Errorf("no peer authentication info")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return "", fmt.Errorf("peer did not authenticate via TLS")
+	}
+	chains := tlsInfo.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return "", fmt.Errorf("no verified peer certificate")
+	}
+	leaf := chains[0][0]
+	if len(leaf.URIs) == 0 {
+		return "", fmt.Errorf("peer certificate has no URI SAN identity")
+	}
+	return leaf.URIs[0].String(), nil
+}
+
+// unaryAuthInterceptor rejects a unary RPC unless its caller's identity is
+// in allowed.
+func unaryAuthInterceptor(allowed AllowedIdentities) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		p, _ := peer.FromContext(ctx)
+		id, err := peerIdentity(p)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "routerauth: %v", err)
+		}
+		if !allowed.allowed(id) {
+			return nil, status.Errorf(codes.PermissionDenied, "routerauth: identity %q is not an authorized router-control identity", id)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamAuthInterceptor is unaryAuthInterceptor's streaming-RPC
+// equivalent.
+func streamAuthInterceptor(allowed AllowedIdentities) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		p, _ := peer.FromContext(ss.Context())
+		id, err := peerIdentity(p)
+		if err != nil {
+			return status.Errorf(codes.Unauthenticated, "routerauth: %v", err)
+		}
+		if !allowed.allowed(id) {
+			return status.Errorf(codes.PermissionDenied, "routerauth: identity %q is not an authorized router-control identity", id)
+		}
+		return handler(srv, ss)
+	}
+}
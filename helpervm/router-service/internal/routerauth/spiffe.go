@@ -0,0 +1,55 @@
+package routerauth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// SPIFFEConfig points at a Workload API socket and names the trust domain
+// a router's peers are expected to belong to.
+type SPIFFEConfig struct {
+	WorkloadAPIAddr string // e.g. "unix:///run/spire/sockets/agent.sock"
+	TrustDomain     string // e.g. "arca.internal"
+}
+
+// SPIFFESource wraps a workloadapi.X509Source so callers can Close it on
+// shutdown alongside the gRPC server it backs. It fetches the router's own
+// X.509-SVID and the trust bundle for TrustDomain once on open, and keeps
+// both automatically up to date in the background for as long as it stays
+// open - routers in a mesh authenticate to each other off of this instead
+// of a static keypair that has to be provisioned and rotated by hand.
+type SPIFFESource struct {
+	*workloadapi.X509Source
+}
+
+// NewSPIFFESource opens a Workload API client (typically talking to a
+// local SPIRE agent) and blocks until it has fetched an initial SVID and
+// trust bundle.
+func NewSPIFFESource(ctx context.Context, cfg SPIFFEConfig) (*SPIFFESource, error) {
+	source, err := workloadapi.NewX509Source(ctx,
+		workloadapi.WithClientOptions(workloadapi.WithAddr(cfg.WorkloadAPIAddr)))
+	if err != nil {
+		return nil, fmt.Errorf("routerauth: connecting to SPIFFE Workload API: %w", err)
+	}
+	return &SPIFFESource{X509Source: source}, nil
+}
+
+// ServerOptions builds the grpc.ServerOptions for mTLS authenticated off
+// of s's rotating SVID and trust bundle, accepting any peer SVID issued
+// for trustDomain and further authorizing the connection against allowed
+// the same way the static-config mode does.
+func (s *SPIFFESource) ServerOptions(trustDomain string, allowed AllowedIdentities) ([]grpc.ServerOption, error) {
+	td, err := spiffeid.TrustDomainFromString(trustDomain)
+	if err != nil {
+		return nil, fmt.Errorf("routerauth: invalid trust domain %q: %w", trustDomain, err)
+	}
+
+	tlsConfig := tlsconfig.MTLSServerConfig(s.X509Source, s.X509Source, tlsconfig.AuthorizeMemberOf(td))
+	return serverOptions(credentials.NewTLS(tlsConfig), allowed), nil
+}
@@ -0,0 +1,122 @@
+// Package rpclog keeps a bounded, in-memory ring buffer of recently-served
+// RouterService RPCs - method, peer address, duration, and outcome - for
+// the debug statusz page to render. It exists purely for operator
+// visibility; nothing in the router depends on what it records.
+package rpclog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// Entry is one recorded RPC.
+type Entry struct {
+	Method   string
+	Peer     string
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// defaultCapacity bounds the ring buffer so a busy router's statusz page
+// stays a fixed, small size rather than growing with uptime.
+const defaultCapacity = 200
+
+// Recorder is a fixed-capacity ring buffer of recent Entries, safe for
+// concurrent use by the interceptors below and by the statusz page reading
+// Entries concurrently with in-flight RPCs.
+type Recorder struct {
+	mu       sync.Mutex
+	entries  []Entry
+	next     int
+	filled   bool
+	capacity int
+}
+
+// NewRecorder returns a Recorder holding up to capacity Entries; capacity
+// <= 0 uses defaultCapacity.
+func NewRecorder(capacity int) *Recorder {
+	if capacity <= 0 {
+		capacity = defaultCapacity
+	}
+	return &Recorder{entries: make([]Entry, capacity), capacity: capacity}
+}
+
+// record appends e, overwriting the oldest entry once the buffer is full.
+func (r *Recorder) record(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % r.capacity
+	if r.next == 0 {
+		r.filled = true
+	}
+}
+
+// Entries returns every recorded Entry, newest first.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if !r.filled {
+		out := make([]Entry, n)
+		for i := 0; i < n; i++ {
+			out[i] = r.entries[n-1-i]
+		}
+		return out
+	}
+
+	out := make([]Entry, r.capacity)
+	for i := 0; i < r.capacity; i++ {
+		out[i] = r.entries[(n-1-i+r.capacity)%r.capacity]
+	}
+	return out
+}
+
+// peerAddr extracts the caller's network address from ctx, or "" if
+// unavailable (e.g. an in-process call with no peer info attached).
+func peerAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	return p.Addr.String()
+}
+
+// UnaryServerInterceptor records every unary RPC r observes.
+func (r *Recorder) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		r.record(Entry{
+			Method:   info.FullMethod,
+			Peer:     peerAddr(ctx),
+			Start:    start,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records every streaming RPC r observes, logging
+// once the stream ends with its total duration.
+func (r *Recorder) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		r.record(Entry{
+			Method:   info.FullMethod,
+			Peer:     peerAddr(ss.Context()),
+			Start:    start,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+		return err
+	}
+}
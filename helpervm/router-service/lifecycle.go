@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+)
+
+// gracefulStopTimeout bounds how long Run waits for grpcServer.GracefulStop
+// to drain in-flight RouterService RPCs before falling back to an
+// immediate Stop(), so a stuck stream can't block a rolling restart
+// forever.
+const gracefulStopTimeout = 30 * time.Second
+
+// Run starts grpcServer on lis alongside any extra background tasks, and
+// blocks until ctx is canceled or one of them returns an error. On
+// cancellation it drains in-flight RPCs via GracefulStop, falling back to
+// Stop() if that doesn't finish within gracefulStopTimeout. It's exposed as
+// its own lifecycle hook - rather than folded into main - so tests can
+// spin up a real router instance against an in-memory listener (e.g.
+// bufconn) and tear it down deterministically instead of leaking a
+// goroutine per test.
+//
+// tasks are run alongside the gRPC server under the same errgroup (health
+// checks, metrics exporters, config watchers) and are expected to return
+// once ctx is canceled.
+func Run(ctx context.Context, grpcServer *grpc.Server, lis net.Listener, tasks ...func(context.Context) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		if err := grpcServer.Serve(lis); err != nil {
+			return fmt.Errorf("grpc serve: %w", err)
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		<-ctx.Done()
+		stopGRPCServer(grpcServer)
+		return nil
+	})
+
+	for _, task := range tasks {
+		task := task
+		g.Go(func() error {
+			return task(ctx)
+		})
+	}
+
+	return g.Wait()
+}
+
+// stopGRPCServer drains in-flight RPCs via GracefulStop, forcing an
+// immediate Stop() if draining takes longer than gracefulStopTimeout.
+func stopGRPCServer(grpcServer *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		log.Println("gRPC server drained in-flight RPCs and stopped")
+	case <-time.After(gracefulStopTimeout):
+		log.Printf("gRPC server did not drain within %s, forcing stop", gracefulStopTimeout)
+		grpcServer.Stop()
+	}
+}
@@ -3,28 +3,149 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
-	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
-	"github.com/mdlayher/vsock"
+	"github.com/Liquescent-Development/arca/helpervm/router-service/internal/dataplane"
+	"github.com/Liquescent-Development/arca/helpervm/router-service/internal/proxy"
+	"github.com/Liquescent-Development/arca/helpervm/router-service/internal/routerauth"
+	"github.com/Liquescent-Development/arca/helpervm/router-service/internal/rpclog"
 	pb "github.com/Liquescent-Development/arca/helpervm/router-service/proto"
+	"github.com/mdlayher/vsock"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
+// dataplaneHealthPollInterval is how often the background health task
+// re-checks configured dataplane plugins and updates their grpc.health.v1
+// serving status.
+const dataplaneHealthPollInterval = 10 * time.Second
+
 func main() {
 	vsockPort := flag.Uint("vsock-port", 50052, "vsock port to listen on")
+	tlsCAFile := flag.String("tls-ca", "", "PEM CA bundle for verifying RouterService clients (enables mTLS)")
+	tlsCertFile := flag.String("tls-cert", "", "PEM server certificate (required with -tls-ca)")
+	tlsKeyFile := flag.String("tls-key", "", "PEM server private key (required with -tls-ca)")
+	spiffeWorkloadAPIAddr := flag.String("spiffe-workload-api", "", "SPIFFE Workload API address (e.g. unix:///run/spire/sockets/agent.sock); takes precedence over -tls-ca")
+	spiffeTrustDomain := flag.String("spiffe-trust-domain", "", "SPIFFE trust domain peers must belong to (required with -spiffe-workload-api)")
+	allowedIdentities := flag.String("tls-allowed-identities", "", "comma-separated SPIFFE IDs/URI SANs authorized to call RouterService; empty allows any identity the CA/trust domain verifies")
+	dataplanePlugins := flag.String("dataplane-plugins", "", "comma-separated name=target pairs of out-of-process dataplane plugins to dial (e.g. vpp=unix:///run/arca/plugins/vpp.sock), dispatched to by name via GetDataplaneStats and friends")
+	otelEndpoint := flag.String("otel-endpoint", "", "OTLP/gRPC collector endpoint (host:port) to export RouterService RPC spans to; tracing is off if unset")
+	debugAddr := flag.String("debug-addr", "", "address to serve pprof and the statusz debug page on (e.g. 127.0.0.1:6060); disabled if unset")
+	proxyBackends := flag.String("proxy-backends", "", "comma-separated name=target pairs of upstream RouterService instances to federate; when set, this process proxies every RouterService RPC to one of them instead of serving locally")
+	proxyRouteHeader := flag.String("proxy-route-header", "x-arca-route", "incoming metadata header used to pick a proxy backend by value (tenant ID, VRF, region, ...)")
+	proxyRoutes := flag.String("proxy-routes", "", "comma-separated value=backend pairs mapping -proxy-route-header values to a -proxy-backends name")
+	proxyDefaultBackend := flag.String("proxy-default-backend", "", "backend name used when -proxy-route-header is absent or its value isn't in -proxy-routes; empty rejects unmatched calls")
 	flag.Parse()
 
 	log.Println("Starting Arca Router Service...")
 
-	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	// Cancel ctx on SIGINT/SIGTERM so Run can drain in-flight RPCs instead
+	// of the process dying mid-stream.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := setupTracing(ctx, *otelEndpoint)
+	if err != nil {
+		log.Fatalf("Failed to configure OTel tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	var allowed routerauth.AllowedIdentities
+	if *allowedIdentities != "" {
+		allowed = strings.Split(*allowedIdentities, ",")
+	}
+
+	serverOpts, closeAuth, err := buildServerOptions(ctx, *spiffeWorkloadAPIAddr, *spiffeTrustDomain, *tlsCAFile, *tlsCertFile, *tlsKeyFile, allowed)
+	if err != nil {
+		log.Fatalf("Failed to configure RouterService authentication: %v", err)
+	}
+	defer closeAuth()
+
+	var tasks []func(context.Context) error
+
+	// otelgrpc's stats handler and the rpclog interceptors are additive to
+	// whatever buildServerOptions already set (a single grpc.UnaryInterceptor
+	// for auth, at most) - grpc.Server only panics on a second
+	// grpc.UnaryInterceptor/StreamInterceptor, not on mixing those with
+	// Chain*Interceptor or a stats handler.
+	recorder := rpclog.NewRecorder(0)
+	serverOpts = append(serverOpts,
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(recorder.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(recorder.StreamServerInterceptor()),
+	)
+
+	var grpcServer *grpc.Server
+	if *proxyBackends != "" {
+		// Proxy mode: every RouterService RPC is relayed to a federated
+		// backend rather than served locally, so nothing else that
+		// assumes the server's normal proto codec (reflection, channelz,
+		// health, the local RouterServer itself) can be registered
+		// alongside it - see internal/proxy's doc comment.
+		pool, director, err := buildProxyDirector(*proxyBackends, *proxyRouteHeader, *proxyRoutes, *proxyDefaultBackend)
+		if err != nil {
+			log.Fatalf("Invalid proxy configuration: %v", err)
+		}
+		defer pool.Close()
+
+		proxyOpts := append(append([]grpc.ServerOption{}, serverOpts...),
+			grpc.ForceServerCodec(proxy.AsCodec()),
+			grpc.UnknownServiceHandler(proxy.TransparentHandler(director)),
+		)
+		grpcServer = grpc.NewServer(proxyOpts...)
+		log.Printf("Running in federation proxy mode, routing on header %q", *proxyRouteHeader)
+	} else {
+		var routerOpts []RouterServerOption
+		var dpManager *dataplane.Manager
+		if *dataplanePlugins != "" {
+			configs, err := parseDataplanePlugins(*dataplanePlugins)
+			if err != nil {
+				log.Fatalf("Invalid -dataplane-plugins: %v", err)
+			}
+			dpManager, err = dataplane.NewManager(ctx, configs)
+			if err != nil {
+				log.Fatalf("Failed to configure dataplane plugins: %v", err)
+			}
+			defer dpManager.Close()
+			log.Printf("Configured %d dataplane plugin(s)", len(configs))
+			routerOpts = append(routerOpts, WithDataplaneManager(dpManager))
+		}
 
-	// Create and register router service
-	routerServer := NewRouterServer()
-	pb.RegisterRouterServiceServer(grpcServer, routerServer)
+		grpcServer = grpc.NewServer(serverOpts...)
+
+		routerServer := NewRouterServer(routerOpts...)
+		pb.RegisterRouterServiceServer(grpcServer, routerServer)
+
+		// Reflection and channelz make the server introspectable with
+		// grpcurl/grpcdebug without any arca-specific tooling.
+		reflection.Register(grpcServer)
+		service.RegisterChannelzServiceToServer(grpcServer)
+
+		// The overall server is always SERVING once it starts accepting
+		// RPCs; per-plugin status is kept current by dataplaneHealthTask
+		// below so grpc_health_probe --service=dataplane.<name> reflects a
+		// plugin that's gone down without taking the whole router out of
+		// rotation.
+		healthServer := health.NewServer()
+		grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
+		healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+		if dpManager != nil {
+			tasks = append(tasks, dataplaneHealthTask(healthServer, dpManager))
+		}
+
+		if *debugAddr != "" {
+			tasks = append(tasks, ServeDebugMux(*debugAddr, NewDebugMux(routerServer, recorder)))
+		}
+	}
 
 	// Listen on vsock using mdlayher/vsock library
 	listener, err := vsock.Listen(uint32(*vsockPort), nil)
@@ -35,25 +156,135 @@ func main() {
 
 	log.Printf("Router service listening on vsock port %d", *vsockPort)
 
-	// Handle graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if err := Run(ctx, grpcServer, listener, tasks...); err != nil {
+		log.Fatalf("Router service exited with error: %v", err)
+	}
+
+	log.Println("Router service stopped")
+}
+
+// buildServerOptions picks an authentication mode for the gRPC server:
+// SPIFFE Workload API if spiffeAddr is set (takes precedence, since a mesh
+// deployment's SVID rotates and a static cert doesn't), else static mTLS
+// if caFile is set, else no transport authentication at all - a loud
+// warning either way, since an unauthenticated RouterService lets any
+// network peer mutate forwarding state. The returned close func releases
+// the SPIFFE Workload API connection (a no-op in the other two modes) and
+// must be deferred by the caller.
+func buildServerOptions(ctx context.Context, spiffeAddr, trustDomain, caFile, certFile, keyFile string, allowed routerauth.AllowedIdentities) ([]grpc.ServerOption, func(), error) {
+	noop := func() {}
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	switch {
+	case spiffeAddr != "":
+		if trustDomain == "" {
+			return nil, noop, fmt.Errorf("-spiffe-trust-domain is required with -spiffe-workload-api")
+		}
+		source, err := routerauth.NewSPIFFESource(ctx, routerauth.SPIFFEConfig{
+			WorkloadAPIAddr: spiffeAddr,
+			TrustDomain:     trustDomain,
+		})
+		if err != nil {
+			return nil, noop, err
+		}
+		opts, err := source.ServerOptions(trustDomain, allowed)
+		if err != nil {
+			source.Close()
+			return nil, noop, err
+		}
+		log.Printf("RouterService authenticating via SPIFFE Workload API (trust domain %s)", trustDomain)
+		return opts, func() { source.Close() }, nil
 
-	go func() {
-		<-sigChan
-		log.Println("Received shutdown signal, stopping server...")
-		grpcServer.GracefulStop()
-		cancel()
-	}()
+	case caFile != "":
+		if certFile == "" || keyFile == "" {
+			return nil, noop, fmt.Errorf("-tls-cert and -tls-key are required with -tls-ca")
+		}
+		opts, err := routerauth.ServerOptions(routerauth.StaticConfig{
+			CAFile:   caFile,
+			CertFile: certFile,
+			KeyFile:  keyFile,
+		}, allowed)
+		if err != nil {
+			return nil, noop, err
+		}
+		log.Println("RouterService authenticating via static mTLS")
+		return opts, noop, nil
 
-	// Start serving
-	if err := grpcServer.Serve(listener); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
+	default:
+		log.Println("Warning: no -tls-ca or -spiffe-workload-api configured, RouterService RPCs are unauthenticated")
+		return nil, noop, nil
 	}
+}
 
-	<-ctx.Done()
-	log.Println("Router service stopped")
+// parseDataplanePlugins parses -dataplane-plugins' "name=target,..." format
+// into the PluginConfigs dataplane.NewManager expects.
+func parseDataplanePlugins(s string) ([]dataplane.PluginConfig, error) {
+	entries := strings.Split(s, ",")
+	configs := make([]dataplane.PluginConfig, 0, len(entries))
+	for _, entry := range entries {
+		name, target, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || target == "" {
+			return nil, fmt.Errorf("malformed plugin entry %q (want name=target)", entry)
+		}
+		configs = append(configs, dataplane.PluginConfig{Name: name, Target: target})
+	}
+	return configs, nil
+}
+
+// buildProxyDirector assembles the proxy.Pool and proxy.Director for
+// federation proxy mode from their flag-string forms: backendsFlag is
+// "name=target,..." (see parseDataplanePlugins for the same shape), and
+// routesFlag is "headerValue=backendName,...". The caller owns closing
+// the returned Pool.
+func buildProxyDirector(backendsFlag, routeHeader, routesFlag, defaultBackend string) (*proxy.Pool, proxy.Director, error) {
+	var backends []proxy.BackendConfig
+	for _, entry := range strings.Split(backendsFlag, ",") {
+		name, target, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || target == "" {
+			return nil, nil, fmt.Errorf("malformed -proxy-backends entry %q (want name=target)", entry)
+		}
+		backends = append(backends, proxy.BackendConfig{Name: name, Target: target})
+	}
+
+	routes := make(map[string]string)
+	if routesFlag != "" {
+		for _, entry := range strings.Split(routesFlag, ",") {
+			value, backend, ok := strings.Cut(entry, "=")
+			if !ok || value == "" || backend == "" {
+				return nil, nil, fmt.Errorf("malformed -proxy-routes entry %q (want value=backend)", entry)
+			}
+			routes[value] = backend
+		}
+	}
+
+	pool := proxy.NewPool(backends)
+	router := proxy.HeaderRouter{Header: routeHeader, Routes: routes, Default: defaultBackend}
+	return pool, proxy.NewDirector(router, pool), nil
+}
+
+// dataplaneHealthTask polls mgr's plugins every dataplaneHealthPollInterval
+// and mirrors the result into healthServer under a per-plugin service name
+// ("dataplane.<name>"), so grpc_health_probe against that name reflects a
+// plugin that's stopped responding without restarting the router.
+func dataplaneHealthTask(healthServer *health.Server, mgr *dataplane.Manager) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(dataplaneHealthPollInterval)
+		defer ticker.Stop()
+
+		for {
+			for name, err := range mgr.HealthCheck(ctx) {
+				status := grpc_health_v1.HealthCheckResponse_SERVING
+				if err != nil {
+					status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+					log.Printf("dataplane plugin %q failed health check: %v", name, err)
+				}
+				healthServer.SetServingStatus("dataplane."+name, status)
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
 }
@@ -7,22 +7,86 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
-	"github.com/vishvananda/netlink"
+	"github.com/Liquescent-Development/arca/helpervm/router-service/internal/dataplane"
+	"github.com/Liquescent-Development/arca/helpervm/router-service/internal/firewall"
 	pb "github.com/Liquescent-Development/arca/helpervm/router-service/proto"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
 	"google.golang.org/grpc"
 )
 
 // RouterServer implements the RouterService gRPC service
 type RouterServer struct {
 	pb.UnimplementedRouterServiceServer
-	mu          sync.RWMutex
-	vlans       map[uint32]*VLANInfo // vlan_id -> VLANInfo
-	dnsEntries  map[uint32]map[string]string // vlan_id -> hostname -> IP
-	startTime   time.Time
+	mu             sync.RWMutex
+	vlans          map[uint32]*VLANInfo         // vlan_id -> VLANInfo
+	vxlans         map[uint32]*VXLANInfo        // vni -> VXLANInfo
+	dnsEntries     map[uint32]map[string]string // vlan_id or vni -> hostname -> IP
+	endpoints      map[endpointKey]*EndpointInfo
+	endpointSeq    uint64
+	dnsmasqProcs   map[uint32]*dnsmasqInstance // vlan_id or vni -> supervised dnsmasq
+	dhcpConfigs    map[uint32]*DHCPConfig      // vlan_id or vni -> DHCP range/options
+	firewall       firewall.Backend
+	portMaps       map[uint32][]firewall.PortMap    // vlan_id or vni -> port mappings
+	egressPolicies map[uint32][]firewall.EgressRule // vlan_id or vni -> ordered egress policy, see SetEgressPolicy
+	routes         map[uint64]*RouteInfo            // route_id -> RouteInfo, see AddRoute
+	routeSeq       uint64
+	dataplane      *dataplane.Manager // configured out-of-process forwarding plugins, see GetDataplaneStats
+	startTime      time.Time
+}
+
+// RouterServerOption configures a RouterServer at NewRouterServer time.
+type RouterServerOption func(*RouterServer)
+
+// WithDataplaneManager attaches mgr's configured plugins, letting
+// RouterService RPCs dispatch to an out-of-process forwarding engine
+// instead of (or alongside) the built-in netlink/firewall dataplane.
+func WithDataplaneManager(mgr *dataplane.Manager) RouterServerOption {
+	return func(s *RouterServer) { s.dataplane = mgr }
+}
+
+// RouteInfo tracks a static route AddRoute installed, so DeleteRoute and
+// ListRoutes can find it again and deleteVLANLocked can purge routes left
+// pointing at an interface that's about to be destroyed. Dst and Gateway
+// are kept in their original string form rather than net.IPNet/net.IP so a
+// default route (Dst == "") round-trips through ListRoutes without special
+// casing.
+type RouteInfo struct {
+	ID        uint64
+	Dst       string // CIDR, or "" for a default route
+	Gateway   string // empty for an on-link route (SCOPE_LINK)
+	Interface string
+	Metric    int
+	Scope     netlink.Scope
+	Table     int
+	CreatedAt time.Time
+}
+
+// endpointKey identifies a macvlan/ipvlan/macvtap slave that CreateEndpoint
+// moved into a container network namespace, so DeleteEndpoint can find it
+// again.
+type endpointKey struct {
+	VlanID    uint32
+	NetnsPath string
+	IfName    string
+}
+
+// EndpointInfo tracks a slave interface CreateEndpoint created. ParentIndex
+// is recorded so DeleteEndpoint can verify - before deleting anything
+// inside the target namespace - that the interface named IfName there is
+// still the same slave device, mirroring libnetwork's delVlanLink pattern.
+type EndpointInfo struct {
+	ParentIndex int
+	Driver      string
+	Mode        string
+	CreatedAt   time.Time
 }
 
 // VLANInfo tracks information about a VLAN interface
@@ -37,6 +101,51 @@ type VLANInfo struct {
 	CreatedAt     time.Time
 }
 
+// defaultVXLANPort is the IANA-assigned VXLAN UDP destination port, used
+// unless a request asks for a non-standard one.
+const defaultVXLANPort = 4789
+
+// VXLANInfo tracks information about a VXLAN overlay interface. It mirrors
+// VLANInfo's subnet/gateway/NAT fields so NAT, DNS and port-mapping code
+// paths can treat a VXLAN network identically to a VLAN sub-interface -
+// see networkGatewayLocked.
+type VXLANInfo struct {
+	VNI           uint32
+	InterfaceName string
+	DstPort       uint16
+	LocalIP       string
+	Gateway       string
+	Subnet        string
+	NetworkName   string
+	NATEnabled    bool
+	Domain        string
+	VTEPs         map[string]bool // remote VTEP IP -> present
+	CreatedAt     time.Time
+}
+
+// dnsmasqInstance tracks a supervised, per-network dnsmasq process so a DNS
+// or DHCP config change can SIGHUP it in place instead of restarting - the
+// old behavior killed the single global dnsmasq on every change, dropping
+// in-flight queries and DHCP leases for every other VLAN.
+type dnsmasqInstance struct {
+	Cmd      *exec.Cmd
+	PidFile  string
+	ConfFile string
+	IfName   string
+}
+
+// DHCPConfig holds the DHCP range and options ConfigureDHCP applied to a
+// VLAN or VXLAN network, rendered into its dnsmasq config alongside the
+// network's DNS entries.
+type DHCPConfig struct {
+	RangeStart   string
+	RangeEnd     string
+	LeaseTime    string
+	DNSServers   []string
+	NTPServers   []string
+	StaticLeases []*pb.StaticLease
+}
+
 // getParentInterface finds the first real physical/virtual network interface to use as the parent for VLANs
 // Skips pseudo-interfaces like loopback, tunnels, bridges, traffic shaping queues
 func (s *RouterServer) getParentInterface() (netlink.Link, error) {
@@ -69,14 +178,14 @@ func (s *RouterServer) getParentInterface() (netlink.Link, error) {
 
 	// Pseudo-interface types to skip
 	pseudoTypes := map[string]bool{
-		"ipip":    true, // IP-in-IP tunnel
-		"sit":     true, // IPv6-in-IPv4 tunnel
-		"ip6tnl":  true, // IPv6 tunnel
-		"gre":     true, // GRE tunnel
-		"gretap":  true, // GRE tap tunnel
-		"erspan":  true, // ERSPAN tunnel
-		"vti":     true, // VTI tunnel
-		"tuntap":  true, // TAP/TUN (OVS creates these)
+		"ipip":   true, // IP-in-IP tunnel
+		"sit":    true, // IPv6-in-IPv4 tunnel
+		"ip6tnl": true, // IPv6 tunnel
+		"gre":    true, // GRE tunnel
+		"gretap": true, // GRE tap tunnel
+		"erspan": true, // ERSPAN tunnel
+		"vti":    true, // VTI tunnel
+		"tuntap": true, // TAP/TUN (OVS creates these)
 	}
 
 	// Find first real interface (physical or vmnet virtual)
@@ -304,6 +413,15 @@ func (s *RouterServer) deleteVLANLocked(vlanID uint32) error {
 	parentName := parent.Attrs().Name
 	vlanName := fmt.Sprintf("%s.%d", parentName, vlanID)
 
+	// Purge any routes AddRoute installed against this VLAN's interface -
+	// the kernel drops them on its own once the interface goes away, but
+	// s.routes would otherwise keep reporting them via ListRoutes forever.
+	for id, info := range s.routes {
+		if info.Interface == vlanName {
+			delete(s.routes, id)
+		}
+	}
+
 	// Get VLAN info
 	vlanInfo, exists := s.vlans[vlanID]
 
@@ -326,6 +444,7 @@ func (s *RouterServer) deleteVLANLocked(vlanID uint32) error {
 		log.Printf("VLAN interface %s not found (already deleted?)", vlanName)
 		delete(s.vlans, vlanID)
 		delete(s.dnsEntries, vlanID)
+		delete(s.egressPolicies, vlanID)
 		return nil
 	}
 
@@ -339,6 +458,7 @@ func (s *RouterServer) deleteVLANLocked(vlanID uint32) error {
 	// Remove from tracking
 	delete(s.vlans, vlanID)
 	delete(s.dnsEntries, vlanID)
+	delete(s.egressPolicies, vlanID)
 
 	return nil
 }
@@ -356,299 +476,1531 @@ func (s *RouterServer) deleteVLANResponseLocked(vlanID uint32) *pb.DeleteVLANRes
 	}
 }
 
-// ConfigureNAT configures NAT (MASQUERADE) for a network
-func (s *RouterServer) ConfigureNAT(ctx context.Context, req *pb.ConfigureNATRequest) (*pb.ConfigureNATResponse, error) {
-	log.Printf("ConfigureNAT: vlanID=%d subnet=%s", req.VlanId, req.SourceSubnet)
+// parentLocalIP returns the first global-unicast IPv4 address configured on
+// parent, used as a VXLAN tunnel's local endpoint when the caller doesn't
+// pin one explicitly.
+func parentLocalIP(parent netlink.Link) (string, error) {
+	addrs, err := netlink.AddrList(parent, netlink.FAMILY_V4)
+	if err != nil {
+		return "", fmt.Errorf("failed to list addresses on %s: %w", parent.Attrs().Name, err)
+	}
+	for _, addr := range addrs {
+		if addr.IP.IsGlobalUnicast() {
+			return addr.IP.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no global-unicast IPv4 address found on %s", parent.Attrs().Name)
+}
+
+// CreateVXLANNetwork creates a VXLAN overlay interface on the helper VM -
+// the VXLAN peer to CreateVLAN's 802.1Q sub-interfaces. Unlike a VLAN,
+// reachability to remote VTEPs doesn't depend on the parent NIC's L2
+// broadcast domain, so a VXLAN network can span multiple hosts.
+func (s *RouterServer) CreateVXLANNetwork(ctx context.Context, req *pb.CreateVXLANRequest) (*pb.CreateVXLANResponse, error) {
+	log.Printf("CreateVXLANNetwork: vni=%d subnet=%s gateway=%s network=%s remoteVteps=%d",
+		req.Vni, req.Subnet, req.Gateway, req.NetworkName, len(req.RemoteVteps))
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if err := s.configureNATLocked(req.VlanId, req.SourceSubnet); err != nil {
-		return &pb.ConfigureNATResponse{
+	// Valid range for a 24-bit VXLAN Network Identifier.
+	if req.Vni == 0 || req.Vni > 16777215 {
+		return &pb.CreateVXLANResponse{
 			Success: false,
-			Error:   err.Error(),
+			Error:   fmt.Sprintf("invalid VNI %d (must be 1-16777215)", req.Vni),
 		}, nil
 	}
 
-	// Update VLAN info
-	if vlanInfo, exists := s.vlans[req.VlanId]; exists {
-		vlanInfo.NATEnabled = true
+	if _, exists := s.vxlans[req.Vni]; exists {
+		log.Printf("VXLAN %d already exists, will recreate", req.Vni)
+		s.deleteVXLANLocked(req.Vni)
 	}
 
-	return &pb.ConfigureNATResponse{
-		Success: true,
-	}, nil
-}
-
-// configureNATLocked configures NAT (must hold lock)
-func (s *RouterServer) configureNATLocked(vlanID uint32, sourceSubnet string) error {
-	// Add MASQUERADE rule for outbound traffic from this subnet
-	cmd := exec.Command("iptables", "-t", "nat", "-A", "POSTROUTING",
-		"-s", sourceSubnet,
-		"-j", "MASQUERADE",
-		"-m", "comment", "--comment", fmt.Sprintf("vlan-%d", vlanID))
+	parent, err := s.getParentInterface()
+	if err != nil {
+		return &pb.CreateVXLANResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to find parent interface: %v", err),
+		}, nil
+	}
 
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to configure NAT: %v, output: %s", err, string(output))
+	localIP, err := parentLocalIP(parent)
+	if err != nil {
+		return &pb.CreateVXLANResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to derive local tunnel endpoint: %v", err),
+		}, nil
 	}
 
-	log.Printf("Configured NAT for subnet %s (VLAN %d)", sourceSubnet, vlanID)
+	dstPort := uint16(defaultVXLANPort)
+	if req.DstPort > 0 {
+		dstPort = uint16(req.DstPort)
+	}
 
-	return nil
-}
+	vxlanName := fmt.Sprintf("vxlan%d", req.Vni)
 
-// RemoveNAT removes NAT configuration for a network
-func (s *RouterServer) RemoveNAT(ctx context.Context, req *pb.RemoveNATRequest) (*pb.RemoveNATResponse, error) {
-	log.Printf("RemoveNAT: vlanID=%d subnet=%s", req.VlanId, req.SourceSubnet)
+	vxlan := &netlink.Vxlan{
+		LinkAttrs: netlink.LinkAttrs{
+			Name: vxlanName,
+		},
+		VxlanId:      int(req.Vni),
+		VtepDevIndex: parent.Attrs().Index,
+		SrcAddr:      net.ParseIP(localIP),
+		Port:         int(dstPort),
+		Learning:     false,
+	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if req.Mtu > 0 {
+		vxlan.MTU = int(req.Mtu)
+	}
 
-	if err := s.removeNATLocked(req.VlanId, req.SourceSubnet); err != nil {
-		return &pb.RemoveNATResponse{
+	if err := netlink.LinkAdd(vxlan); err != nil {
+		return &pb.CreateVXLANResponse{
 			Success: false,
-			Error:   err.Error(),
+			Error:   fmt.Sprintf("failed to create VXLAN interface: %v", err),
 		}, nil
 	}
 
-	// Update VLAN info
-	if vlanInfo, exists := s.vlans[req.VlanId]; exists {
-		vlanInfo.NATEnabled = false
+	log.Printf("Created VXLAN interface %s (VNI %d, local %s:%d)", vxlanName, req.Vni, localIP, dstPort)
+
+	vxlanLink, err := netlink.LinkByName(vxlanName)
+	if err != nil {
+		netlink.LinkDel(vxlan)
+		return &pb.CreateVXLANResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to retrieve created VXLAN interface: %v", err),
+		}, nil
 	}
 
-	return &pb.RemoveNATResponse{
-		Success: true,
-	}, nil
-}
+	// Parse and configure gateway IP with subnet mask from subnet
+	_, ipnet, err := net.ParseCIDR(req.Subnet)
+	if err != nil {
+		netlink.LinkDel(vxlanLink)
+		return &pb.CreateVXLANResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid subnet %s: %v", req.Subnet, err),
+		}, nil
+	}
 
-// removeNATLocked removes NAT (must hold lock)
-func (s *RouterServer) removeNATLocked(vlanID uint32, sourceSubnet string) error {
-	// Remove MASQUERADE rule
-	cmd := exec.Command("iptables", "-t", "nat", "-D", "POSTROUTING",
-		"-s", sourceSubnet,
-		"-j", "MASQUERADE",
-		"-m", "comment", "--comment", fmt.Sprintf("vlan-%d", vlanID))
+	maskSize, _ := ipnet.Mask.Size()
+	addr, err := netlink.ParseAddr(fmt.Sprintf("%s/%d", req.Gateway, maskSize))
+	if err != nil {
+		netlink.LinkDel(vxlanLink)
+		return &pb.CreateVXLANResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid gateway IP %s: %v", req.Gateway, err),
+		}, nil
+	}
 
-	if output, err := cmd.CombinedOutput(); err != nil {
-		// Don't fail if rule doesn't exist
-		if !strings.Contains(string(output), "does a matching rule exist") {
-			return fmt.Errorf("failed to remove NAT: %v, output: %s", err, string(output))
-		}
+	if err := netlink.AddrAdd(vxlanLink, addr); err != nil {
+		netlink.LinkDel(vxlanLink)
+		return &pb.CreateVXLANResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to add gateway IP: %v", err),
+		}, nil
 	}
 
-	log.Printf("Removed NAT for subnet %s (VLAN %d)", sourceSubnet, vlanID)
+	log.Printf("Configured gateway IP %s/%d on %s", req.Gateway, maskSize, vxlanName)
 
-	return nil
-}
+	if err := netlink.LinkSetUp(vxlanLink); err != nil {
+		netlink.LinkDel(vxlanLink)
+		return &pb.CreateVXLANResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to bring interface up: %v", err),
+		}, nil
+	}
 
-// ConfigureDNS configures dnsmasq for a VLAN network
-// This uses the same dnsmasq approach as the OVS control-api
-func (s *RouterServer) ConfigureDNS(ctx context.Context, req *pb.ConfigureDNSRequest) (*pb.ConfigureDNSResponse, error) {
-	log.Printf("ConfigureDNS: vlanID=%d domain=%s gateway=%s hosts=%d",
-		req.VlanId, req.Domain, req.Gateway, len(req.Hosts))
+	log.Printf("Brought up VXLAN interface %s", vxlanName)
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.logNetworkState(vxlanName)
 
-	// Update VLAN info with domain
-	if vlanInfo, exists := s.vlans[req.VlanId]; exists {
-		vlanInfo.Domain = req.Domain
+	// Configure NAT if enabled (default: true unless explicitly disabled)
+	enableNAT := true
+	if req.EnableNat {
+		enableNAT = req.EnableNat
+	}
+	if enableNAT && req.Subnet != "" {
+		if err := s.configureNATLocked(req.Vni, req.Subnet); err != nil {
+			log.Printf("Warning: failed to configure NAT: %v", err)
+		}
 	}
 
-	// Store DNS entries
-	if s.dnsEntries[req.VlanId] == nil {
-		s.dnsEntries[req.VlanId] = make(map[string]string)
+	s.vxlans[req.Vni] = &VXLANInfo{
+		VNI:           req.Vni,
+		InterfaceName: vxlanName,
+		DstPort:       dstPort,
+		LocalIP:       localIP,
+		Gateway:       req.Gateway,
+		Subnet:        req.Subnet,
+		NetworkName:   req.NetworkName,
+		NATEnabled:    enableNAT,
+		VTEPs:         make(map[string]bool),
+		CreatedAt:     time.Now(),
 	}
-	for hostname, ip := range req.Hosts {
-		s.dnsEntries[req.VlanId][hostname] = ip
+
+	// Initialize DNS entries map for this VXLAN, same as CreateVLAN does.
+	s.dnsEntries[req.Vni] = make(map[string]string)
+
+	// Install a unicast FDB entry per remote VTEP so BUM traffic
+	// head-end-replicates without depending on multicast.
+	for _, remote := range req.RemoteVteps {
+		if err := addVTEPFDB(vxlanLink.Attrs().Index, remote); err != nil {
+			log.Printf("Warning: failed to install FDB entry for VTEP %s: %v", remote, err)
+			continue
+		}
+		s.vxlans[req.Vni].VTEPs[remote] = true
 	}
 
-	// Write dnsmasq configuration
-	if err := s.writeDnsmasqConfigLocked(req.VlanId); err != nil {
-		return &pb.ConfigureDNSResponse{
+	macAddr := vxlanLink.Attrs().HardwareAddr.String()
+
+	return &pb.CreateVXLANResponse{
+		Success:       true,
+		InterfaceName: vxlanName,
+		MacAddress:    macAddr,
+		LocalIp:       localIP,
+	}, nil
+}
+
+// DeleteVXLANNetwork removes a VXLAN overlay interface.
+func (s *RouterServer) DeleteVXLANNetwork(ctx context.Context, req *pb.DeleteVXLANRequest) (*pb.DeleteVXLANResponse, error) {
+	log.Printf("DeleteVXLANNetwork: vni=%d", req.Vni)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.deleteVXLANLocked(req.Vni); err != nil {
+		return &pb.DeleteVXLANResponse{
 			Success: false,
 			Error:   err.Error(),
 		}, nil
 	}
-
-	return &pb.ConfigureDNSResponse{
+	return &pb.DeleteVXLANResponse{
 		Success: true,
 	}, nil
 }
 
-// writeDnsmasqConfigLocked writes dnsmasq config for a VLAN (must hold lock)
-// This mirrors the approach in helpervm/control-api/server.go
-func (s *RouterServer) writeDnsmasqConfigLocked(vlanID uint32) error {
-	vlanInfo := s.vlans[vlanID]
-	if vlanInfo == nil {
-		return fmt.Errorf("VLAN %d not found", vlanID)
-	}
-
-	configFile := fmt.Sprintf("/etc/dnsmasq.d/vlan-%d.conf", vlanID)
-
-	// Build dnsmasq configuration
-	var config strings.Builder
-
-	// Listen only on the VLAN gateway IP
-	// This makes dnsmasq bind specifically to this network's DNS service
-	config.WriteString(fmt.Sprintf("listen-address=%s\n", vlanInfo.Gateway))
+// deleteVXLANLocked deletes a VXLAN overlay interface (must hold lock)
+func (s *RouterServer) deleteVXLANLocked(vni uint32) error {
+	vxlanInfo, exists := s.vxlans[vni]
 
-	// Add host records for all containers on this network
-	if entries := s.dnsEntries[vlanID]; entries != nil {
-		for hostname, ip := range entries {
-			if hostname != "" {
-				config.WriteString(fmt.Sprintf("host-record=%s,%s\n", hostname, ip))
-			}
+	if exists && vxlanInfo.NATEnabled && vxlanInfo.Subnet != "" {
+		if err := s.removeNATLocked(vni, vxlanInfo.Subnet); err != nil {
+			log.Printf("Warning: failed to remove NAT: %v", err)
 		}
 	}
 
-	// Write config file
-	if err := os.WriteFile(configFile, []byte(config.String()), 0644); err != nil {
-		return fmt.Errorf("failed to write dnsmasq config: %v", err)
+	if err := s.removeDnsmasqConfigLocked(vni); err != nil {
+		log.Printf("Warning: failed to remove dnsmasq config: %v", err)
 	}
 
-	log.Printf("Wrote dnsmasq config for VLAN %d with %d entries", vlanID, len(s.dnsEntries[vlanID]))
-
-	// Test config before applying
-	cmd := exec.Command("dnsmasq", "--conf-file=/etc/dnsmasq.conf", "--test")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		log.Printf("ERROR: dnsmasq config test failed: %v, output: %s", err, string(output))
-		return fmt.Errorf("dnsmasq config test failed: %v", err)
+	vxlanName := fmt.Sprintf("vxlan%d", vni)
+	link, err := netlink.LinkByName(vxlanName)
+	if err != nil {
+		log.Printf("VXLAN interface %s not found (already deleted?)", vxlanName)
+		delete(s.vxlans, vni)
+		delete(s.dnsEntries, vni)
+		return nil
 	}
 
-	// Restart dnsmasq to apply changes
-	// Kill existing dnsmasq processes
-	exec.Command("killall", "-9", "dnsmasq").Run()
-	time.Sleep(1 * time.Second)
-
-	// Start dnsmasq
-	cmd = exec.Command("dnsmasq", "--conf-file=/etc/dnsmasq.conf", "--log-queries")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to start dnsmasq: %v, output: %s", err, string(output))
+	if err := netlink.LinkDel(link); err != nil {
+		return fmt.Errorf("failed to delete interface: %v", err)
 	}
 
-	log.Printf("dnsmasq restarted successfully for VLAN %d", vlanID)
-
-	return nil
-}
-
-// removeDnsmasqConfigLocked removes dnsmasq config for a VLAN (must hold lock)
-func (s *RouterServer) removeDnsmasqConfigLocked(vlanID uint32) error {
-	configFile := fmt.Sprintf("/etc/dnsmasq.d/vlan-%d.conf", vlanID)
-
-	if err := os.Remove(configFile); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove dnsmasq config: %v", err)
-	}
+	log.Printf("Deleted VXLAN interface %s", vxlanName)
 
-	// Restart dnsmasq
-	exec.Command("killall", "-HUP", "dnsmasq").Run()
+	delete(s.vxlans, vni)
+	delete(s.dnsEntries, vni)
 
 	return nil
 }
 
-// AddPortMapping adds a port forwarding rule (DNAT)
-func (s *RouterServer) AddPortMapping(ctx context.Context, req *pb.AddPortMappingRequest) (*pb.AddPortMappingResponse, error) {
-	log.Printf("AddPortMapping: %s:%d -> %s:%d (vlan %d)",
-		"0.0.0.0", req.HostPort, req.ContainerIp, req.ContainerPort, req.VlanId)
+// AddVTEP installs a unicast FDB entry for a remote VTEP on an existing
+// VXLAN network, so BUM traffic head-end-replicates to it without relying
+// on multicast.
+func (s *RouterServer) AddVTEP(ctx context.Context, req *pb.AddVTEPRequest) (*pb.AddVTEPResponse, error) {
+	log.Printf("AddVTEP: vni=%d remote=%s", req.Vni, req.RemoteIp)
 
-	protocol := strings.ToLower(req.Protocol)
-	if protocol != "tcp" && protocol != "udp" {
-		return &pb.AddPortMappingResponse{
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vxlanInfo, exists := s.vxlans[req.Vni]
+	if !exists {
+		return &pb.AddVTEPResponse{
 			Success: false,
-			Error:   fmt.Sprintf("invalid protocol %s (must be tcp or udp)", req.Protocol),
+			Error:   fmt.Sprintf("VXLAN %d not found", req.Vni),
 		}, nil
 	}
 
-	// Add DNAT rule for incoming traffic
-	cmd := exec.Command("iptables", "-t", "nat", "-A", "PREROUTING",
-		"-p", protocol,
-		"--dport", fmt.Sprintf("%d", req.HostPort),
-		"-j", "DNAT",
-		"--to-destination", fmt.Sprintf("%s:%d", req.ContainerIp, req.ContainerPort),
-		"-m", "comment", "--comment", fmt.Sprintf("port-%d-vlan-%d", req.HostPort, req.VlanId))
+	link, err := netlink.LinkByName(vxlanInfo.InterfaceName)
+	if err != nil {
+		return &pb.AddVTEPResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to find VXLAN interface: %v", err),
+		}, nil
+	}
 
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return &pb.AddPortMappingResponse{
+	if err := addVTEPFDB(link.Attrs().Index, req.RemoteIp); err != nil {
+		return &pb.AddVTEPResponse{
 			Success: false,
-			Error:   fmt.Sprintf("failed to add port mapping: %v, output: %s", err, string(output)),
+			Error:   fmt.Sprintf("failed to install FDB entry: %v", err),
 		}, nil
 	}
 
-	log.Printf("Added port mapping %s:%d -> %s:%d",
-		protocol, req.HostPort, req.ContainerIp, req.ContainerPort)
+	vxlanInfo.VTEPs[req.RemoteIp] = true
 
-	return &pb.AddPortMappingResponse{
-		Success: true,
-	}, nil
+	log.Printf("Added VTEP %s to VXLAN %d", req.RemoteIp, req.Vni)
+
+	return &pb.AddVTEPResponse{Success: true}, nil
 }
 
-// RemovePortMapping removes a port forwarding rule
-func (s *RouterServer) RemovePortMapping(ctx context.Context, req *pb.RemovePortMappingRequest) (*pb.RemovePortMappingResponse, error) {
-	log.Printf("RemovePortMapping: %s:%d", req.Protocol, req.HostPort)
+// RemoveVTEP removes a remote VTEP's unicast FDB entry from a VXLAN network.
+func (s *RouterServer) RemoveVTEP(ctx context.Context, req *pb.RemoveVTEPRequest) (*pb.RemoveVTEPResponse, error) {
+	log.Printf("RemoveVTEP: vni=%d remote=%s", req.Vni, req.RemoteIp)
 
-	protocol := strings.ToLower(req.Protocol)
-	if protocol != "tcp" && protocol != "udp" {
-		return &pb.RemovePortMappingResponse{
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vxlanInfo, exists := s.vxlans[req.Vni]
+	if !exists {
+		return &pb.RemoveVTEPResponse{
 			Success: false,
-			Error:   fmt.Sprintf("invalid protocol %s (must be tcp or udp)", req.Protocol),
+			Error:   fmt.Sprintf("VXLAN %d not found", req.Vni),
 		}, nil
 	}
 
-	// List all PREROUTING rules to find the one to delete
-	cmd := exec.Command("iptables", "-t", "nat", "-L", "PREROUTING", "--line-numbers", "-n")
-	output, err := cmd.CombinedOutput()
+	link, err := netlink.LinkByName(vxlanInfo.InterfaceName)
 	if err != nil {
-		return &pb.RemovePortMappingResponse{
+		return &pb.RemoveVTEPResponse{
 			Success: false,
-			Error:   fmt.Sprintf("failed to list iptables rules: %v", err),
-		}, nil
-	}
-
-	// Parse output to find rule number for this port
-	lines := strings.Split(string(output), "\n")
-	var ruleNum string
-	targetPort := fmt.Sprintf("dpt:%d", req.HostPort)
-
-	for _, line := range lines {
-		if strings.Contains(line, protocol) && strings.Contains(line, targetPort) {
-			fields := strings.Fields(line)
-			if len(fields) > 0 {
-				ruleNum = fields[0]
-				break
-			}
-		}
-	}
-
-	if ruleNum == "" {
-		// Rule not found - consider it success
-		log.Printf("Port mapping %s:%d not found (already deleted?)", protocol, req.HostPort)
-		return &pb.RemovePortMappingResponse{
-			Success: true,
+			Error:   fmt.Sprintf("failed to find VXLAN interface: %v", err),
 		}, nil
 	}
 
-	// Delete the rule by number
-	cmd = exec.Command("iptables", "-t", "nat", "-D", "PREROUTING", ruleNum)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return &pb.RemovePortMappingResponse{
+	if err := removeVTEPFDB(link.Attrs().Index, req.RemoteIp); err != nil {
+		return &pb.RemoveVTEPResponse{
 			Success: false,
-			Error:   fmt.Sprintf("failed to remove port mapping: %v, output: %s", err, string(output)),
+			Error:   fmt.Sprintf("failed to remove FDB entry: %v", err),
 		}, nil
 	}
 
-	log.Printf("Removed port mapping %s:%d", protocol, req.HostPort)
+	delete(vxlanInfo.VTEPs, req.RemoteIp)
 
-	return &pb.RemovePortMappingResponse{
-		Success: true,
-	}, nil
+	log.Printf("Removed VTEP %s from VXLAN %d", req.RemoteIp, req.Vni)
+
+	return &pb.RemoveVTEPResponse{Success: true}, nil
 }
 
-// ListVLANs lists all VLAN interfaces
-func (s *RouterServer) ListVLANs(ctx context.Context, req *pb.ListVLANsRequest) (*pb.ListVLANsResponse, error) {
-	log.Printf("ListVLANs: filter=%d", req.VlanId)
+// addVTEPFDB installs a permanent, unicast bridge FDB entry pointing the
+// all-zero "catch-all" MAC at remote, so BUM (broadcast/unknown-unicast/
+// multicast) traffic on linkIndex is head-end-replicated to remote instead
+// of relying on multicast, which most cloud/NAT'd networks don't route.
+func addVTEPFDB(linkIndex int, remote string) error {
+	remoteIP := net.ParseIP(remote)
+	if remoteIP == nil {
+		return fmt.Errorf("invalid remote VTEP IP %q", remote)
+	}
+	return netlink.NeighAppend(&netlink.Neigh{
+		LinkIndex:    linkIndex,
+		Family:       netlink.FAMILY_BRIDGE,
+		State:        netlink.NUD_PERMANENT,
+		Flags:        netlink.NTF_SELF,
+		IP:           remoteIP,
+		HardwareAddr: net.HardwareAddr{0, 0, 0, 0, 0, 0},
+	})
+}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// removeVTEPFDB removes the FDB entry installed by addVTEPFDB.
+func removeVTEPFDB(linkIndex int, remote string) error {
+	remoteIP := net.ParseIP(remote)
+	if remoteIP == nil {
+		return fmt.Errorf("invalid remote VTEP IP %q", remote)
+	}
+	return netlink.NeighDel(&netlink.Neigh{
+		LinkIndex:    linkIndex,
+		Family:       netlink.FAMILY_BRIDGE,
+		State:        netlink.NUD_PERMANENT,
+		Flags:        netlink.NTF_SELF,
+		IP:           remoteIP,
+		HardwareAddr: net.HardwareAddr{0, 0, 0, 0, 0, 0},
+	})
+}
+
+// macvlanModes maps the mode strings CreateEndpoint accepts to the netlink
+// constants shared by the macvlan and macvtap drivers (macvtap is a macvlan
+// variant with an attached tap character device).
+var macvlanModes = map[string]netlink.MacvlanMode{
+	"bridge":   netlink.MACVLAN_MODE_BRIDGE,
+	"private":  netlink.MACVLAN_MODE_PRIVATE,
+	"vepa":     netlink.MACVLAN_MODE_VEPA,
+	"passthru": netlink.MACVLAN_MODE_PASSTHRU,
+}
+
+// ipvlanModes maps the mode strings CreateEndpoint accepts to the netlink
+// constants for the ipvlan driver.
+var ipvlanModes = map[string]netlink.IPVlanMode{
+	"l2":  netlink.IPVLAN_MODE_L2,
+	"l3":  netlink.IPVLAN_MODE_L3,
+	"l3s": netlink.IPVLAN_MODE_L3S,
+}
+
+// resolveEndpointParentLocked resolves the parent link for a macvlan/ipvlan/
+// macvtap slave: an existing VLAN's subinterface if vlanID names one,
+// falling back to the host's physical uplink otherwise (must hold lock).
+func (s *RouterServer) resolveEndpointParentLocked(vlanID uint32) (netlink.Link, error) {
+	if vlanInfo, exists := s.vlans[vlanID]; exists {
+		return netlink.LinkByName(vlanInfo.InterfaceName)
+	}
+	return s.getParentInterface()
+}
+
+// newSlaveLink builds the netlink.Link for driver ("macvlan", "ipvlan", or
+// "macvtap"), ready for netlink.LinkAdd. It is given tmpName rather than the
+// endpoint's final name because the rename has to happen after
+// netlink.LinkSetNsFd moves the link into the target namespace - a rename
+// across namespaces isn't atomic.
+func newSlaveLink(driver, tmpName string, parentIndex int, mode string) (netlink.Link, error) {
+	attrs := netlink.LinkAttrs{
+		Name:        tmpName,
+		ParentIndex: parentIndex,
+	}
+
+	switch driver {
+	case "macvlan":
+		macvlanMode, ok := macvlanModes[mode]
+		if !ok {
+			return nil, fmt.Errorf("invalid macvlan mode %q", mode)
+		}
+		return &netlink.Macvlan{LinkAttrs: attrs, Mode: macvlanMode}, nil
+	case "macvtap":
+		macvlanMode, ok := macvlanModes[mode]
+		if !ok {
+			return nil, fmt.Errorf("invalid macvtap mode %q", mode)
+		}
+		return &netlink.Macvtap{Macvlan: netlink.Macvlan{LinkAttrs: attrs, Mode: macvlanMode}}, nil
+	case "ipvlan":
+		ipvlanMode, ok := ipvlanModes[mode]
+		if !ok {
+			return nil, fmt.Errorf("invalid ipvlan mode %q", mode)
+		}
+		return &netlink.IPVlan{LinkAttrs: attrs, Mode: ipvlanMode}, nil
+	default:
+		return nil, fmt.Errorf("unsupported driver %q (want macvlan, ipvlan, or macvtap)", driver)
+	}
+}
+
+// renameAndActivateInNS enters ns on a goroutine locked to its OS thread,
+// renames the slave device from tmpName to ifName, optionally sets its MAC
+// address, and brings it up. The rename has to happen from inside the
+// namespace because netlink.LinkSetName operates against the calling
+// thread's current namespace, not the link's.
+func renameAndActivateInNS(ns netns.NsHandle, tmpName, ifName string, mac net.HardwareAddr) error {
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		origns, err := netns.Get()
+		if err != nil {
+			errCh <- fmt.Errorf("failed to get current namespace: %v", err)
+			return
+		}
+		defer origns.Close()
+
+		if err := netns.Set(ns); err != nil {
+			errCh <- fmt.Errorf("failed to enter target namespace: %v", err)
+			return
+		}
+		defer netns.Set(origns)
+
+		link, err := netlink.LinkByName(tmpName)
+		if err != nil {
+			errCh <- fmt.Errorf("failed to find %s in target namespace: %v", tmpName, err)
+			return
+		}
+
+		if err := netlink.LinkSetName(link, ifName); err != nil {
+			errCh <- fmt.Errorf("failed to rename %s to %s: %v", tmpName, ifName, err)
+			return
+		}
+
+		if mac != nil {
+			if err := netlink.LinkSetHardwareAddr(link, mac); err != nil {
+				errCh <- fmt.Errorf("failed to set MAC address on %s: %v", ifName, err)
+				return
+			}
+		}
+
+		if err := netlink.LinkSetUp(link); err != nil {
+			errCh <- fmt.Errorf("failed to bring %s up: %v", ifName, err)
+			return
+		}
+
+		errCh <- nil
+	}()
+	return <-errCh
+}
+
+// deleteEndpointInNS enters ns on a goroutine locked to its OS thread and
+// deletes the interface named ifName, but only after confirming it is still
+// the slave device CreateEndpoint created - mirroring libnetwork's
+// delVlanLink, which refuses to touch an interface whose ParentIndex
+// doesn't match the one it recorded at creation time.
+func deleteEndpointInNS(ns netns.NsHandle, ifName string, wantParentIndex int) error {
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		origns, err := netns.Get()
+		if err != nil {
+			errCh <- fmt.Errorf("failed to get current namespace: %v", err)
+			return
+		}
+		defer origns.Close()
+
+		if err := netns.Set(ns); err != nil {
+			errCh <- fmt.Errorf("failed to enter target namespace: %v", err)
+			return
+		}
+		defer netns.Set(origns)
+
+		link, err := netlink.LinkByName(ifName)
+		if err != nil {
+			// Already gone - nothing left to do.
+			errCh <- nil
+			return
+		}
+
+		if parentIndex := link.Attrs().ParentIndex; wantParentIndex != 0 && parentIndex != wantParentIndex {
+			errCh <- fmt.Errorf("refusing to delete %s: parent index %d does not match recorded parent %d", ifName, parentIndex, wantParentIndex)
+			return
+		}
+
+		errCh <- netlink.LinkDel(link)
+	}()
+	return <-errCh
+}
+
+// CreateEndpoint attaches a macvlan, ipvlan, or macvtap slave of the given
+// VLAN (or the host uplink, if vlanID doesn't name one) directly into a
+// container's network namespace. It's a lower-overhead alternative to the
+// router-based VLAN/VXLAN topology for containers that don't need NAT,
+// DHCP, or DNS from the helper VM.
+func (s *RouterServer) CreateEndpoint(ctx context.Context, req *pb.CreateEndpointRequest) (*pb.CreateEndpointResponse, error) {
+	log.Printf("CreateEndpoint: vlanID=%d driver=%s mode=%s netns=%s ifName=%s",
+		req.VlanId, req.Driver, req.Mode, req.NetnsPath, req.IfName)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := endpointKey{VlanID: req.VlanId, NetnsPath: req.NetnsPath, IfName: req.IfName}
+	if _, exists := s.endpoints[key]; exists {
+		return &pb.CreateEndpointResponse{
+			Success: false,
+			Error:   fmt.Sprintf("endpoint %s already exists in namespace %s", req.IfName, req.NetnsPath),
+		}, nil
+	}
+
+	parent, err := s.resolveEndpointParentLocked(req.VlanId)
+	if err != nil {
+		return &pb.CreateEndpointResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to resolve parent interface: %v", err),
+		}, nil
+	}
+
+	var mac net.HardwareAddr
+	if req.MacAddress != "" {
+		mac, err = net.ParseMAC(req.MacAddress)
+		if err != nil {
+			return &pb.CreateEndpointResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid MAC address %s: %v", req.MacAddress, err),
+			}, nil
+		}
+	}
+
+	ns, err := netns.GetFromPath(req.NetnsPath)
+	if err != nil {
+		return &pb.CreateEndpointResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to open network namespace %s: %v", req.NetnsPath, err),
+		}, nil
+	}
+	defer ns.Close()
+
+	tmpName := fmt.Sprintf("arca%d", atomic.AddUint64(&s.endpointSeq, 1))
+
+	link, err := newSlaveLink(req.Driver, tmpName, parent.Attrs().Index, req.Mode)
+	if err != nil {
+		return &pb.CreateEndpointResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	if err := netlink.LinkAdd(link); err != nil {
+		return &pb.CreateEndpointResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to create %s slave: %v", req.Driver, err),
+		}, nil
+	}
+
+	createdLink, err := netlink.LinkByName(tmpName)
+	if err != nil {
+		netlink.LinkDel(link)
+		return &pb.CreateEndpointResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to retrieve created %s slave: %v", req.Driver, err),
+		}, nil
+	}
+
+	if err := netlink.LinkSetNsFd(createdLink, int(ns)); err != nil {
+		netlink.LinkDel(createdLink)
+		return &pb.CreateEndpointResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to move %s into namespace %s: %v", tmpName, req.NetnsPath, err),
+		}, nil
+	}
+
+	if err := renameAndActivateInNS(ns, tmpName, req.IfName, mac); err != nil {
+		return &pb.CreateEndpointResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	s.endpoints[key] = &EndpointInfo{
+		ParentIndex: parent.Attrs().Index,
+		Driver:      req.Driver,
+		Mode:        req.Mode,
+		CreatedAt:   time.Now(),
+	}
+
+	log.Printf("Created %s endpoint %s (parent %s, mode %s) in namespace %s",
+		req.Driver, req.IfName, parent.Attrs().Name, req.Mode, req.NetnsPath)
+
+	return &pb.CreateEndpointResponse{
+		Success:    true,
+		MacAddress: req.MacAddress,
+	}, nil
+}
+
+// DeleteEndpoint removes a macvlan/ipvlan/macvtap slave that CreateEndpoint
+// attached to a container network namespace.
+func (s *RouterServer) DeleteEndpoint(ctx context.Context, req *pb.DeleteEndpointRequest) (*pb.DeleteEndpointResponse, error) {
+	log.Printf("DeleteEndpoint: vlanID=%d netns=%s ifName=%s", req.VlanId, req.NetnsPath, req.IfName)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := endpointKey{VlanID: req.VlanId, NetnsPath: req.NetnsPath, IfName: req.IfName}
+	info, exists := s.endpoints[key]
+	if !exists {
+		return &pb.DeleteEndpointResponse{
+			Success: false,
+			Error:   fmt.Sprintf("endpoint %s not found in namespace %s", req.IfName, req.NetnsPath),
+		}, nil
+	}
+
+	ns, err := netns.GetFromPath(req.NetnsPath)
+	if err != nil {
+		// The namespace is gone (container already torn down) - drop our
+		// bookkeeping rather than leaving a stale entry nothing can clean up.
+		delete(s.endpoints, key)
+		return &pb.DeleteEndpointResponse{
+			Success: false,
+			Error:   fmt.Sprintf("namespace %s no longer exists, dropped stale endpoint: %v", req.NetnsPath, err),
+		}, nil
+	}
+	defer ns.Close()
+
+	if err := deleteEndpointInNS(ns, req.IfName, info.ParentIndex); err != nil {
+		return &pb.DeleteEndpointResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	delete(s.endpoints, key)
+
+	log.Printf("Deleted endpoint %s from namespace %s", req.IfName, req.NetnsPath)
+
+	return &pb.DeleteEndpointResponse{Success: true}, nil
+}
+
+// ConfigureNAT configures NAT (MASQUERADE) for a network
+func (s *RouterServer) ConfigureNAT(ctx context.Context, req *pb.ConfigureNATRequest) (*pb.ConfigureNATResponse, error) {
+	log.Printf("ConfigureNAT: vlanID=%d subnet=%s", req.VlanId, req.SourceSubnet)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.configureNATLocked(req.VlanId, req.SourceSubnet); err != nil {
+		return &pb.ConfigureNATResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.ConfigureNATResponse{
+		Success: true,
+	}, nil
+}
+
+// configureNATLocked enables MASQUERADE for id's subnet by flagging it in
+// s.vlans/s.vxlans and re-applying the full firewall state - rather than
+// issuing a single `-A POSTROUTING` rule - so the backend can roll the
+// change back atomically if the apply fails partway through (must hold
+// lock).
+func (s *RouterServer) configureNATLocked(id uint32, sourceSubnet string) error {
+	if vlanInfo, exists := s.vlans[id]; exists {
+		vlanInfo.NATEnabled = true
+	} else if vxlanInfo, exists := s.vxlans[id]; exists {
+		vxlanInfo.NATEnabled = true
+	} else {
+		return fmt.Errorf("network %d not found", id)
+	}
+
+	if err := s.applyFirewallLocked(); err != nil {
+		return fmt.Errorf("failed to configure NAT: %v", err)
+	}
+
+	log.Printf("Configured NAT for subnet %s (network %d)", sourceSubnet, id)
+
+	return nil
+}
+
+// RemoveNAT removes NAT configuration for a network
+func (s *RouterServer) RemoveNAT(ctx context.Context, req *pb.RemoveNATRequest) (*pb.RemoveNATResponse, error) {
+	log.Printf("RemoveNAT: vlanID=%d subnet=%s", req.VlanId, req.SourceSubnet)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.removeNATLocked(req.VlanId, req.SourceSubnet); err != nil {
+		return &pb.RemoveNATResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.RemoveNATResponse{
+		Success: true,
+	}, nil
+}
+
+// removeNATLocked disables MASQUERADE for id and re-applies the full
+// firewall state (must hold lock).
+func (s *RouterServer) removeNATLocked(id uint32, sourceSubnet string) error {
+	if vlanInfo, exists := s.vlans[id]; exists {
+		vlanInfo.NATEnabled = false
+	} else if vxlanInfo, exists := s.vxlans[id]; exists {
+		vxlanInfo.NATEnabled = false
+	}
+
+	if err := s.applyFirewallLocked(); err != nil {
+		return fmt.Errorf("failed to remove NAT: %v", err)
+	}
+
+	log.Printf("Removed NAT for subnet %s (network %d)", sourceSubnet, id)
+
+	return nil
+}
+
+// buildRuleSetsLocked derives the full desired firewall state from
+// s.vlans, s.vxlans and s.portMaps, so ReconcileFirewall (and every
+// mutation above) can re-render every rule without keeping a separate
+// record of what was last applied (must hold lock).
+func (s *RouterServer) buildRuleSetsLocked() map[uint32]*firewall.RuleSet {
+	sets := make(map[uint32]*firewall.RuleSet, len(s.vlans)+len(s.vxlans))
+
+	for id, vlanInfo := range s.vlans {
+		set := &firewall.RuleSet{NetworkID: id, PortMaps: s.portMaps[id], EgressRules: s.egressPolicies[id]}
+		if vlanInfo.NATEnabled {
+			set.Subnet = vlanInfo.Subnet
+		}
+		sets[id] = set
+	}
+	for id, vxlanInfo := range s.vxlans {
+		set := &firewall.RuleSet{NetworkID: id, PortMaps: s.portMaps[id], EgressRules: s.egressPolicies[id]}
+		if vxlanInfo.NATEnabled {
+			set.Subnet = vxlanInfo.Subnet
+		}
+		sets[id] = set
+	}
+
+	return sets
+}
+
+// parseEgressRule validates one *pb.EgressRule and converts it to a
+// firewall.EgressRule, rejecting unparseable CIDRs/IPs and unknown actions
+// up front rather than letting the firewall backend fail the whole Apply
+// partway through.
+func parseEgressRule(r *pb.EgressRule) (firewall.EgressRule, error) {
+	if r.DstCidr != "" {
+		if _, _, err := net.ParseCIDR(r.DstCidr); err != nil {
+			return firewall.EgressRule{}, fmt.Errorf("invalid dstCIDR %s: %v", r.DstCidr, err)
+		}
+	}
+	if r.Protocol != "" && r.Protocol != "tcp" && r.Protocol != "udp" {
+		return firewall.EgressRule{}, fmt.Errorf("invalid protocol %s (must be tcp or udp)", r.Protocol)
+	}
+
+	switch {
+	case r.Action == firewall.ActionMasquerade, r.Action == firewall.ActionAccept,
+		r.Action == firewall.ActionReject, r.Action == firewall.ActionDrop:
+	case strings.HasPrefix(r.Action, firewall.ActionSNATPrefix):
+		ip := strings.TrimPrefix(r.Action, firewall.ActionSNATPrefix)
+		if net.ParseIP(ip) == nil {
+			return firewall.EgressRule{}, fmt.Errorf("invalid SNAT target IP in action %q", r.Action)
+		}
+	default:
+		return firewall.EgressRule{}, fmt.Errorf(
+			"invalid action %q (want MASQUERADE, SNAT-to-<ip>, ACCEPT, REJECT, or DROP)", r.Action)
+	}
+
+	return firewall.EgressRule{
+		DstCIDR:  r.DstCidr,
+		Protocol: r.Protocol,
+		DstPort:  r.DstPort,
+		Action:   r.Action,
+	}, nil
+}
+
+// SetEgressPolicy replaces a network's egress policy with an ordered list
+// of rules, evaluated top-to-bottom against traffic leaving its subnet -
+// see firewall.EgressRule. Passing no rules reverts the network to the
+// default blanket MASQUERADE. This is what lets an operator pin a specific
+// SNAT source IP per network or blocklist destinations, rather than every
+// VLAN sharing one unconditional MASQUERADE rule.
+func (s *RouterServer) SetEgressPolicy(ctx context.Context, req *pb.SetEgressPolicyRequest) (*pb.SetEgressPolicyResponse, error) {
+	log.Printf("SetEgressPolicy: vlanID=%d rules=%d", req.VlanId, len(req.Rules))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.networkGatewayLocked(req.VlanId); !ok {
+		return &pb.SetEgressPolicyResponse{
+			Success: false,
+			Error:   fmt.Sprintf("network %d not found", req.VlanId),
+		}, nil
+	}
+
+	rules := make([]firewall.EgressRule, 0, len(req.Rules))
+	for _, r := range req.Rules {
+		rule, err := parseEgressRule(r)
+		if err != nil {
+			return &pb.SetEgressPolicyResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		rules = append(rules, rule)
+	}
+
+	if len(rules) == 0 {
+		delete(s.egressPolicies, req.VlanId)
+	} else {
+		s.egressPolicies[req.VlanId] = rules
+	}
+
+	if err := s.applyFirewallLocked(); err != nil {
+		return &pb.SetEgressPolicyResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to apply egress policy: %v", err),
+		}, nil
+	}
+
+	log.Printf("Set egress policy for network %d (%d rule(s))", req.VlanId, len(rules))
+
+	return &pb.SetEgressPolicyResponse{
+		Success: true,
+	}, nil
+}
+
+// applyFirewallLocked re-renders the full firewall state from current
+// server state and commits it to s.firewall in a single transaction (must
+// hold lock).
+func (s *RouterServer) applyFirewallLocked() error {
+	if s.firewall == nil {
+		return fmt.Errorf("no firewall backend available")
+	}
+	return s.firewall.Apply(s.buildRuleSetsLocked())
+}
+
+// ReconcileFirewall re-derives every network's firewall rules from current
+// server state and re-installs them, so the helper VM can recover cleanly
+// after an external `iptables -F`/`nft flush ruleset` without an operator
+// needing to replay every ConfigureNAT/AddPortMapping call by hand.
+func (s *RouterServer) ReconcileFirewall(ctx context.Context, req *pb.ReconcileFirewallRequest) (*pb.ReconcileFirewallResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.applyFirewallLocked(); err != nil {
+		return &pb.ReconcileFirewallResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	log.Printf("Reconciled firewall rules for %d network(s) via %s backend",
+		len(s.vlans)+len(s.vxlans), s.firewall.Name())
+
+	return &pb.ReconcileFirewallResponse{
+		Success: true,
+	}, nil
+}
+
+// ConfigureDNS configures dnsmasq for a VLAN network
+// This uses the same dnsmasq approach as the OVS control-api
+func (s *RouterServer) ConfigureDNS(ctx context.Context, req *pb.ConfigureDNSRequest) (*pb.ConfigureDNSResponse, error) {
+	log.Printf("ConfigureDNS: vlanID=%d domain=%s gateway=%s hosts=%d",
+		req.VlanId, req.Domain, req.Gateway, len(req.Hosts))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Update VLAN info with domain
+	if vlanInfo, exists := s.vlans[req.VlanId]; exists {
+		vlanInfo.Domain = req.Domain
+	}
+
+	// Store DNS entries
+	if s.dnsEntries[req.VlanId] == nil {
+		s.dnsEntries[req.VlanId] = make(map[string]string)
+	}
+	for hostname, ip := range req.Hosts {
+		s.dnsEntries[req.VlanId][hostname] = ip
+	}
+
+	// Write dnsmasq configuration
+	if err := s.writeDnsmasqConfigLocked(req.VlanId); err != nil {
+		return &pb.ConfigureDNSResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.ConfigureDNSResponse{
+		Success: true,
+	}, nil
+}
+
+// ConfigureDHCP sets the DHCP range and options dnsmasq hands out on a VLAN
+// or VXLAN network, so containers on it can receive addresses from the
+// helper VM instead of needing static configuration.
+func (s *RouterServer) ConfigureDHCP(ctx context.Context, req *pb.ConfigureDHCPRequest) (*pb.ConfigureDHCPResponse, error) {
+	log.Printf("ConfigureDHCP: vlanID=%d range=%s-%s leaseTime=%s staticLeases=%d",
+		req.VlanId, req.RangeStart, req.RangeEnd, req.LeaseTime, len(req.StaticLeases))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.networkGatewayLocked(req.VlanId); !ok {
+		return &pb.ConfigureDHCPResponse{
+			Success: false,
+			Error:   fmt.Sprintf("network %d not found", req.VlanId),
+		}, nil
+	}
+
+	if net.ParseIP(req.RangeStart) == nil {
+		return &pb.ConfigureDHCPResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid range start %s", req.RangeStart),
+		}, nil
+	}
+	if net.ParseIP(req.RangeEnd) == nil {
+		return &pb.ConfigureDHCPResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid range end %s", req.RangeEnd),
+		}, nil
+	}
+
+	s.dhcpConfigs[req.VlanId] = &DHCPConfig{
+		RangeStart:   req.RangeStart,
+		RangeEnd:     req.RangeEnd,
+		LeaseTime:    req.LeaseTime,
+		DNSServers:   req.DnsServers,
+		NTPServers:   req.NtpServers,
+		StaticLeases: req.StaticLeases,
+	}
+
+	if err := s.writeDnsmasqConfigLocked(req.VlanId); err != nil {
+		return &pb.ConfigureDHCPResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	log.Printf("Configured DHCP range %s-%s for network %d", req.RangeStart, req.RangeEnd, req.VlanId)
+
+	return &pb.ConfigureDHCPResponse{
+		Success: true,
+	}, nil
+}
+
+// networkGatewayLocked resolves id (a VLAN ID or a VXLAN VNI - the two ID
+// spaces are caller-managed and expected not to collide) to the gateway IP
+// of whichever transport owns it, so NAT, DNS and port-mapping code can
+// treat a VXLAN overlay exactly like a VLAN sub-interface. Must hold s.mu.
+func (s *RouterServer) networkGatewayLocked(id uint32) (string, bool) {
+	if v, exists := s.vlans[id]; exists {
+		return v.Gateway, true
+	}
+	if v, exists := s.vxlans[id]; exists {
+		return v.Gateway, true
+	}
+	return "", false
+}
+
+// networkInterfaceLocked resolves id (a VLAN ID or a VXLAN VNI) to the
+// interface name dnsmasq should bind to, mirroring networkGatewayLocked's
+// VLAN/VXLAN fallback. Must hold s.mu.
+func (s *RouterServer) networkInterfaceLocked(id uint32) (string, bool) {
+	if v, exists := s.vlans[id]; exists {
+		return v.InterfaceName, true
+	}
+	if v, exists := s.vxlans[id]; exists {
+		return v.InterfaceName, true
+	}
+	return "", false
+}
+
+// writeDnsmasqConfigLocked writes dnsmasq config for a VLAN or VXLAN
+// network and applies it via that network's own supervised dnsmasq
+// instance (must hold lock). This mirrors the approach in
+// helpervm/control-api/server.go, except each network now gets its own
+// dnsmasq process bound to just its interface.
+func (s *RouterServer) writeDnsmasqConfigLocked(vlanID uint32) error {
+	gateway, ok := s.networkGatewayLocked(vlanID)
+	if !ok {
+		return fmt.Errorf("network %d not found", vlanID)
+	}
+	ifName, _ := s.networkInterfaceLocked(vlanID)
+
+	configFile := fmt.Sprintf("/etc/dnsmasq.d/vlan-%d.conf", vlanID)
+	pidFile := fmt.Sprintf("/run/dnsmasq-vlan-%d.pid", vlanID)
+
+	// Build dnsmasq configuration
+	var config strings.Builder
+
+	config.WriteString(fmt.Sprintf("interface=%s\n", ifName))
+	config.WriteString("bind-interfaces\n")
+	config.WriteString("except-interface=lo\n")
+	config.WriteString("no-resolv\n")
+	config.WriteString(fmt.Sprintf("pid-file=%s\n", pidFile))
+
+	// Listen only on the network's gateway IP
+	// This makes dnsmasq bind specifically to this network's DNS service
+	config.WriteString(fmt.Sprintf("listen-address=%s\n", gateway))
+
+	// Add host records for all containers on this network
+	if entries := s.dnsEntries[vlanID]; entries != nil {
+		for hostname, ip := range entries {
+			if hostname != "" {
+				config.WriteString(fmt.Sprintf("host-record=%s,%s\n", hostname, ip))
+			}
+		}
+	}
+
+	// Add the DHCP range/options, if ConfigureDHCP has been called for this network
+	if dhcp := s.dhcpConfigs[vlanID]; dhcp != nil {
+		config.WriteString(dhcpConfigLines(vlanID, dhcp))
+	}
+
+	// Write config file
+	if err := os.WriteFile(configFile, []byte(config.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write dnsmasq config: %v", err)
+	}
+
+	log.Printf("Wrote dnsmasq config for network %d with %d DNS entries", vlanID, len(s.dnsEntries[vlanID]))
+
+	return s.applyDnsmasqConfigLocked(vlanID, ifName, configFile, pidFile)
+}
+
+// applyDnsmasqConfigLocked starts vlanID's supervised dnsmasq if it isn't
+// running yet, restarts it if the interface binding changed, or otherwise
+// just SIGHUPs the running process so it reloads configFile in place
+// without dropping in-flight queries or DHCP leases (must hold lock).
+func (s *RouterServer) applyDnsmasqConfigLocked(vlanID uint32, ifName, configFile, pidFile string) error {
+	if inst, running := s.dnsmasqProcs[vlanID]; running {
+		if inst.IfName == ifName && processAlive(inst.Cmd) {
+			if err := inst.Cmd.Process.Signal(syscall.SIGHUP); err != nil {
+				return fmt.Errorf("failed to reload dnsmasq for network %d: %v", vlanID, err)
+			}
+			log.Printf("Reloaded dnsmasq for network %d via SIGHUP", vlanID)
+			return nil
+		}
+		s.stopDnsmasqLocked(vlanID)
+	}
+
+	cmd := exec.Command("dnsmasq",
+		"--keep-in-foreground",
+		"--conf-file="+configFile,
+	)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start dnsmasq for network %d: %v", vlanID, err)
+	}
+
+	s.dnsmasqProcs[vlanID] = &dnsmasqInstance{
+		Cmd:      cmd,
+		PidFile:  pidFile,
+		ConfFile: configFile,
+		IfName:   ifName,
+	}
+
+	log.Printf("Started dnsmasq for network %d bound to %s", vlanID, ifName)
+
+	return nil
+}
+
+// processAlive reports whether cmd's process is still running.
+func processAlive(cmd *exec.Cmd) bool {
+	if cmd == nil || cmd.Process == nil {
+		return false
+	}
+	return cmd.Process.Signal(syscall.Signal(0)) == nil
+}
+
+// stopDnsmasqLocked stops vlanID's supervised dnsmasq process, if any, and
+// forgets it (must hold lock).
+func (s *RouterServer) stopDnsmasqLocked(vlanID uint32) {
+	inst, exists := s.dnsmasqProcs[vlanID]
+	if !exists {
+		return
+	}
+	if processAlive(inst.Cmd) {
+		if err := inst.Cmd.Process.Kill(); err != nil {
+			log.Printf("Warning: failed to stop dnsmasq for network %d: %v", vlanID, err)
+		}
+		inst.Cmd.Wait()
+	}
+	os.Remove(inst.PidFile)
+	delete(s.dnsmasqProcs, vlanID)
+}
+
+// dhcpConfigLines renders cfg as the dnsmasq dhcp-range/dhcp-option/
+// dhcp-host directives for vlanID's config file, tagging them so they only
+// apply to that network's interface.
+func dhcpConfigLines(vlanID uint32, cfg *DHCPConfig) string {
+	var b strings.Builder
+
+	leaseTime := cfg.LeaseTime
+	if leaseTime == "" {
+		leaseTime = "12h"
+	}
+	tag := fmt.Sprintf("vlan%d", vlanID)
+
+	b.WriteString(fmt.Sprintf("dhcp-range=set:%s,%s,%s,%s\n", tag, cfg.RangeStart, cfg.RangeEnd, leaseTime))
+
+	for _, dns := range cfg.DNSServers {
+		b.WriteString(fmt.Sprintf("dhcp-option=tag:%s,option:dns-server,%s\n", tag, dns))
+	}
+	for _, ntp := range cfg.NTPServers {
+		b.WriteString(fmt.Sprintf("dhcp-option=tag:%s,option:ntp-server,%s\n", tag, ntp))
+	}
+	for _, lease := range cfg.StaticLeases {
+		b.WriteString(fmt.Sprintf("dhcp-host=%s,%s,%s\n", lease.MacAddress, lease.IpAddress, lease.Hostname))
+	}
+
+	return b.String()
+}
+
+// removeDnsmasqConfigLocked stops vlanID's supervised dnsmasq, removes its
+// config file, and forgets its DHCP settings (must hold lock).
+func (s *RouterServer) removeDnsmasqConfigLocked(vlanID uint32) error {
+	s.stopDnsmasqLocked(vlanID)
+	delete(s.dhcpConfigs, vlanID)
+
+	configFile := fmt.Sprintf("/etc/dnsmasq.d/vlan-%d.conf", vlanID)
+	if err := os.Remove(configFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove dnsmasq config: %v", err)
+	}
+
+	return nil
+}
+
+// AddPortMapping adds a port forwarding rule (DNAT)
+func (s *RouterServer) AddPortMapping(ctx context.Context, req *pb.AddPortMappingRequest) (*pb.AddPortMappingResponse, error) {
+	log.Printf("AddPortMapping: %s:%d -> %s:%d (vlan %d)",
+		"0.0.0.0", req.HostPort, req.ContainerIp, req.ContainerPort, req.VlanId)
+
+	protocol := strings.ToLower(req.Protocol)
+	if protocol != "tcp" && protocol != "udp" {
+		return &pb.AddPortMappingResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid protocol %s (must be tcp or udp)", req.Protocol),
+		}, nil
+	}
+	if net.ParseIP(req.ContainerIp) == nil {
+		return &pb.AddPortMappingResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid container IP %s", req.ContainerIp),
+		}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.portMaps[req.VlanId] = append(s.portMaps[req.VlanId], firewall.PortMap{
+		Protocol:      protocol,
+		HostPort:      req.HostPort,
+		ContainerIP:   req.ContainerIp,
+		ContainerPort: req.ContainerPort,
+	})
+
+	if err := s.applyFirewallLocked(); err != nil {
+		// Roll back the in-memory mapping so a failed apply doesn't leave
+		// behind a mapping ReconcileFirewall would later install anyway.
+		s.portMaps[req.VlanId] = s.portMaps[req.VlanId][:len(s.portMaps[req.VlanId])-1]
+		return &pb.AddPortMappingResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to add port mapping: %v", err),
+		}, nil
+	}
+
+	log.Printf("Added port mapping %s:%d -> %s:%d",
+		protocol, req.HostPort, req.ContainerIp, req.ContainerPort)
+
+	return &pb.AddPortMappingResponse{
+		Success: true,
+	}, nil
+}
+
+// RemovePortMapping removes a port forwarding rule
+func (s *RouterServer) RemovePortMapping(ctx context.Context, req *pb.RemovePortMappingRequest) (*pb.RemovePortMappingResponse, error) {
+	log.Printf("RemovePortMapping: %s:%d", req.Protocol, req.HostPort)
+
+	protocol := strings.ToLower(req.Protocol)
+	if protocol != "tcp" && protocol != "udp" {
+		return &pb.RemovePortMappingResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid protocol %s (must be tcp or udp)", req.Protocol),
+		}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found bool
+	for id, maps := range s.portMaps {
+		for i, pm := range maps {
+			if pm.Protocol == protocol && pm.HostPort == req.HostPort {
+				s.portMaps[id] = append(maps[:i], maps[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if found {
+			break
+		}
+	}
+
+	if !found {
+		// Rule not found - consider it success
+		log.Printf("Port mapping %s:%d not found (already deleted?)", protocol, req.HostPort)
+		return &pb.RemovePortMappingResponse{
+			Success: true,
+		}, nil
+	}
+
+	if err := s.applyFirewallLocked(); err != nil {
+		return &pb.RemovePortMappingResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to remove port mapping: %v", err),
+		}, nil
+	}
+
+	log.Printf("Removed port mapping %s:%d", protocol, req.HostPort)
+
+	return &pb.RemovePortMappingResponse{
+		Success: true,
+	}, nil
+}
+
+// parseRouteScope maps a human-readable scope name to its netlink
+// constant. SCOPE_LINK is for a prefix reachable directly off devInterface
+// with no gateway (an on-link route); SCOPE_UNIVERSE is for a remote
+// network reached via a gateway. AddRoute defaults to one or the other
+// based on whether a gateway was given, but callers can override it (e.g.
+// SCOPE_HOST for a local route, or a blackhole via a 0-metric UNIVERSE
+// route with no gateway).
+func parseRouteScope(scope string) (netlink.Scope, error) {
+	switch strings.ToLower(scope) {
+	case "", "universe":
+		return netlink.SCOPE_UNIVERSE, nil
+	case "site":
+		return netlink.SCOPE_SITE, nil
+	case "link":
+		return netlink.SCOPE_LINK, nil
+	case "host":
+		return netlink.SCOPE_HOST, nil
+	default:
+		return 0, fmt.Errorf("invalid scope %q (want universe, site, link, or host)", scope)
+	}
+}
+
+// routeScopeString is the inverse of parseRouteScope, used when reporting a
+// tracked route back to a caller via ListRoutes or logNetworkState.
+func routeScopeString(scope netlink.Scope) string {
+	switch scope {
+	case netlink.SCOPE_UNIVERSE:
+		return "universe"
+	case netlink.SCOPE_SITE:
+		return "site"
+	case netlink.SCOPE_LINK:
+		return "link"
+	case netlink.SCOPE_HOST:
+		return "host"
+	default:
+		return fmt.Sprintf("scope(%d)", scope)
+	}
+}
+
+// netlinkRoute rebuilds the *netlink.Route a RouteInfo describes, for
+// handing to netlink.RouteDel - RouteDel only needs enough of the route
+// filled in to disambiguate it from others on the same link.
+func netlinkRoute(info *RouteInfo) *netlink.Route {
+	route := &netlink.Route{
+		Scope:    info.Scope,
+		Priority: info.Metric,
+		Table:    info.Table,
+	}
+	if link, err := netlink.LinkByName(info.Interface); err == nil {
+		route.LinkIndex = link.Attrs().Index
+	}
+	if info.Dst != "" {
+		if _, dst, err := net.ParseCIDR(info.Dst); err == nil {
+			route.Dst = dst
+		}
+	}
+	if info.Gateway != "" {
+		route.Gw = net.ParseIP(info.Gateway)
+	}
+	return route
+}
+
+// AddRoute installs a static route via devInterface and tracks it in
+// s.routes, so it can be torn down again by ID via DeleteRoute or
+// automatically when devInterface's VLAN is deleted. A route with no
+// gateway is on-link (SCOPE_LINK) - dst is reachable directly off
+// devInterface; a route with a gateway is SCOPE_UNIVERSE, mirroring how
+// delRouteIface and delRemoteRoute distinguish the two cases elsewhere in
+// this file. This lets operators pin traffic between two VLANs via a
+// specific next-hop, or steer a subnet into a secondary table for policy
+// routing.
+func (s *RouterServer) AddRoute(ctx context.Context, req *pb.AddRouteRequest) (*pb.AddRouteResponse, error) {
+	log.Printf("AddRoute: dst=%s gateway=%s dev=%s metric=%d scope=%s table=%d",
+		req.Dst, req.Gateway, req.DevInterface, req.Metric, req.Scope, req.Table)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, err := netlink.LinkByName(req.DevInterface)
+	if err != nil {
+		return &pb.AddRouteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to find interface %s: %v", req.DevInterface, err),
+		}, nil
+	}
+
+	route := &netlink.Route{LinkIndex: link.Attrs().Index}
+
+	if req.Dst != "" && req.Dst != "default" {
+		_, dst, err := net.ParseCIDR(req.Dst)
+		if err != nil {
+			return &pb.AddRouteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid destination %s: %v", req.Dst, err),
+			}, nil
+		}
+		route.Dst = dst
+	} else {
+		req.Dst = ""
+	}
+
+	if req.Gateway != "" {
+		gw := net.ParseIP(req.Gateway)
+		if gw == nil {
+			return &pb.AddRouteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid gateway %s", req.Gateway),
+			}, nil
+		}
+		route.Gw = gw
+		route.Scope = netlink.SCOPE_UNIVERSE
+	} else {
+		route.Scope = netlink.SCOPE_LINK
+	}
+
+	if req.Scope != "" {
+		scope, err := parseRouteScope(req.Scope)
+		if err != nil {
+			return &pb.AddRouteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		route.Scope = scope
+	}
+
+	if req.Metric > 0 {
+		route.Priority = int(req.Metric)
+	}
+	if req.Table > 0 {
+		route.Table = int(req.Table)
+	}
+
+	if err := netlink.RouteAdd(route); err != nil {
+		return &pb.AddRouteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to add route: %v", err),
+		}, nil
+	}
+
+	s.routeSeq++
+	id := s.routeSeq
+	s.routes[id] = &RouteInfo{
+		ID:        id,
+		Dst:       req.Dst,
+		Gateway:   req.Gateway,
+		Interface: req.DevInterface,
+		Metric:    int(req.Metric),
+		Scope:     route.Scope,
+		Table:     int(req.Table),
+		CreatedAt: time.Now(),
+	}
+
+	log.Printf("Added route %d: dst=%s gw=%s dev=%s", id, req.Dst, req.Gateway, req.DevInterface)
+
+	return &pb.AddRouteResponse{
+		Success: true,
+		RouteId: id,
+	}, nil
+}
+
+// deleteRouteLocked removes a tracked route from the kernel and from
+// s.routes (must hold lock). It's not an error for the route to already be
+// gone from the kernel - deleteVLANLocked and manual `ip route del` both
+// leave s.routes as the only record in that case.
+func (s *RouterServer) deleteRouteLocked(routeID uint64) error {
+	info, exists := s.routes[routeID]
+	if !exists {
+		return fmt.Errorf("route %d not found", routeID)
+	}
+
+	if err := netlink.RouteDel(netlinkRoute(info)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete route: %v", err)
+	}
+
+	delete(s.routes, routeID)
+
+	log.Printf("Deleted route %d (dst=%s dev=%s)", routeID, info.Dst, info.Interface)
+
+	return nil
+}
+
+// DeleteRoute removes a route previously installed by AddRoute.
+func (s *RouterServer) DeleteRoute(ctx context.Context, req *pb.DeleteRouteRequest) (*pb.DeleteRouteResponse, error) {
+	log.Printf("DeleteRoute: routeID=%d", req.RouteId)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.deleteRouteLocked(req.RouteId); err != nil {
+		return &pb.DeleteRouteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.DeleteRouteResponse{Success: true}, nil
+}
+
+// ListRoutes lists routes AddRoute has installed, optionally filtered down
+// to the one VLAN or VXLAN network req.VlanId names.
+func (s *RouterServer) ListRoutes(ctx context.Context, req *pb.ListRoutesRequest) (*pb.ListRoutesResponse, error) {
+	log.Printf("ListRoutes: filter=%d", req.VlanId)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ifName string
+	if req.VlanId != 0 {
+		name, ok := s.networkInterfaceLocked(req.VlanId)
+		if !ok {
+			return &pb.ListRoutesResponse{}, nil
+		}
+		ifName = name
+	}
+
+	var routes []*pb.RouteEntry
+	for _, info := range s.routes {
+		if ifName != "" && info.Interface != ifName {
+			continue
+		}
+		routes = append(routes, &pb.RouteEntry{
+			RouteId:      info.ID,
+			Dst:          info.Dst,
+			Gateway:      info.Gateway,
+			DevInterface: info.Interface,
+			Metric:       uint32(info.Metric),
+			Scope:        routeScopeString(info.Scope),
+			Table:        uint32(info.Table),
+		})
+	}
+
+	return &pb.ListRoutesResponse{Routes: routes}, nil
+}
+
+// GetDataplaneStats fetches packet/byte counters for a network from a
+// configured out-of-process dataplane plugin, rather than from the built-in
+// netlink/firewall dataplane. req.Plugin selects which configured plugin to
+// ask, so a router can run several (e.g. "vpp" for one set of networks,
+// "ebpf" for another) side by side.
+func (s *RouterServer) GetDataplaneStats(ctx context.Context, req *pb.GetDataplaneStatsRequest) (*pb.GetDataplaneStatsResponse, error) {
+	log.Printf("GetDataplaneStats: plugin=%s vlanID=%d", req.Plugin, req.VlanId)
+
+	if s.dataplane == nil {
+		return &pb.GetDataplaneStatsResponse{
+			Success: false,
+			Error:   "no dataplane plugins configured",
+		}, nil
+	}
+
+	plugin, ok := s.dataplane.Plugin(req.Plugin)
+	if !ok {
+		return &pb.GetDataplaneStatsResponse{
+			Success: false,
+			Error:   fmt.Sprintf("no dataplane plugin named %q configured", req.Plugin),
+		}, nil
+	}
+
+	stats, err := plugin.GetStats(ctx, req.VlanId)
+	if err != nil {
+		return &pb.GetDataplaneStatsResponse{
+			Success: false,
+			Error:   fmt.Sprintf("plugin %q: %v", req.Plugin, err),
+		}, nil
+	}
+
+	return &pb.GetDataplaneStatsResponse{
+		Success:        true,
+		RxPackets:      stats.RxPackets,
+		TxPackets:      stats.TxPackets,
+		RxBytes:        stats.RxBytes,
+		TxBytes:        stats.TxBytes,
+		DroppedPackets: stats.DroppedPackets,
+	}, nil
+}
+
+// ListVLANs lists all VLAN interfaces
+func (s *RouterServer) ListVLANs(ctx context.Context, req *pb.ListVLANsRequest) (*pb.ListVLANsResponse, error) {
+	log.Printf("ListVLANs: filter=%d", req.VlanId)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	var vlans []*pb.VLANInterface
 
@@ -687,6 +2039,70 @@ func (s *RouterServer) ListVLANs(ctx context.Context, req *pb.ListVLANsRequest)
 	}, nil
 }
 
+// ListNetworks lists every network the router manages, VLAN and VXLAN
+// alike, reporting which transport backs each one. ListVLANs remains for
+// backward compatibility and only reports the VLAN subset.
+func (s *RouterServer) ListNetworks(ctx context.Context, req *pb.ListNetworksRequest) (*pb.ListNetworksResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var networks []*pb.NetworkInterface
+
+	for _, vlanInfo := range s.vlans {
+		link, err := netlink.LinkByName(vlanInfo.InterfaceName)
+		if err != nil {
+			log.Printf("Warning: VLAN %d interface not found: %v", vlanInfo.VlanID, err)
+			continue
+		}
+		attrs := link.Attrs()
+		networks = append(networks, &pb.NetworkInterface{
+			Transport:     "vlan",
+			NetworkId:     vlanInfo.VlanID,
+			InterfaceName: vlanInfo.InterfaceName,
+			Gateway:       vlanInfo.Gateway,
+			Subnet:        vlanInfo.Subnet,
+			MacAddress:    attrs.HardwareAddr.String(),
+			Mtu:           uint32(attrs.MTU),
+			IsUp:          attrs.Flags&net.FlagUp != 0,
+			NatEnabled:    vlanInfo.NATEnabled,
+		})
+	}
+
+	for _, vxlanInfo := range s.vxlans {
+		link, err := netlink.LinkByName(vxlanInfo.InterfaceName)
+		if err != nil {
+			log.Printf("Warning: VXLAN %d interface not found: %v", vxlanInfo.VNI, err)
+			continue
+		}
+		attrs := link.Attrs()
+		remotes := make([]string, 0, len(vxlanInfo.VTEPs))
+		for remote := range vxlanInfo.VTEPs {
+			remotes = append(remotes, remote)
+		}
+		networks = append(networks, &pb.NetworkInterface{
+			Transport:     "vxlan",
+			NetworkId:     vxlanInfo.VNI,
+			InterfaceName: vxlanInfo.InterfaceName,
+			Gateway:       vxlanInfo.Gateway,
+			Subnet:        vxlanInfo.Subnet,
+			MacAddress:    attrs.HardwareAddr.String(),
+			Mtu:           uint32(attrs.MTU),
+			IsUp:          attrs.Flags&net.FlagUp != 0,
+			NatEnabled:    vxlanInfo.NATEnabled,
+			Vni:           vxlanInfo.VNI,
+			UdpPort:       uint32(vxlanInfo.DstPort),
+			LocalIp:       vxlanInfo.LocalIP,
+			RemoteVteps:   remotes,
+		})
+	}
+
+	log.Printf("Listed %d network(s)", len(networks))
+
+	return &pb.ListNetworksResponse{
+		Networks: networks,
+	}, nil
+}
+
 // GetHealth returns health status
 func (s *RouterServer) GetHealth(ctx context.Context, req *pb.HealthRequest) (*pb.HealthResponse, error) {
 	s.mu.RLock()
@@ -782,6 +2198,26 @@ func (s *RouterServer) logNetworkState(vlanName string) {
 		}
 	}
 
+	// Log managed routes - the subset of the routing table above that
+	// AddRoute installed and s.routes is tracking, as opposed to routes the
+	// kernel or another process put there.
+	log.Printf("Managed routes:")
+	if len(s.routes) == 0 {
+		log.Printf("  (none)")
+	}
+	for _, info := range s.routes {
+		dst := info.Dst
+		if dst == "" {
+			dst = "default"
+		}
+		gw := info.Gateway
+		if gw == "" {
+			gw = "on-link"
+		}
+		log.Printf("  - id=%d dst=%s gw=%s dev=%s metric=%d scope=%s table=%d",
+			info.ID, dst, gw, info.Interface, info.Metric, routeScopeString(info.Scope), info.Table)
+	}
+
 	// Log iptables NAT rules
 	cmd := exec.Command("iptables", "-t", "nat", "-L", "-n", "-v")
 	if output, err := cmd.CombinedOutput(); err != nil {
@@ -802,12 +2238,33 @@ func (s *RouterServer) logNetworkState(vlanName string) {
 }
 
 // NewRouterServer creates a new router server instance
-func NewRouterServer() *RouterServer {
-	return &RouterServer{
-		vlans:      make(map[uint32]*VLANInfo),
-		dnsEntries: make(map[uint32]map[string]string),
-		startTime:  time.Now(),
+func NewRouterServer(opts ...RouterServerOption) *RouterServer {
+	fw, err := firewall.New()
+	if err != nil {
+		log.Printf("Warning: no firewall backend available, NAT/port-mapping RPCs will fail: %v", err)
+	} else {
+		log.Printf("Using %s firewall backend", fw.Name())
+	}
+
+	s := &RouterServer{
+		vlans:          make(map[uint32]*VLANInfo),
+		vxlans:         make(map[uint32]*VXLANInfo),
+		dnsEntries:     make(map[uint32]map[string]string),
+		endpoints:      make(map[endpointKey]*EndpointInfo),
+		dnsmasqProcs:   make(map[uint32]*dnsmasqInstance),
+		dhcpConfigs:    make(map[uint32]*DHCPConfig),
+		firewall:       fw,
+		portMaps:       make(map[uint32][]firewall.PortMap),
+		egressPolicies: make(map[uint32][]firewall.EgressRule),
+		routes:         make(map[uint64]*RouteInfo),
+		startTime:      time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
 // StartServer starts the gRPC server
@@ -0,0 +1,92 @@
+// Command manager runs the controller-runtime manager that reconciles
+// arca.io/v1alpha1 Network objects into OVN logical switches via the
+// NetworkControl gRPC service.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	arcav1alpha1 "github.com/Liquescent-Development/arca/k8s-controller/pkg/apis/arca/v1alpha1"
+	networkcontroller "github.com/Liquescent-Development/arca/k8s-controller/pkg/controller/network"
+)
+
+var (
+	metricsAddr          = flag.String("metrics-bind-address", ":8080", "address the metrics endpoint binds to")
+	probeAddr            = flag.String("health-probe-bind-address", ":8081", "address the health probe endpoint binds to")
+	networkControlTarget = flag.String("network-control-addr", "", "NetworkControl gRPC endpoint (e.g. helper VM vsock address); required")
+)
+
+func main() {
+	flag.Parse()
+	logf.SetLogger(zap.New())
+	log := logf.Log.WithName("manager")
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(arcav1alpha1.AddToScheme(scheme))
+
+	mgr, err := ctrl.NewManager(config.GetConfigOrDie(), ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: *metricsAddr},
+		HealthProbeBindAddress: *probeAddr,
+	})
+	if err != nil {
+		log.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	netClient, err := newNetworkClient(*networkControlTarget)
+	if err != nil {
+		log.Error(err, "unable to build NetworkControl client")
+		os.Exit(1)
+	}
+
+	reconciler := &networkcontroller.Reconciler{
+		Client:  mgr.GetClient(),
+		Network: netClient,
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		log.Error(err, "unable to create controller", "controller", "Network")
+		os.Exit(1)
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		log.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		log.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
+	log.Info("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		log.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
+
+// newNetworkClient builds the networkcontroller.Client the reconciler calls
+// into. The real implementation dials target with a generated
+// pb.NetworkControlClient, but that client is generated in the
+// arca-network-api proto module, which this controller doesn't vendor yet -
+// until it's wired in, fail fast with a clear error instead of silently
+// reconciling nothing.
+func newNetworkClient(target string) (networkcontroller.Client, error) {
+	if target == "" {
+		return nil, fmt.Errorf("network-control-addr is required")
+	}
+	return nil, fmt.Errorf("no NetworkControl gRPC client implementation wired in yet for %s; see pkg/controller/network.Client", target)
+}
@@ -0,0 +1,22 @@
+// Package v1alpha1 contains the arca.io/v1alpha1 API Schema definitions -
+// the Kubernetes-native surface onto the same OVN logical switches the
+// NetworkControl gRPC service (helpervm/control-api) manages imperatively.
+// +kubebuilder:object:generate=true
+// +groupName=arca.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is group version used to register these objects.
+	GroupVersion = schema.GroupVersion{Group: "arca.io", Version: "v1alpha1"}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme.
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types in this group-version to the given scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,84 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NetworkRoute is a single static route pushed to containers attached to a
+// Network, mirroring the StaticRoute type AttachContainerInterfaces accepts
+// in helpervm/control-api.
+type NetworkRoute struct {
+	Destination string `json:"destination"`
+	Gateway     string `json:"gateway"`
+}
+
+// NetworkSpec is the desired state of an OVN logical switch.
+type NetworkSpec struct {
+	// CIDR is the subnet CreateLogicalSwitch provisions DHCP options for.
+	CIDR string `json:"cidr"`
+
+	// Gateway is the logical switch's router-port address and DHCP default
+	// route.
+	Gateway string `json:"gateway"`
+
+	// DNSRecords maps hostname -> IP address, applied via
+	// ovn.NBClient.SetDNSRecord.
+	// +optional
+	DNSRecords map[string]string `json:"dnsRecords,omitempty"`
+
+	// Routes are static routes pushed to every container attached to this
+	// network.
+	// +optional
+	Routes []NetworkRoute `json:"routes,omitempty"`
+}
+
+// NetworkStatus is observed state, filled in by the controller - operators
+// should treat every field here as read-only.
+type NetworkStatus struct {
+	// Ready is true once the logical switch, its DHCP options and its peer
+	// router have all been created in OVN.
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+
+	// LogicalSwitchUUID is the OVN-assigned UUID of the Logical_Switch row
+	// backing this Network.
+	// +optional
+	LogicalSwitchUUID string `json:"logicalSwitchUUID,omitempty"`
+
+	// AttachedContainers mirrors NetworkServer.containerMap for this
+	// network's containerID set.
+	// +optional
+	AttachedContainers []string `json:"attachedContainers,omitempty"`
+
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="CIDR",type=string,JSONPath=`.spec.cidr`
+// +kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+
+// Network is the Schema for the networks API: a declarative handle onto one
+// OVN logical switch, reconciled into existence by
+// pkg/controller/network.Reconciler through the NetworkControl gRPC service.
+type Network struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NetworkSpec   `json:"spec,omitempty"`
+	Status NetworkStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NetworkList contains a list of Network.
+type NetworkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Network `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Network{}, &NetworkList{})
+}
@@ -0,0 +1,122 @@
+// Package network implements the controller-runtime reconciler that turns
+// Network CRD objects into OVN logical switches, giving operators a
+// declarative handle onto the same state the NetworkControl gRPC service
+// (helpervm/control-api) manages imperatively over vsock.
+package network
+
+import (
+	"context"
+	"fmt"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	arcav1alpha1 "github.com/Liquescent-Development/arca/k8s-controller/pkg/apis/arca/v1alpha1"
+)
+
+// networkProtectFinalizer blocks deletion of a Network object until its OVN
+// logical switch (and anything hung off it, like DNS records) have been torn
+// down - the same "don't disappear the Kubernetes object until the backing
+// resource is actually gone" pattern as Kubernetes' own PV protection
+// finalizer.
+const networkProtectFinalizer = "arca.io/network-protect"
+
+// Client is the subset of the NetworkControl gRPC service this reconciler
+// needs. It's declared here rather than satisfied directly by a generated
+// pb.NetworkControlClient because the generated client lives in the
+// arca-network-api proto module, which this controller doesn't share a
+// go.mod with (see cmd/manager/main.go for where the real gRPC-backed
+// implementation gets wired in once that module is vendored here).
+type Client interface {
+	// CreateNetwork creates networkID's OVN logical switch if it doesn't
+	// already exist and returns its Logical_Switch UUID.
+	CreateNetwork(ctx context.Context, networkID, cidr, gateway string) (logicalSwitchUUID string, err error)
+	// DeleteNetwork deletes networkID's OVN logical switch, tolerating one
+	// that's already gone.
+	DeleteNetwork(ctx context.Context, networkID string) error
+	// SetDNSRecords replaces networkID's DNS records with exactly records.
+	SetDNSRecords(ctx context.Context, networkID string, records map[string]string) error
+	// AttachedContainers lists the containers currently attached to
+	// networkID, mirroring NetworkServer.containerMap.
+	AttachedContainers(ctx context.Context, networkID string) ([]string, error)
+}
+
+// Reconciler reconciles a Network object.
+type Reconciler struct {
+	client.Client
+	Network Client
+}
+
+// +kubebuilder:rbac:groups=arca.io,resources=networks,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=arca.io,resources=networks/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=arca.io,resources=networks/finalizers,verbs=update
+
+// Reconcile implements the Network lifecycle: create-or-update the backing
+// OVN logical switch and DNS records while the object exists, and block
+// deletion behind networkProtectFinalizer until OVN teardown succeeds.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var net arcav1alpha1.Network
+	if err := r.Get(ctx, req.NamespacedName, &net); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	networkID := networkIDFor(req.NamespacedName)
+
+	if !net.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(&net, networkProtectFinalizer) {
+			if err := r.Network.DeleteNetwork(ctx, networkID); err != nil {
+				return ctrl.Result{}, fmt.Errorf("deleting OVN logical switch for %s: %w", req.NamespacedName, err)
+			}
+			controllerutil.RemoveFinalizer(&net, networkProtectFinalizer)
+			if err := r.Update(ctx, &net); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(&net, networkProtectFinalizer) {
+		controllerutil.AddFinalizer(&net, networkProtectFinalizer)
+		if err := r.Update(ctx, &net); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	uuid, err := r.Network.CreateNetwork(ctx, networkID, net.Spec.CIDR, net.Spec.Gateway)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("creating OVN logical switch for %s: %w", req.NamespacedName, err)
+	}
+
+	if err := r.Network.SetDNSRecords(ctx, networkID, net.Spec.DNSRecords); err != nil {
+		return ctrl.Result{}, fmt.Errorf("setting DNS records for %s: %w", req.NamespacedName, err)
+	}
+
+	containers, err := r.Network.AttachedContainers(ctx, networkID)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("listing attached containers for %s: %w", req.NamespacedName, err)
+	}
+
+	net.Status.Ready = true
+	net.Status.LogicalSwitchUUID = uuid
+	net.Status.AttachedContainers = containers
+	if err := r.Status().Update(ctx, &net); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// networkIDFor derives the flat OVN networkID CreateNetwork expects from a
+// Network object's namespace/name, so two Networks named the same in
+// different namespaces don't collide in OVN's flat logical-switch namespace.
+func networkIDFor(key client.ObjectKey) string {
+	return key.Namespace + "-" + key.Name
+}
+
+// SetupWithManager registers the reconciler to watch Network objects.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&arcav1alpha1.Network{}).
+		Complete(r)
+}